@@ -9,7 +9,6 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -20,11 +19,11 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"shotgun_code/internal/labgradient"
+	"shotgun_code/internal/llm/provider"
+	"shotgun_code/pkg/gitrepo"
+	"shotgun_code/pkg/shotgun"
 )
 
-const maxOutputSizeBytes = 10_000_000 // 10MB
-var ErrContextTooLong = errors.New("context is too long")
-
 //go:embed ignore.glob
 var defaultCustomIgnoreRulesContent string
 
@@ -34,6 +33,8 @@ const (
 	LLMProviderOpenAI     = "openai"
 	LLMProviderOpenRouter = "openrouter"
 	LLMProviderGemini     = "gemini"
+	LLMProviderAnthropic  = "anthropic"
+	LLMProviderLocal      = "local" // Ollama or an OpenAI-compatible server; needs no API key
 )
 
 type LLMSettings struct {
@@ -42,27 +43,49 @@ type LLMSettings struct {
 	OpenAIKey      string `json:"openAIKey"`
 	OpenRouterKey  string `json:"openRouterKey"`
 	GeminiKey      string `json:"geminiKey"`
+	AnthropicKey   string `json:"anthropicKey"`
 	BaseURL        string `json:"baseURL"`
+	// TimeoutSeconds bounds every LLM call; zero means the default of ten minutes.
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// AnthropicThinkingBudget is the extended thinking budget of Anthropic models in tokens;
+	// zero disables thinking.
+	AnthropicThinkingBudget int `json:"anthropicThinkingBudget"`
+	// LocalBaseURL is the address of the local provider's server; empty means Ollama's default.
+	LocalBaseURL string `json:"localBaseURL"`
+	// LocalContextLength is the context window of local models in tokens. It is requested from
+	// Ollama and bounds the context budget; zero keeps the server's default.
+	LocalContextLength int `json:"localContextLength"`
 }
 
 type AppSettings struct {
 	CustomIgnoreRules string      `json:"customIgnoreRules"`
 	CustomPromptRules string      `json:"customPromptRules"`
 	LLMSettings       LLMSettings `json:"llmSettings"`
+	// ReencodeText converts UTF-16 and Latin-1 files to UTF-8 during context generation
+	// instead of replacing them with a placeholder.
+	ReencodeText bool `json:"reencodeText"`
+	// SymlinkPolicy decides how symbolic links are listed and packed: "show" (default), "skip" or "follow".
+	SymlinkPolicy string `json:"symlinkPolicy"`
+	// FitTokenBudget checks the generated context against the context window of the active model.
+	FitTokenBudget bool `json:"fitTokenBudget"`
 }
 
 type App struct {
 	ctx                         context.Context
 	contextGenerator            *ContextGenerator
 	fileWatcher                 *Watchman
+	treeIndexer                 *TreeIndexer
 	settings                    AppSettings
 	currentCustomIgnorePatterns *gitignore.GitIgnore
 	configPath                  string
 	useGitignore                bool
 	useCustomIgnore             bool
-	projectGitignore            *gitignore.GitIgnore // Compiled .gitignore for the current project
+	projectGitignore            *shotgun.GitIgnore    // Layered git ignore rules for the current project
+	projectConfig               shotgun.ProjectConfig // Checked-in .shotgun configuration of the current project
+	projectConfigRoot           string                // Project projectConfig was loaded from
 	autoContextService          *AutoContextService
 	historyManager              *HistoryManager
+	llmJobs                     *LLMJobRegistry
 	llmCache                    cachedProvider
 	autoContextButtonTexture    string
 }
@@ -76,7 +99,9 @@ func (a *App) startup(ctx context.Context) {
 	a.contextGenerator = NewContextGenerator(a)
 	a.autoContextService = NewAutoContextService()
 	a.historyManager = NewHistoryManager(a)
+	a.llmJobs = NewLLMJobRegistry(a)
 	a.fileWatcher = NewWatchman(a)
+	a.treeIndexer = NewTreeIndexer(a)
 	a.useGitignore = true    // Default to true, matching frontend
 	a.useCustomIgnore = true // Default to true, matching frontend
 
@@ -100,6 +125,7 @@ func (a *App) startup(ctx context.Context) {
 	}
 
 	a.initAutoContextButtonTexture()
+	a.preloadTokenEncoding()
 }
 
 func (a *App) initAutoContextButtonTexture() {
@@ -127,145 +153,45 @@ func (a *App) GetAutoContextButtonTexture() string {
 	return a.autoContextButtonTexture
 }
 
-type FileNode struct {
-	Name            string      `json:"name"`
-	Path            string      `json:"path"`    // Full path
-	RelPath         string      `json:"relPath"` // Path relative to selected root
-	IsDir           bool        `json:"isDir"`
-	Children        []*FileNode `json:"children,omitempty"`
-	IsGitignored    bool        `json:"isGitignored"`    // True if path matches a .gitignore rule
-	IsCustomIgnored bool        `json:"isCustomIgnored"` // True if path matches a ignore.glob rule
-}
+// FileNode is the tree node type returned to the frontend by ListFiles.
+type FileNode = shotgun.FileNode
 
 // SelectDirectory opens a dialog to select a directory and returns the chosen path
 func (a *App) SelectDirectory() (string, error) {
 	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{})
 }
 
-// ListFiles lists files and folders in a directory, parsing .gitignore if present
+// ListFiles lists files and folders in a directory, applying the project's git ignore rules
+// (nested .gitignore files, .git/info/exclude and core.excludesFile).
 func (a *App) ListFiles(dirPath string) ([]*FileNode, error) {
 	runtime.LogDebugf(a.ctx, "ListFiles called for directory: %s", dirPath)
 
-	a.projectGitignore = nil        // Reset for the new directory
-	var gitIgn *gitignore.GitIgnore // For .gitignore in the project directory
-	gitignorePath := filepath.Join(dirPath, ".gitignore")
-	runtime.LogDebugf(a.ctx, "Attempting to find .gitignore at: %s", gitignorePath)
-	if _, err := os.Stat(gitignorePath); err == nil {
-		runtime.LogDebugf(a.ctx, ".gitignore found at: %s", gitignorePath)
-		gitIgn, err = gitignore.CompileIgnoreFile(gitignorePath)
-		if err != nil {
-			runtime.LogWarningf(a.ctx, "Error compiling .gitignore file at %s: %v", gitignorePath, err)
-			gitIgn = nil
-		} else {
-			a.projectGitignore = gitIgn // Store the compiled project-specific gitignore
-			runtime.LogDebug(a.ctx, ".gitignore compiled successfully.")
-		}
-	} else {
-		runtime.LogDebugf(a.ctx, ".gitignore not found at %s (os.Stat error: %v)", gitignorePath, err)
-		gitIgn = nil
-	}
-
-	// App-level custom ignore patterns are in a.currentCustomIgnorePatterns
-
-	rootNode := &FileNode{
-		Name:         filepath.Base(dirPath),
-		Path:         dirPath,
-		RelPath:      ".",
-		IsDir:        true,
-		IsGitignored: false, // Root itself is not gitignored by default
-		// IsCustomIgnored for root is also false by default, specific patterns would be needed
-		IsCustomIgnored: a.currentCustomIgnorePatterns != nil && a.currentCustomIgnorePatterns.MatchesPath("."),
-	}
-
-	children, err := buildTreeRecursive(context.TODO(), dirPath, dirPath, gitIgn, a.currentCustomIgnorePatterns, 0)
+	matcher := a.openProject(dirPath)
+	rootNode, err := shotgun.BuildTree(context.TODO(), dirPath, matcher, a.settings.SymlinkPolicy)
 	if err != nil {
 		return []*FileNode{rootNode}, fmt.Errorf("error building children tree for %s: %w", dirPath, err)
 	}
-	rootNode.Children = children
 
 	return []*FileNode{rootNode}, nil
 }
 
-func buildTreeRecursive(ctx context.Context, currentPath, rootPath string, gitIgn *gitignore.GitIgnore, customIgn *gitignore.GitIgnore, depth int) ([]*FileNode, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-	}
-
-	entries, err := os.ReadDir(currentPath)
+// openProject compiles the git ignore rules and loads the .shotgun configuration of dirPath,
+// and returns the matcher used to flag tree entries.
+func (a *App) openProject(dirPath string) shotgun.Matcher {
+	a.projectGitignore = nil // Reset for the new directory
+	gitIgn, err := shotgun.CompileGitignore(dirPath)
 	if err != nil {
-		return nil, err
+		runtime.LogWarningf(a.ctx, "Error compiling git ignore rules in %s: %v", dirPath, err)
+		gitIgn = nil
+	} else {
+		a.projectGitignore = gitIgn // Store the project-specific ignore engine
+		runtime.LogDebug(a.ctx, "Git ignore rules compiled successfully.")
 	}
 
-	var nodes []*FileNode
-	for _, entry := range entries {
-		nodePath := filepath.Join(currentPath, entry.Name())
-		relPath, _ := filepath.Rel(rootPath, nodePath)
-		// For gitignore matching, paths should generally be relative to the .gitignore file (rootPath)
-		// and use OS-specific separators. go-gitignore handles this.
-
-		isGitignored := false
-		isCustomIgnored := false
-		pathToMatch := relPath
-		if entry.IsDir() {
-			if !strings.HasSuffix(pathToMatch, string(os.PathSeparator)) {
-				pathToMatch += string(os.PathSeparator)
-			}
-		}
-
-		if gitIgn != nil {
-			isGitignored = gitIgn.MatchesPath(pathToMatch)
-		}
-		if customIgn != nil {
-			isCustomIgnored = customIgn.MatchesPath(pathToMatch)
-		}
-
-		if depth < 2 || strings.Contains(relPath, "node_modules") || strings.HasSuffix(relPath, ".log") {
-			fmt.Printf("Checking path: '%s' (original relPath: '%s'), IsDir: %v, Gitignored: %v, CustomIgnored: %v\n", pathToMatch, relPath, entry.IsDir(), isGitignored, isCustomIgnored)
-		}
-
-		node := &FileNode{
-			Name:            entry.Name(),
-			Path:            nodePath,
-			RelPath:         relPath,
-			IsDir:           entry.IsDir(),
-			IsGitignored:    isGitignored,
-			IsCustomIgnored: isCustomIgnored,
-		}
+	a.loadProjectConfig(dirPath)
 
-		if entry.IsDir() {
-			// If it's a directory, recursively call buildTree.
-			// Recursion stops only for folders ignored by custom rules.
-			// For folders matched by .gitignore, recursion continues so the UI can show their contents
-			// and allow selective inclusion of files inside gitignored folders.
-			if !isCustomIgnored {
-				children, err := buildTreeRecursive(ctx, nodePath, rootPath, gitIgn, customIgn, depth+1)
-				if err != nil {
-					if errors.Is(err, context.Canceled) {
-						return nil, err // Propagate cancellation
-					}
-					// runtime.LogWarnf(ctx, "Error building subtree for %s: %v", nodePath, err) // Use ctx if available
-					runtime.LogWarningf(context.Background(), "Error building subtree for %s: %v", nodePath, err) // Fallback for now
-					// Decide: skip this dir or return error up. For now, skip with log.
-				} else {
-					node.Children = children
-				}
-			}
-		}
-		nodes = append(nodes, node)
-	}
-	// Sort nodes: directories first, then files, then alphabetically
-	sort.SliceStable(nodes, func(i, j int) bool {
-		if nodes[i].IsDir && !nodes[j].IsDir {
-			return true
-		}
-		if !nodes[i].IsDir && nodes[j].IsDir {
-			return false
-		}
-		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
-	})
-	return nodes, nil
+	// App-level custom ignore patterns are in a.currentCustomIgnorePatterns
+	return shotgun.Matcher{Gitignore: gitIgn, Custom: a.currentCustomIgnorePatterns}
 }
 
 // ContextGenerator manages the asynchronous generation of shotgun context
@@ -273,7 +199,8 @@ type ContextGenerator struct {
 	app                *App // To access Wails runtime context for emitting events
 	mu                 sync.Mutex
 	currentCancelFunc  context.CancelFunc
-	currentCancelToken interface{} // Token to identify the current cancel func
+	currentCancelToken interface{}      // Token to identify the current cancel func
+	lastManifest       shotgun.Manifest // Per-file breakdown of the last successful generation
 }
 
 func NewContextGenerator(app *App) *ContextGenerator {
@@ -283,7 +210,7 @@ func NewContextGenerator(app *App) *ContextGenerator {
 // RequestShotgunContextGeneration is called by the frontend to start/restart generation.
 // This method itself is not bound to Wails directly if it's part of App.
 // Instead, a wrapper method in App struct will be bound.
-func (cg *ContextGenerator) requestShotgunContextGenerationInternal(rootDir string, excludedPaths []string) {
+func (cg *ContextGenerator) requestShotgunContextGenerationInternal(rootDir string, excludedPaths []string, opts ContextGenerationOptions) {
 	cg.mu.Lock()
 	if cg.currentCancelFunc != nil {
 		runtime.LogDebug(cg.app.ctx, "Cancelling previous context generation job.")
@@ -294,7 +221,7 @@ func (cg *ContextGenerator) requestShotgunContextGenerationInternal(rootDir stri
 	myToken := new(struct{}) // Create a unique token for this generation job
 	cg.currentCancelFunc = cancel
 	cg.currentCancelToken = myToken
-	runtime.LogInfof(cg.app.ctx, "Starting new shotgun context generation for: %s. Max size: %d bytes.", rootDir, maxOutputSizeBytes)
+	runtime.LogInfof(cg.app.ctx, "Starting new shotgun context generation for: %s.", rootDir)
 	cg.mu.Unlock()
 
 	go func(tokenForThisJob interface{}) {
@@ -317,7 +244,7 @@ func (cg *ContextGenerator) requestShotgunContextGenerationInternal(rootDir stri
 			return
 		}
 
-		output, err := cg.app.generateShotgunOutputWithProgress(genCtx, rootDir, excludedPaths)
+		result, err := cg.app.generateShotgunOutputWithProgress(genCtx, rootDir, excludedPaths, opts)
 
 		select {
 		case <-genCtx.Done():
@@ -328,29 +255,117 @@ func (cg *ContextGenerator) requestShotgunContextGenerationInternal(rootDir stri
 			if err != nil {
 				errMsg := fmt.Sprintf("Error generating shotgun output for %s: %v", rootDir, err)
 				runtime.LogError(cg.app.ctx, errMsg)
+				var budgetErr *shotgun.BudgetError
+				if errors.As(err, &budgetErr) {
+					// Lets the frontend offer a ranked truncation (see ContextGenerationOptions.TruncateToBudget).
+					runtime.EventsEmit(cg.app.ctx, "shotgunContextBudgetExceeded", budgetErr)
+				}
 				runtime.EventsEmit(cg.app.ctx, "shotgunContextError", errMsg)
 			} else {
+				output := result.Output
 				finalSize := len(output)
-				successMsg := fmt.Sprintf("Shotgun context generated successfully for %s. Size: %d bytes.", rootDir, finalSize)
-				if finalSize > maxOutputSizeBytes { // Should have been caught by ErrContextTooLong, but as a safeguard
-					runtime.LogWarningf(cg.app.ctx, "Warning: Generated context size %d exceeds max %d, but was not caught by ErrContextTooLong.", finalSize, maxOutputSizeBytes)
+				cg.mu.Lock()
+				if cg.currentCancelToken == tokenForThisJob { // Don't let a stale job overwrite a newer manifest
+					cg.lastManifest = result.Manifest
 				}
+				cg.mu.Unlock()
+				successMsg := fmt.Sprintf("Shotgun context generated successfully for %s. Size: %d bytes.", rootDir, finalSize)
 				runtime.LogInfo(cg.app.ctx, successMsg)
+				if len(result.Truncated) > 0 {
+					runtime.LogWarningf(cg.app.ctx, "Truncated %d files to fit the token budget (%d tokens).", len(result.Truncated), result.Tokens)
+					runtime.EventsEmit(cg.app.ctx, "shotgunContextTruncated", result.Truncated)
+				}
 				runtime.EventsEmit(cg.app.ctx, "shotgunContextGenerated", output)
 			}
 		}
 	}(myToken) // Pass the token to the goroutine
 }
 
+// ContextGenerationOptions carries per-request generation settings from the frontend.
+type ContextGenerationOptions struct {
+	// TruncateToBudget cuts the heaviest files instead of failing when the context does not fit
+	// the active model's token budget.
+	TruncateToBudget bool `json:"truncateToBudget"`
+	// Format names the payload format: "shotgun" (default), "markdown", "json" or "xml".
+	Format string `json:"format"`
+	// IncludePatterns and ExcludePatterns are doublestar globs relative to the project root,
+	// e.g. "internal/**/*.go" and "**/*_test.go". They apply on top of excludedPaths.
+	IncludePatterns []string `json:"includePatterns"`
+	ExcludePatterns []string `json:"excludePatterns"`
+	// ForceInclude lists relative paths the user checked although an enabled ignore rule matches
+	// them or one of their folders. Everything else matched by those rules is left out.
+	ForceInclude []string `json:"forceInclude"`
+	// GitDiff appends the git diff of the packed files as a section: "modified", "staged" or
+	// "changed" (against GitBaseRef). Empty leaves it out.
+	GitDiff    string `json:"gitDiff"`
+	GitBaseRef string `json:"gitBaseRef"`
+	// GitHistory appends the last GitHistory commit subjects of every packed file; zero leaves
+	// the section out. GitBlame appends who last changed each region of every packed file.
+	// The byte budgets of both sections default to the gitrepo defaults when zero.
+	GitHistory      int  `json:"gitHistory"`
+	GitHistoryBytes int  `json:"gitHistoryBytes"`
+	GitBlame        bool `json:"gitBlame"`
+	GitBlameBytes   int  `json:"gitBlameBytes"`
+	// Symbols maps relative paths of Go files to the declarations to pack instead of the whole
+	// file (see GetFileSymbols).
+	Symbols map[string][]string `json:"symbols"`
+	// RenderModes maps relative paths to "outline" (function bodies elided) or "omitted" (listed
+	// in the tree only); other files are packed in full.
+	RenderModes map[string]string `json:"renderModes"`
+}
+
 // RequestShotgunContextGeneration is the method bound to Wails.
 func (a *App) RequestShotgunContextGeneration(rootDir string, excludedPaths []string) {
+	a.RequestShotgunContextGenerationWithOptions(rootDir, excludedPaths, ContextGenerationOptions{})
+}
+
+// RequestShotgunContextGenerationWithOptions starts/restarts generation with explicit options.
+func (a *App) RequestShotgunContextGenerationWithOptions(rootDir string, excludedPaths []string, opts ContextGenerationOptions) {
 	if a.contextGenerator == nil {
 		// This should not happen if startup initializes it correctly
 		runtime.LogError(a.ctx, "ContextGenerator not initialized")
 		runtime.EventsEmit(a.ctx, "shotgunContextError", "Internal error: ContextGenerator not initialized")
 		return
 	}
-	a.contextGenerator.requestShotgunContextGenerationInternal(rootDir, excludedPaths)
+	a.contextGenerator.requestShotgunContextGenerationInternal(rootDir, excludedPaths, opts)
+}
+
+// ParseShotgunContext reads a generated (or shared) context payload back into its tree and files.
+func (a *App) ParseShotgunContext(payload string) (*shotgun.ParsedPayload, error) {
+	return shotgun.ParsePayload(payload)
+}
+
+// MaterializeShotgunContext writes the files of a context payload into targetDir and returns
+// the written relative paths. Placeholders of binary, undecoded and truncated files are skipped.
+func (a *App) MaterializeShotgunContext(payload string, targetDir string) ([]string, error) {
+	if strings.TrimSpace(targetDir) == "" {
+		return nil, errors.New("target directory is empty")
+	}
+	parsed, err := shotgun.ParsePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	written, err := parsed.Materialize(targetDir)
+	if err != nil {
+		return written, err
+	}
+	runtime.LogInfof(a.ctx, "Materialized %d files from context payload into %s", len(written), targetDir)
+	return written, nil
+}
+
+// GetContextFormats lists the payload formats accepted by ContextGenerationOptions.Format.
+func (a *App) GetContextFormats() []string {
+	return shotgun.FormatNames()
+}
+
+// GetShotgunContextManifest returns the per-file byte/line/token breakdown of the last generated context.
+func (a *App) GetShotgunContextManifest() shotgun.Manifest {
+	if a.contextGenerator == nil {
+		return shotgun.Manifest{}
+	}
+	a.contextGenerator.mu.Lock()
+	defer a.contextGenerator.mu.Unlock()
+	return a.contextGenerator.lastManifest
 }
 
 func (a *App) RequestAutoContextSelection(rootDir string, excludedPaths []string, userTask string) ([]string, error) {
@@ -385,7 +400,7 @@ func (a *App) RequestAutoContextSelection(rootDir string, excludedPaths []string
 		return nil, err
 	}
 
-	cfg := buildProviderConfig(a.settings.LLMSettings)
+	cfg := buildProviderConfig(a.effectiveLLMSettings())
 	providerInstance, err := a.getOrCreateProvider(cfg)
 	if err != nil {
 		a.emitAutoContextError(fmt.Sprintf("failed to configure provider: %v", err))
@@ -393,7 +408,10 @@ func (a *App) RequestAutoContextSelection(rootDir string, excludedPaths []string
 	}
 
 	// Execute LLM call
-	raw, apiCall, err := providerInstance.Generate(a.ctx, prompt)
+	callCtx, _, finish := a.startLLMExecution(LLMExecutionAutoContext, cfg)
+	result, err := providerInstance.Generate(callCtx, prompt)
+	err = finish(err)
+	raw := result.Text
 
 	// Log to shared prompt history for diagnostics (Step 3 view).
 	if a.historyManager != nil {
@@ -412,7 +430,7 @@ func (a *App) RequestAutoContextSelection(rootDir string, excludedPaths []string
 		if err != nil {
 			responseForHistory = fmt.Sprintf("ERROR during auto-context LLM call: %v", err)
 		}
-		a.historyManager.AddItem(historyLabel, prompt, responseForHistory, apiCall)
+		a.historyManager.AddItem(historyLabel, prompt, responseForHistory, result.APICall, newLLMCallStats(cfg, result, prompt))
 	}
 
 	if err != nil {
@@ -436,216 +454,207 @@ func (a *App) RequestAutoContextSelection(rootDir string, excludedPaths []string
 	return selected, nil
 }
 
-// countProcessableItems estimates the total number of operations for progress tracking.
-// Operations: 1 for root dir line, 1 for each dir/file entry in tree, 1 for each file content read.
-func (a *App) countProcessableItems(jobCtx context.Context, rootDir string, excludedMap map[string]bool) (int, error) {
-	count := 1 // For the root directory line itself
-
-	var counterHelper func(currentPath string) error
-	counterHelper = func(currentPath string) error {
-		select {
-		case <-jobCtx.Done():
-			return jobCtx.Err()
-		default:
-		}
-
-		entries, err := os.ReadDir(currentPath)
-		if err != nil {
-			runtime.LogWarningf(a.ctx, "countProcessableItems: error reading dir %s: %v", currentPath, err)
-			return nil // Continue counting other parts if a subdir is inaccessible
-		}
-
-		for _, entry := range entries {
-			path := filepath.Join(currentPath, entry.Name())
-			relPath, _ := filepath.Rel(rootDir, path)
-
-			if excludedMap[relPath] {
-				continue
-			}
-
-			count++ // For the tree entry (dir or file)
+// GetGitSelectionModes lists the modes accepted by SelectGitFiles.
+func (a *App) GetGitSelectionModes() []string {
+	return gitrepo.Modes()
+}
 
-			if entry.IsDir() {
-				err := counterHelper(path)
-				if err != nil { // Propagate cancellation or critical errors
-					return err
-				}
-			} else {
-				count++ // For reading the file content
-			}
-		}
-		return nil
+// SelectGitFiles returns the files of rootDir picked by the git selection modes ("modified",
+// "staged", "untracked", "changed"), in the same shape as RequestAutoContextSelection.
+// baseRef (e.g. origin/main) is required by "changed" only.
+func (a *App) SelectGitFiles(rootDir string, modes []string, baseRef string) ([]string, error) {
+	rootDir = strings.TrimSpace(rootDir)
+	if rootDir == "" {
+		return nil, errors.New("project root is required")
 	}
-
-	err := counterHelper(rootDir)
+	if len(modes) == 0 {
+		return nil, errors.New("no git selection mode given")
+	}
+	repo, err := gitrepo.Open(rootDir)
 	if err != nil {
-		return 0, err // Return error if counting was interrupted (e.g. context cancelled)
+		return nil, err
 	}
-	return count, nil
-}
-
-type generationProgressState struct {
-	processedItems int
-	totalItems     int
-}
-
-func (a *App) emitProgress(state *generationProgressState) {
-	runtime.EventsEmit(a.ctx, "shotgunContextGenerationProgress", map[string]int{
-		"current": state.processedItems,
-		"total":   state.totalItems,
-	})
+	selected, err := repo.Files(a.ctx, modes, strings.TrimSpace(baseRef))
+	if err != nil {
+		return nil, err
+	}
+	runtime.LogInfof(a.ctx, "Git selection (%s) picked %d files", strings.Join(modes, ", "), len(selected))
+	return selected, nil
 }
 
-// generateShotgunOutputWithProgress generates the TXT output with progress reporting and size limits
-func (a *App) generateShotgunOutputWithProgress(jobCtx context.Context, rootDir string, excludedPaths []string) (string, error) {
-	if err := jobCtx.Err(); err != nil { // Check for cancellation at the beginning
-		return "", err
+// GetFileSymbols lists the top-level declarations of a Go file that can be packed on their own
+// through ContextGenerationOptions.Symbols: functions, methods as "Type.Method", types,
+// constants and variables.
+func (a *App) GetFileSymbols(rootDir, relPath string) ([]string, error) {
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimSpace(relPath)))
+	if !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("path %q is outside the project", relPath)
 	}
-
-	excludedMap := make(map[string]bool)
-	for _, p := range excludedPaths {
-		excludedMap[p] = true
+	if !strings.EqualFold(filepath.Ext(rel), ".go") {
+		return nil, fmt.Errorf("symbols can only be picked in Go files, not %s", relPath)
+	}
+	content, err := os.ReadFile(filepath.Join(rootDir, rel))
+	if err != nil {
+		return nil, err
 	}
+	return shotgun.GoSymbols(content)
+}
 
-	totalItems, err := a.countProcessableItems(jobCtx, rootDir, excludedMap)
+// ExpandDependencies follows the imports of the selected files (Go packages of the project's
+// modules, relative JS/TS/Vue imports) up to depth levels and returns the files they pull in,
+// each with the reason it was added. Files matched by the enabled ignore rules are not added.
+func (a *App) ExpandDependencies(rootDir string, selected []string, depth int) ([]shotgun.Dependency, error) {
+	rootDir = strings.TrimSpace(rootDir)
+	if rootDir == "" {
+		return nil, errors.New("project root is required")
+	}
+	deps, err := shotgun.ExpandDependencies(a.ctx, rootDir, selected, depth, a.ignoreMatcher(rootDir))
 	if err != nil {
-		return "", fmt.Errorf("failed to count processable items: %w", err)
+		return nil, err
 	}
-	progressState := &generationProgressState{processedItems: 0, totalItems: totalItems}
-	a.emitProgress(progressState) // Initial progress (0 / total)
+	runtime.LogInfof(a.ctx, "Dependency expansion of %d files added %d files", len(selected), len(deps))
+	return deps, nil
+}
 
-	var output strings.Builder
-	var fileContents strings.Builder
+func (a *App) emitProgress(progress shotgun.Progress) {
+	runtime.EventsEmit(a.ctx, "shotgunContextGenerationProgress", progress)
+}
 
-	// Root directory line
-	output.WriteString(filepath.Base(rootDir) + string(os.PathSeparator) + "\n")
-	progressState.processedItems++
-	a.emitProgress(progressState)
-	if output.Len() > maxOutputSizeBytes {
-		return "", fmt.Errorf("%w: content limit of %d bytes exceeded after root dir line (size: %d bytes)", ErrContextTooLong, maxOutputSizeBytes, output.Len())
+// generateShotgunOutputWithProgress generates the TXT output and forwards progress to the frontend as Wails events.
+func (a *App) generateShotgunOutputWithProgress(jobCtx context.Context, rootDir string, excludedPaths []string, opts ContextGenerationOptions) (*shotgun.Result, error) {
+	formatter, err := shotgun.FormatterByName(opts.Format)
+	if err != nil {
+		return nil, err
 	}
-
-	// buildShotgunTreeRecursive is a recursive helper for generating the tree string and file contents
-	var buildShotgunTreeRecursive func(pCtx context.Context, currentPath, prefix string) error
-	buildShotgunTreeRecursive = func(pCtx context.Context, currentPath, prefix string) error {
-		select {
-		case <-pCtx.Done():
-			return pCtx.Err()
-		default:
+	var sections []shotgun.SectionFunc
+	if opts.GitDiff != "" {
+		if err := gitrepo.ValidateMode(opts.GitDiff); err != nil {
+			return nil, err
 		}
+		sections = append(sections, gitrepo.DiffSection(opts.GitDiff, strings.TrimSpace(opts.GitBaseRef), 0))
+	}
+	if opts.GitHistory > 0 {
+		sections = append(sections, gitrepo.HistorySection(opts.GitHistory, opts.GitHistoryBytes))
+	}
+	if opts.GitBlame {
+		sections = append(sections, gitrepo.BlameSection(opts.GitBlameBytes))
+	}
+	budget, counter := a.contextTokenBudget()
+	generator := shotgun.NewGenerator(shotgun.Options{
+		RootDir:          rootDir,
+		ExcludedPaths:    excludedPaths,
+		TokenBudget:      budget,
+		TokenCounter:     counter,
+		TruncateToBudget: opts.TruncateToBudget,
+		ReencodeText:     a.settings.ReencodeText,
+		Symlinks:         a.settings.SymlinkPolicy,
+		Formatter:        formatter,
+		Patterns:         a.contextPatterns(rootDir, opts),
+		Ignore:           a.ignoreMatcher(rootDir),
+		ForceInclude:     normalizeForceInclude(opts.ForceInclude),
+		Symbols:          normalizePathKeys(opts.Symbols),
+		RenderModes:      normalizePathKeys(opts.RenderModes),
+		Sections:         sections,
+	}, a.emitProgress)
+	if budget > 0 {
+		runtime.LogInfof(a.ctx, "Context limits: %d bytes, %d tokens.", generator.MaxOutputBytes(), budget)
+	} else {
+		runtime.LogInfof(a.ctx, "Context limit: %d bytes.", generator.MaxOutputBytes())
+	}
+	return generator.Generate(jobCtx)
+}
 
-		entries, err := os.ReadDir(currentPath)
-		if err != nil {
-			runtime.LogWarningf(a.ctx, "buildShotgunTreeRecursive: error reading dir %s: %v", currentPath, err)
-			// Decide if this error should halt the entire process or just skip this directory
-			// For now, returning nil to skip, but log it. Could also return the error.
-			return nil // Or return err if this should stop everything
-		}
+// contextPatterns returns the selection globs of a generation: the ones in opts, or else the
+// project's default selection from its .shotgun configuration, like the CLI does.
+func (a *App) contextPatterns(rootDir string, opts ContextGenerationOptions) shotgun.PathPatterns {
+	if len(opts.IncludePatterns) > 0 || len(opts.ExcludePatterns) > 0 {
+		return shotgun.PathPatterns{Include: opts.IncludePatterns, Exclude: opts.ExcludePatterns}
+	}
+	if filepath.Clean(a.projectConfigRoot) != filepath.Clean(rootDir) {
+		return shotgun.PathPatterns{}
+	}
+	return a.projectConfig.Patterns()
+}
 
-		// Sort entries like in ListFiles for consistent tree
-		sort.SliceStable(entries, func(i, j int) bool {
-			entryI := entries[i]
-			entryJ := entries[j]
-			isDirI := entryI.IsDir()
-			isDirJ := entryJ.IsDir()
-			if isDirI && !isDirJ {
-				return true
-			}
-			if !isDirI && isDirJ {
-				return false
-			}
-			return strings.ToLower(entryI.Name()) < strings.ToLower(entryJ.Name())
-		})
-
-		// Create a temporary slice to hold non-excluded entries for correct prefixing
-		var visibleEntries []fs.DirEntry
-		for _, entry := range entries {
-			path := filepath.Join(currentPath, entry.Name())
-			relPath, _ := filepath.Rel(rootDir, path)
-			if !excludedMap[relPath] {
-				visibleEntries = append(visibleEntries, entry)
+// ignoreMatcher returns the ignore rules enabled by useGitignore and useCustomIgnore for rootDir.
+// The git rules of the listed project are reused; another root gets its own.
+func (a *App) ignoreMatcher(rootDir string) shotgun.Matcher {
+	var matcher shotgun.Matcher
+	if a.useGitignore {
+		gitIgn := a.projectGitignore
+		if gitIgn == nil || filepath.Clean(gitIgn.RootDir()) != filepath.Clean(rootDir) {
+			var err error
+			if gitIgn, err = shotgun.CompileGitignore(rootDir); err != nil {
+				runtime.LogWarningf(a.ctx, "Error compiling git ignore rules in %s: %v", rootDir, err)
 			}
 		}
+		matcher.Gitignore = gitIgn
+	}
+	if a.useCustomIgnore {
+		matcher.Custom = a.currentCustomIgnorePatterns
+	}
+	return matcher
+}
 
-		for i, entry := range visibleEntries {
-			select {
-			case <-pCtx.Done():
-				return pCtx.Err()
-			default:
-			}
-
-			path := filepath.Join(currentPath, entry.Name())
-			relPath, _ := filepath.Rel(rootDir, path)
-
-			isLast := i == len(visibleEntries)-1
-
-			branch := "├── "
-			nextPrefix := prefix + "│   "
-			if isLast {
-				branch = "└── "
-				nextPrefix = prefix + "    "
-			}
-			output.WriteString(prefix + branch + entry.Name() + "\n")
-
-			progressState.processedItems++ // For tree entry
-			a.emitProgress(progressState)
-
-			if output.Len()+fileContents.Len() > maxOutputSizeBytes {
-				return fmt.Errorf("%w: content limit of %d bytes exceeded during tree generation (size: %d bytes)", ErrContextTooLong, maxOutputSizeBytes, output.Len()+fileContents.Len())
-			}
-
-			if entry.IsDir() {
-				err := buildShotgunTreeRecursive(pCtx, path, nextPrefix)
-				if err != nil {
-					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-						return err
-					}
-					fmt.Printf("Error processing subdirectory %s: %v\n", path, err)
-				}
-			} else {
-				select { // Check before heavy I/O
-				case <-pCtx.Done():
-					return pCtx.Err()
-				default:
-				}
-				content, err := os.ReadFile(path)
-				if err != nil {
-					fmt.Printf("Error reading file %s: %v\n", path, err)
-					content = []byte(fmt.Sprintf("Error reading file: %v", err))
-				}
-
-				// Ensure forward slashes for the name attribute, consistent with documentation.
-				relPathForwardSlash := filepath.ToSlash(relPath)
-
-				fileContents.WriteString(fmt.Sprintf("<file path=\"%s\">\n", relPathForwardSlash))
-				fileContents.WriteString(string(content))
-				fileContents.WriteString("\n</file>\n") // Each file block ends with a newline
-
-				progressState.processedItems++ // For file content
-				a.emitProgress(progressState)
-
-				if output.Len()+fileContents.Len() > maxOutputSizeBytes { // Final check after append
-					return fmt.Errorf("%w: content limit of %d bytes exceeded after appending file %s (total size: %d bytes)", ErrContextTooLong, maxOutputSizeBytes, relPath, output.Len()+fileContents.Len())
-				}
-			}
+// normalizeForceInclude converts the frontend's relative paths to OS separators.
+func normalizeForceInclude(paths []string) []string {
+	normalized := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			normalized = append(normalized, filepath.Clean(filepath.FromSlash(p)))
 		}
-		return nil
 	}
+	return normalized
+}
 
-	err = buildShotgunTreeRecursive(jobCtx, rootDir, "")
-	if err != nil {
-		return "", fmt.Errorf("failed to build tree for shotgun: %w", err)
+// normalizePathKeys converts the relative paths keying ContextGenerationOptions.Symbols and
+// RenderModes to the OS paths used by the generator.
+func normalizePathKeys[V any](m map[string]V) map[string]V {
+	normalized := make(map[string]V, len(m))
+	for p, v := range m {
+		if p = strings.TrimSpace(p); p != "" {
+			normalized[filepath.Clean(filepath.FromSlash(p))] = v
+		}
 	}
+	return normalized
+}
 
-	if err := jobCtx.Err(); err != nil { // Check for cancellation before final string operations
-		return "", err
+// contextTokenBudget returns the token budget for the active model (its catalog context window,
+// or LocalContextLength for local models) and a matching token counter. The budget is 0 unless
+// FitTokenBudget is on, a model is active and its window is known.
+func (a *App) contextTokenBudget() (int, shotgun.TokenCounter) {
+	settings := a.effectiveLLMSettings()
+	if !a.settings.FitTokenBudget || settings.ActiveProvider == "" {
+		return 0, nil
+	}
+	model := fallbackModel(settings)
+	var budget int
+	if settings.ActiveProvider == LLMProviderLocal {
+		// Local models have no catalog, so provider.ContextWindow knows none of them.
+		budget = settings.LocalContextLength
+	} else {
+		budget = provider.ContextWindow(settings.ActiveProvider, model)
+	}
+	if budget <= 0 {
+		return 0, nil
 	}
+	return budget, shotgun.NewTokenCounter(model)
+}
 
-	// The final output is the tree, a newline, then all concatenated file contents.
-	// If fileContents is empty, we still want the newline after the tree.
-	// If fileContents is not empty, it already ends with a newline, so an extra one might not be desired
-	// depending on how it's structured. Given each <file> block ends with \n, this should be fine.
-	return output.String() + "\n" + strings.TrimRight(fileContents.String(), "\n"), nil
+// preloadTokenEncoding loads the tokenizer of the active model in the background, so that
+// context generation counts its tokens exactly without downloading anything itself. Until it
+// is loaded, or if it cannot be, tokens are estimated.
+func (a *App) preloadTokenEncoding() {
+	settings := a.effectiveLLMSettings()
+	if !a.settings.FitTokenBudget || settings.ActiveProvider == "" {
+		return
+	}
+	model := fallbackModel(settings)
+	go func() {
+		if err := shotgun.LoadTokenEncoding(model); err != nil {
+			runtime.LogWarningf(a.ctx, "Token counts for %s will be estimated: %v", model, err)
+		}
+	}()
 }
 
 // --- Watchman Implementation ---
@@ -661,7 +670,7 @@ type Watchman struct {
 	cancelFunc context.CancelFunc
 
 	// Store current patterns to be used by scanDirectoryStateInternal
-	currentProjectGitignore *gitignore.GitIgnore
+	currentProjectGitignore *shotgun.GitIgnore
 	currentCustomPatterns   *gitignore.GitIgnore
 }
 
@@ -804,11 +813,21 @@ func (w *Watchman) run(ctx context.Context) {
 				continue
 			}
 
-			// Check if the event path is ignored
-			isIgnoredByGit := projIgn != nil && projIgn.MatchesPath(relEventPath)
-			isIgnoredByCustom := custIgn != nil && custIgn.MatchesPath(relEventPath)
+			// An edited, added or removed .gitignore file changes the rules the engines have cached.
+			// The watcher's engine is the project's one unless .gitignore use is switched off.
+			if projIgn.Invalidate(relEventPath) {
+				runtime.LogDebugf(w.app.ctx, "Watchman: Reloading git ignore rules after a change to %s.", event.Name)
+			}
+			if appIgn := w.app.projectGitignore; appIgn != projIgn {
+				appIgn.Invalidate(relEventPath)
+			}
+
+			// Check if the event path is ignored. Removed paths cannot be stat'ed and are matched as files.
+			matcher := shotgun.Matcher{Gitignore: projIgn, Custom: custIgn}
+			eventInfo, statErr := os.Stat(event.Name)
+			isEventDir := statErr == nil && eventInfo.IsDir()
 
-			if isIgnoredByGit || isIgnoredByCustom {
+			if matcher.Ignored(relEventPath, isEventDir) {
 				runtime.LogDebugf(w.app.ctx, "Watchman: Ignoring event for %s as it's an ignored path.", event.Name)
 				continue
 			}
@@ -820,19 +839,10 @@ func (w *Watchman) run(ctx context.Context) {
 			}
 
 			// Dynamic directory watching
-			if event.Op&fsnotify.Create != 0 {
-				info, statErr := os.Stat(event.Name)
-				if statErr == nil && info.IsDir() {
-					// Check if this new directory itself is ignored before adding
-					isNewDirIgnoredByGit := projIgn != nil && projIgn.MatchesPath(relEventPath)
-					isNewDirIgnoredByCustom := custIgn != nil && custIgn.MatchesPath(relEventPath)
-					if !isNewDirIgnoredByGit && !isNewDirIgnoredByCustom {
-						runtime.LogDebugf(w.app.ctx, "Watchman: New directory created %s, adding to watcher.", event.Name)
-						w.addPathsToWatcherRecursive(event.Name) // This will add event.Name and its children
-					} else {
-						runtime.LogDebugf(w.app.ctx, "Watchman: New directory %s is ignored, not adding to watcher.", event.Name)
-					}
-				}
+			// The event path was checked against the ignore rules above, so a new directory is not ignored.
+			if event.Op&fsnotify.Create != 0 && isEventDir {
+				runtime.LogDebugf(w.app.ctx, "Watchman: New directory created %s, adding to watcher.", event.Name)
+				w.addPathsToWatcherRecursive(event.Name) // This will add event.Name and its children
 			}
 
 			if event.Op&fsnotify.Remove != 0 || event.Op&fsnotify.Rename != 0 {
@@ -902,10 +912,8 @@ func (w *Watchman) addPathsToWatcherRecursive(baseDirToAdd string) {
 			}
 		}
 
-		isIgnoredByGit := projIgn != nil && projIgn.MatchesPath(relPath)
-		isIgnoredByCustom := custIgn != nil && custIgn.MatchesPath(relPath)
-
-		if isIgnoredByGit || isIgnoredByCustom {
+		matcher := shotgun.Matcher{Gitignore: projIgn, Custom: custIgn}
+		if relPath != "." && matcher.Ignored(relPath, true) {
 			runtime.LogDebugf(w.app.ctx, "Watchman.addPathsToWatcherRecursive: Skipping ignored directory: %s", path)
 			return filepath.SkipDir
 		}
@@ -978,24 +986,14 @@ func (w *Watchman) RefreshIgnoresAndRescan() error {
 // --- Configuration Management ---
 
 func (a *App) compileCustomIgnorePatterns() error {
-	if strings.TrimSpace(a.settings.CustomIgnoreRules) == "" {
-		a.currentCustomIgnorePatterns = nil
+	// CompileRules returns nil for blank rules; CompileIgnoreLines in this library version
+	// does not report errors, so there is nothing else to check here.
+	rules := shotgun.MergeRules(a.settings.CustomIgnoreRules, a.projectConfig.IgnoreRules)
+	a.currentCustomIgnorePatterns = shotgun.CompileRules(rules)
+	if a.currentCustomIgnorePatterns == nil {
 		runtime.LogDebug(a.ctx, "Custom ignore rules are empty, no patterns compiled.")
 		return nil
 	}
-	lines := strings.Split(strings.ReplaceAll(a.settings.CustomIgnoreRules, "\r\n", "\n"), "\n")
-	var validLines []string
-	for _, line := range lines {
-		// CompileIgnoreLines should handle empty/comment lines appropriately based on .gitignore syntax
-		validLines = append(validLines, line)
-	}
-
-	ign := gitignore.CompileIgnoreLines(validLines...)
-	// Поскольку CompileIgnoreLines в этой версии не возвращает ошибку,
-	// проверка на err удалена.
-	// Если ign будет nil (например, если все строки были пустыми или комментариями,
-	// и библиотека так обрабатывает), то это будет корректно обработано ниже.
-	a.currentCustomIgnorePatterns = ign
 	runtime.LogInfo(a.ctx, "Successfully compiled custom ignore patterns.")
 	return nil
 }
@@ -1040,6 +1038,10 @@ func (a *App) loadSettings() {
 				runtime.LogInfo(a.ctx, "Custom prompt rules are empty or missing, using default.")
 				a.settings.CustomPromptRules = defaultCustomPromptRulesContent
 			}
+			if err := shotgun.ValidateSymlinkPolicy(a.settings.SymlinkPolicy); err != nil {
+				runtime.LogWarningf(a.ctx, "%v; using the default.", err)
+				a.settings.SymlinkPolicy = ""
+			}
 		}
 	}
 
@@ -1126,6 +1128,65 @@ func (a *App) SetCustomPromptRules(rules string) error {
 	return nil
 }
 
+// GetReencodeText reports whether non-UTF-8 text files are re-encoded to UTF-8 during context generation.
+func (a *App) GetReencodeText() bool {
+	return a.settings.ReencodeText
+}
+
+// SetReencodeText updates and saves the re-encoding setting used for the next context generation.
+func (a *App) SetReencodeText(enabled bool) error {
+	a.settings.ReencodeText = enabled
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save re-encoding setting: %w", err)
+	}
+	runtime.LogInfof(a.ctx, "App setting reencodeText changed to: %v", enabled)
+	return nil
+}
+
+// GetFitTokenBudget reports whether generated contexts are checked against the context window
+// of the active model.
+func (a *App) GetFitTokenBudget() bool {
+	return a.settings.FitTokenBudget
+}
+
+// SetFitTokenBudget updates and saves the token budget setting used for the next context generation.
+func (a *App) SetFitTokenBudget(enabled bool) error {
+	a.settings.FitTokenBudget = enabled
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save token budget setting: %w", err)
+	}
+	runtime.LogInfof(a.ctx, "App setting fitTokenBudget changed to: %v", enabled)
+	a.preloadTokenEncoding()
+	return nil
+}
+
+// GetSymlinkPolicies lists the accepted symbolic link policies.
+func (a *App) GetSymlinkPolicies() []string {
+	return shotgun.SymlinkPolicies()
+}
+
+// GetSymlinkPolicy returns how symbolic links are handled in the tree and the context.
+func (a *App) GetSymlinkPolicy() string {
+	if a.settings.SymlinkPolicy == "" {
+		return shotgun.SymlinkShow
+	}
+	return a.settings.SymlinkPolicy
+}
+
+// SetSymlinkPolicy updates and saves the symbolic link policy ("show", "skip" or "follow").
+// The file tree has to be reloaded for it to take effect there.
+func (a *App) SetSymlinkPolicy(policy string) error {
+	if err := shotgun.ValidateSymlinkPolicy(policy); err != nil {
+		return err
+	}
+	a.settings.SymlinkPolicy = policy
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save symlink policy: %w", err)
+	}
+	runtime.LogInfof(a.ctx, "App setting symlinkPolicy changed to: %q", policy)
+	return nil
+}
+
 // SetUseGitignore updates the app's setting for using .gitignore and informs the watcher.
 func (a *App) SetUseGitignore(enabled bool) error {
 	a.useGitignore = enabled
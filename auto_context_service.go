@@ -304,13 +304,27 @@ func resolveLLMSelection(rootDir string, candidates []string) ([]string, error)
 	return sorted, nil
 }
 
+// buildProviderConfig turns the settings into the configuration of the active provider. The
+// custom base URL is meant for OpenAI-compatible endpoints, so Anthropic and Gemini are always
+// called at their own APIs, and the local provider uses its server address.
 func buildProviderConfig(settings LLMSettings) provider.Config {
-	return provider.Config{
+	cfg := provider.Config{
 		Provider: settings.ActiveProvider,
 		Model:    fallbackModel(settings),
 		APIKey:   settings.keyForProvider(settings.ActiveProvider),
 		BaseURL:  strings.TrimSpace(settings.BaseURL),
 	}
+	switch cfg.Provider {
+	case LLMProviderAnthropic:
+		cfg.BaseURL = ""
+		cfg.ThinkingBudget = settings.AnthropicThinkingBudget
+	case LLMProviderGemini:
+		cfg.BaseURL = ""
+	case LLMProviderLocal:
+		cfg.BaseURL = strings.TrimSpace(settings.LocalBaseURL)
+		cfg.ContextLength = settings.LocalContextLength
+	}
+	return cfg
 }
 
 func fallbackModel(settings LLMSettings) string {
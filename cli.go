@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+
+	"shotgun_code/internal/llm/provider"
+	"shotgun_code/pkg/gitrepo"
+	"shotgun_code/pkg/shotgun"
+)
+
+// --- Headless CLI ---
+//
+// The CLI reuses the same generator as the desktop app but never touches the Wails runtime,
+// so it can run in CI and pre-commit hooks on machines without a display.
+
+const cliUsage = `Usage:
+  shotgun-code context [flags] <dir>
+
+Generates the shotgun context payload for <dir> without opening the app window.
+
+Flags:
+`
+
+const extractUsage = `Usage:
+  shotgun-code extract [flags] <payload-file|->
+
+Writes the files of a shotgun context payload (shotgun, json or xml format) into a directory.
+Placeholders of binary, undecoded and truncated files are skipped.
+
+Flags:
+`
+
+// stringListFlag collects repeated occurrences of a flag, e.g. -exclude a -exclude b.
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*s = append(*s, part)
+		}
+	}
+	return nil
+}
+
+// runCLI dispatches headless subcommands. The second return value is false when args do not
+// name a known subcommand, in which case the caller should start the desktop app instead.
+func runCLI(args []string, stdout, stderr io.Writer) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	switch args[0] {
+	case "context":
+		return runContextCommand(args[1:], stdout, stderr), true
+	case "extract":
+		return runExtractCommand(args[1:], os.Stdin, stdout, stderr), true
+	default:
+		return 0, false
+	}
+}
+
+// renderModes maps the -outline and -omit paths to their render modes.
+func renderModes(outlines, omits []string) map[string]string {
+	modes := make(map[string]string, len(outlines)+len(omits))
+	for _, p := range outlines {
+		modes[filepath.Clean(filepath.FromSlash(p))] = shotgun.RenderOutline
+	}
+	for _, p := range omits {
+		modes[filepath.Clean(filepath.FromSlash(p))] = shotgun.RenderOmitted
+	}
+	return modes
+}
+
+// symbolsFlag collects -symbols file=Symbol,Type.Method occurrences, keyed by OS path.
+type symbolsFlag map[string][]string
+
+func (s symbolsFlag) String() string {
+	var parts []string
+	for file, symbols := range s {
+		parts = append(parts, file+"="+strings.Join(symbols, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s symbolsFlag) Set(value string) error {
+	file, list, ok := strings.Cut(value, "=")
+	file = strings.TrimSpace(file)
+	if !ok || file == "" {
+		return fmt.Errorf("expected file=Symbol[,Symbol...], got %q", value)
+	}
+	key := filepath.Clean(filepath.FromSlash(file))
+	for _, symbol := range strings.Split(list, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			s[key] = append(s[key], symbol)
+		}
+	}
+	return nil
+}
+
+func runContextCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("context", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, cliUsage)
+		flags.PrintDefaults()
+	}
+
+	var includes, excludes, includeGlobs, excludeGlobs, gitSelect, outlines, omits stringListFlag
+	symbols := symbolsFlag{}
+	outputPath := flags.String("o", "", "write the payload to this file instead of stdout")
+	noGitignore := flags.Bool("no-gitignore", false, "do not apply the project's .gitignore")
+	noCustomIgnore := flags.Bool("no-custom-ignore", false, "do not apply the custom ignore rules (ignore.glob)")
+	noProjectConfig := flags.Bool("no-project-config", false, "do not apply the project's .shotgunignore / .shotgun.json (or .shotgun/) configuration")
+	rulesPath := flags.String("ignore-rules", "", "read custom ignore rules from this file instead of the app settings")
+	model := flags.String("model", "", "target model; its catalog context window becomes the token budget")
+	maxTokens := flags.Int("max-tokens", 0, "token budget for the payload (overrides the -model context window)")
+	truncate := flags.Bool("truncate", false, "cut the heaviest files instead of failing when the token budget is exceeded")
+	format := flags.String("format", shotgun.FormatShotgun, "payload format: "+strings.Join(shotgun.FormatNames(), ", "))
+	reencode := flags.Bool("reencode", false, "convert UTF-16 and Latin-1 files to UTF-8 instead of omitting them")
+	symlinks := flags.String("symlinks", shotgun.SymlinkShow, "symbolic links: "+strings.Join(shotgun.SymlinkPolicies(), ", "))
+	deps := flags.Int("deps", 0, "also include the files imported by the -include / -git-select files, up to this many levels")
+	gitDiff := flags.String("git-diff", "", "append the git diff of the packed files: modified, staged or changed (against -git-base)")
+	gitBase := flags.String("git-base", "", "base ref for the changed git mode, e.g. origin/main")
+	gitHistory := flags.Int("git-history", 0, "append the last N commit subjects of every packed file")
+	gitHistoryBytes := flags.Int("git-history-bytes", gitrepo.DefaultHistoryBytes, "size budget of the -git-history section")
+	gitBlame := flags.Bool("git-blame", false, "append a summary of who last changed each region of every packed file, and when")
+	gitBlameBytes := flags.Int("git-blame-bytes", gitrepo.DefaultBlameBytes, "size budget of the -git-blame section")
+	manifestPath := flags.String("manifest", "", "write the per-file byte/line/token manifest as JSON to this file")
+	flags.Var(&includes, "include", "relative path to include; may be repeated or comma separated (default: everything)")
+	flags.Var(&excludes, "exclude", "relative path to exclude; may be repeated or comma separated")
+	flags.Var(&includeGlobs, "include-glob", "keep only files matching this doublestar glob, e.g. 'internal/**/*.go'; may be repeated")
+	flags.Var(&excludeGlobs, "exclude-glob", "drop files and folders matching this doublestar glob, e.g. '**/*_test.go'; may be repeated")
+	flags.Var(symbols, "symbols", "pack only these declarations of a Go file, e.g. 'app.go=App.ListFiles,AppSettings'; may be repeated")
+	flags.Var(&outlines, "outline", "relative path of a file to pack as an outline, with function bodies elided; may be repeated or comma separated")
+	flags.Var(&omits, "omit", "relative path of a file to list in the tree without its content; may be repeated or comma separated")
+	flags.Var(&gitSelect, "git-select", "include the files picked by git: modified, staged, untracked or changed (against -git-base); may be repeated or comma separated")
+
+	// Allow flags both before and after the directory argument.
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			return 2
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	rootDir, err := filepath.Abs(positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "error: invalid directory %q: %v\n", positional[0], err)
+		return 1
+	}
+	if info, err := os.Stat(rootDir); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "error: %s is not a directory\n", rootDir)
+		return 1
+	}
+
+	formatter, err := shotgun.FormatterByName(*format)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if err := shotgun.ValidateSymlinkPolicy(*symlinks); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	for _, mode := range gitSelect {
+		if err := gitrepo.ValidateMode(mode); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+	var sections []shotgun.SectionFunc
+	if *gitDiff != "" {
+		if err := gitrepo.ValidateMode(*gitDiff); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+		sections = append(sections, gitrepo.DiffSection(*gitDiff, *gitBase, 0))
+	}
+	if *gitHistory > 0 {
+		sections = append(sections, gitrepo.HistorySection(*gitHistory, *gitHistoryBytes))
+	}
+	if *gitBlame {
+		sections = append(sections, gitrepo.BlameSection(*gitBlameBytes))
+	}
+
+	var project shotgun.ProjectConfig
+	if !*noProjectConfig {
+		project, err = shotgun.LoadProjectConfig(rootDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	// Globs given on the command line replace the project's default selection.
+	patterns := project.Patterns()
+	if len(includeGlobs) > 0 || len(excludeGlobs) > 0 {
+		patterns = shotgun.PathPatterns{Include: includeGlobs, Exclude: excludeGlobs}
+	}
+	if err := patterns.Validate(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	var matcher shotgun.Matcher
+	if !*noGitignore {
+		gitIgn, err := shotgun.CompileGitignore(rootDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: failed to compile .gitignore in %s: %v\n", rootDir, err)
+		}
+		matcher.Gitignore = gitIgn
+	}
+	if !*noCustomIgnore {
+		rules, err := loadCLICustomIgnoreRules(*rulesPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		matcher.Custom = shotgun.CompileRules(shotgun.MergeRules(rules, project.IgnoreRules))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(gitSelect) > 0 {
+		repo, err := gitrepo.Open(rootDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		selected, err := repo.Files(ctx, gitSelect, *gitBase)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		if len(selected) == 0 {
+			// An empty include list would mean the whole project.
+			fmt.Fprintf(stderr, "error: git selection (%s) picked no files\n", strings.Join(gitSelect, ", "))
+			return 1
+		}
+		for _, p := range selected {
+			includes = append(includes, filepath.FromSlash(p))
+		}
+	}
+
+	if *deps > 0 {
+		if len(includes) == 0 {
+			fmt.Fprintln(stderr, "error: -deps needs files selected with -include or -git-select")
+			return 2
+		}
+		added, err := shotgun.ExpandDependencies(ctx, rootDir, includes, *deps, matcher)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		for _, dep := range added {
+			fmt.Fprintf(stderr, "added %s (%s)\n", dep.Path, dep.Reason)
+			includes = append(includes, filepath.FromSlash(dep.Path))
+		}
+	}
+
+	excludedPaths, err := shotgun.ExcludedPaths(rootDir, matcher, shotgun.Selection{Include: includes, Exclude: excludes})
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to scan %s: %v\n", rootDir, err)
+		return 1
+	}
+
+	budget := *maxTokens
+	var counter shotgun.TokenCounter
+	if *model != "" {
+		if budget == 0 {
+			info, ok := provider.LookupModel(*model)
+			if !ok || info.ContextWindow == 0 {
+				fmt.Fprintf(stderr, "error: unknown context window for model %q; pass -max-tokens\n", *model)
+				return 1
+			}
+			budget = info.ContextWindow
+		}
+		if err := shotgun.LoadTokenEncoding(*model); err != nil {
+			fmt.Fprintf(stderr, "warning: token counts for %s are estimated: %v\n", *model, err)
+		}
+		counter = shotgun.NewTokenCounter(*model)
+	}
+
+	generator := shotgun.NewGenerator(shotgun.Options{
+		RootDir:          rootDir,
+		ExcludedPaths:    excludedPaths,
+		TokenBudget:      budget,
+		TokenCounter:     counter,
+		TruncateToBudget: *truncate,
+		ReencodeText:     *reencode,
+		Symlinks:         *symlinks,
+		Formatter:        formatter,
+		Patterns:         patterns,
+		// ExcludedPaths does not walk into linked folders; the generator applies the rules there.
+		Ignore:       matcher,
+		ForceInclude: normalizeForceInclude(includes),
+		Symbols:      symbols,
+		RenderModes:  renderModes(outlines, omits),
+		Sections:     sections,
+	}, nil)
+	result, err := generator.Generate(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to generate context for %s: %v\n", rootDir, err)
+		var budgetErr *shotgun.BudgetError
+		if errors.As(err, &budgetErr) {
+			printBudgetReport(stderr, budgetErr)
+		}
+		return 1
+	}
+	for _, f := range result.Truncated {
+		fmt.Fprintf(stderr, "truncated %s (%d tokens)\n", f.Path, f.Tokens)
+	}
+	if *manifestPath != "" {
+		data, err := json.MarshalIndent(result.Manifest, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*manifestPath, data, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to write manifest %s: %v\n", *manifestPath, err)
+			return 1
+		}
+	}
+	output := result.Output
+
+	if *outputPath == "" {
+		if _, err := io.WriteString(stdout, output); err != nil {
+			fmt.Fprintf(stderr, "error: failed to write payload: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := os.WriteFile(*outputPath, []byte(output), 0644); err != nil {
+		fmt.Fprintf(stderr, "error: failed to write %s: %v\n", *outputPath, err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "Wrote %d bytes to %s\n", len(output), *outputPath)
+	return 0
+}
+
+func runExtractCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, extractUsage)
+		flags.PrintDefaults()
+	}
+	outputDir := flags.String("o", ".", "directory to write the files into")
+	listOnly := flags.Bool("list", false, "only list the files of the payload, do not write anything")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if source := flags.Arg(0); source == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to read payload: %v\n", err)
+		return 1
+	}
+
+	payload, err := shotgun.ParsePayload(string(data))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *listOnly {
+		for _, f := range payload.Files {
+			if f.Omitted {
+				fmt.Fprintf(stdout, "%s (omitted)\n", f.Path)
+			} else if f.Partial {
+				fmt.Fprintf(stdout, "%s (partial, %d bytes)\n", f.Path, len(f.Content))
+			} else {
+				fmt.Fprintf(stdout, "%s (%d bytes)\n", f.Path, len(f.Content))
+			}
+		}
+		return 0
+	}
+
+	written, err := payload.Materialize(*outputDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "Wrote %d files to %s (%d placeholders skipped)\n", len(written), *outputDir, len(payload.Files)-len(written))
+	return 0
+}
+
+// loadCLICustomIgnoreRules returns the custom ignore rules the desktop app would use:
+// an explicit rules file if given, otherwise the saved settings, otherwise the embedded ignore.glob.
+func loadCLICustomIgnoreRules(rulesPath string) (string, error) {
+	if rulesPath != "" {
+		data, err := os.ReadFile(rulesPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ignore rules %s: %w", rulesPath, err)
+		}
+		return string(data), nil
+	}
+
+	// SearchConfigFile does not create directories, unlike xdg.ConfigFile used by the app.
+	if configPath, err := xdg.SearchConfigFile("shotgun-code/settings.json"); err == nil {
+		data, err := os.ReadFile(configPath)
+		if err == nil {
+			var settings AppSettings
+			if err := json.Unmarshal(data, &settings); err == nil && strings.TrimSpace(settings.CustomIgnoreRules) != "" {
+				return settings.CustomIgnoreRules, nil
+			}
+		}
+	}
+	return defaultCustomIgnoreRulesContent, nil
+}
+
+// printBudgetReport explains which files pushed the payload over the token budget.
+func printBudgetReport(w io.Writer, budgetErr *shotgun.BudgetError) {
+	if len(budgetErr.Overflow) > 0 {
+		fmt.Fprintf(w, "Budget of %d tokens was crossed at %s.\n", budgetErr.Budget, budgetErr.Overflow[0].Path)
+	}
+	if !budgetErr.TruncationFits {
+		fmt.Fprintln(w, "Truncating files would not fit the budget: the tree, the sections and the placeholders need more.")
+		return
+	}
+	if len(budgetErr.Truncation) > 0 {
+		fmt.Fprintln(w, "Re-run with -truncate to cut these files, heaviest first:")
+		for _, f := range budgetErr.Truncation {
+			fmt.Fprintf(w, "  %8d tokens  %s\n", f.Tokens, f.Path)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"shotgun_code/pkg/shotgun"
+)
+
+// cliProject writes a small project and a custom ignore rules file next to it, and keeps the
+// user's git configuration out of the .gitignore evaluation.
+func cliProject(t *testing.T) (root, rules string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	dir := t.TempDir()
+	root = filepath.Join(dir, "project")
+	files := map[string]string{
+		".gitignore":      "*.log\n",
+		"main.go":         "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n",
+		"docs/guide.md":   "# Guide\n",
+		"internal/a.go":   "package internal\n",
+		"internal/a_test": "test data\n",
+		"debug.log":       "gitignored\n",
+		"build/out.txt":   "custom ignored\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules = filepath.Join(dir, "rules.glob")
+	if err := os.WriteFile(rules, []byte("build/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root, rules
+}
+
+// runCommand runs the CLI with args and returns its exit code, stdout and stderr.
+func runCommand(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code, handled := runCLI(args, &stdout, &stderr)
+	if !handled {
+		t.Fatalf("%v was not handled by the CLI", args)
+	}
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunCLIDispatch(t *testing.T) {
+	for _, args := range [][]string{nil, {}, {"-v"}, {"serve", "x"}} {
+		if _, handled := runCLI(args, &bytes.Buffer{}, &bytes.Buffer{}); handled {
+			t.Errorf("runCLI(%q) was handled; the app should start", args)
+		}
+	}
+	for _, command := range []string{"context", "extract"} {
+		code, _, stderr := runCommand(t, command)
+		if code != 2 || !strings.Contains(stderr, "Usage:\n  shotgun-code "+command) {
+			t.Errorf("%s without arguments = %d\n%s", command, code, stderr)
+		}
+		if code, _, _ := runCommand(t, command, "-h"); code != 0 {
+			t.Errorf("%s -h = %d, want 0", command, code)
+		}
+	}
+}
+
+func TestContextCommand(t *testing.T) {
+	root, rules := cliProject(t)
+	file := func(path string) string { return "<file path=\"" + path + "\">" }
+
+	tests := []struct {
+		name    string
+		args    []string
+		packed  []string
+		dropped []string
+	}{
+		{
+			"ignore rules apply",
+			[]string{"-ignore-rules", rules, root},
+			[]string{"main.go", "docs/guide.md", "internal/a.go", "internal/a_test"},
+			[]string{"debug.log", "build/out.txt"},
+		},
+		{
+			"rules switched off, flags after the directory",
+			[]string{root, "-no-gitignore", "-no-custom-ignore"},
+			[]string{"main.go", "debug.log", "build/out.txt"},
+			nil,
+		},
+		{
+			"includes and excludes",
+			[]string{"-ignore-rules", rules, "-include", "internal,main.go", "-exclude", "internal/a_test", root},
+			[]string{"main.go", "internal/a.go"},
+			[]string{"docs/guide.md", "internal/a_test", "debug.log"},
+		},
+		{
+			"forced ignored file",
+			[]string{"-ignore-rules", rules, "-include", "debug.log", root},
+			[]string{"debug.log"},
+			[]string{"main.go", "build/out.txt"},
+		},
+		{
+			"globs",
+			[]string{"-ignore-rules", rules, "-include-glob", "**/*.go", "-exclude-glob", "internal/**", root},
+			[]string{"main.go"},
+			[]string{"internal/a.go", "docs/guide.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCommand(t, append([]string{"context"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("exit code %d\n%s", code, stderr)
+			}
+			if !strings.HasPrefix(stdout, "project/\n") {
+				t.Errorf("payload does not start with the tree:\n%s", stdout)
+			}
+			for _, p := range tt.packed {
+				if !strings.Contains(stdout, file(p)) {
+					t.Errorf("%s is missing:\n%s", p, stdout)
+				}
+			}
+			for _, p := range tt.dropped {
+				if strings.Contains(stdout, file(p)) {
+					t.Errorf("%s is packed:\n%s", p, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestContextCommandRenderOptions(t *testing.T) {
+	root, rules := cliProject(t)
+	code, stdout, stderr := runCommand(t, "context", "-ignore-rules", rules, "-outline", "main.go", "-omit", "docs/guide.md", "-format", "json", root)
+	if code != 0 {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	var doc struct {
+		Files []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+			Omitted bool   `json:"omitted"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	for _, f := range doc.Files {
+		switch f.Path {
+		case "main.go":
+			if f.Content != "package main\n\nfunc main() { ... }\n" {
+				t.Errorf("main.go is not outlined: %q", f.Content)
+			}
+		case "docs/guide.md":
+			if !f.Omitted {
+				t.Errorf("docs/guide.md is not omitted: %+v", f)
+			}
+		}
+	}
+}
+
+func TestContextCommandOutputFiles(t *testing.T) {
+	root, rules := cliProject(t)
+	out := filepath.Join(t.TempDir(), "payload.txt")
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	code, stdout, stderr := runCommand(t, "context", "-ignore-rules", rules, "-o", out, "-manifest", manifestPath, root)
+	if code != 0 || stdout != "" {
+		t.Fatalf("exit code %d, stdout %q\n%s", code, stdout, stderr)
+	}
+	payload, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr, "Wrote "+strconv.Itoa(len(payload))+" bytes to "+out) {
+		t.Errorf("stderr = %q", stderr)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest shotgun.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v\n%s", err, data)
+	}
+	if manifest.RootDir != root || len(manifest.Files) != 5 || manifest.TotalTokens == 0 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+func TestContextCommandBudget(t *testing.T) {
+	root, rules := cliProject(t)
+	if err := os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("0123456789\n", 400)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr := runCommand(t, "context", "-ignore-rules", rules, "-max-tokens", "400", root)
+	if code != 1 || !strings.Contains(stderr, "Budget of 400 tokens was crossed") || !strings.Contains(stderr, "Re-run with -truncate to cut these files, heaviest first:\n") || !strings.Contains(stderr, " tokens  big.txt\n") {
+		t.Errorf("over budget: exit code %d\n%s", code, stderr)
+	}
+
+	code, stdout, stderr := runCommand(t, "context", "-ignore-rules", rules, "-max-tokens", "400", "-truncate", root)
+	if code != 0 || !strings.HasPrefix(stderr, "truncated big.txt (") || !strings.Contains(stdout, "<file path=\"main.go\">") {
+		t.Errorf("with -truncate: exit code %d\n%s", code, stderr)
+	}
+}
+
+func TestContextCommandErrors(t *testing.T) {
+	root, rules := cliProject(t)
+	tests := []struct {
+		args []string
+		code int
+		msg  string
+	}{
+		{[]string{root, root}, 2, "Usage:"},
+		{[]string{filepath.Join(root, "main.go")}, 1, "is not a directory"},
+		{[]string{filepath.Join(root, "missing")}, 1, "is not a directory"},
+		{[]string{"-format", "yaml", root}, 2, "unknown output format"},
+		{[]string{"-symlinks", "maybe", root}, 2, "unknown symlink policy"},
+		{[]string{"-git-select", "dirty", root}, 2, "dirty"},
+		{[]string{"-git-diff", "dirty", root}, 2, "dirty"},
+		{[]string{"-include-glob", "src/[a-", root}, 2, "invalid glob pattern"},
+		{[]string{"-deps", "1", root}, 2, "-deps needs files selected"},
+		{[]string{"-ignore-rules", filepath.Join(root, "missing.glob"), root}, 1, "failed to read ignore rules"},
+		{[]string{"-model", "no-such-model", root}, 1, "unknown context window"},
+		{[]string{"-no-such-flag", root}, 2, "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		code, _, stderr := runCommand(t, append([]string{"context", "-ignore-rules", rules}, tt.args...)...)
+		if code != tt.code || !strings.Contains(stderr, tt.msg) {
+			t.Errorf("context %q = %d\n%s\nwant %d and %q", tt.args, code, stderr, tt.code, tt.msg)
+		}
+	}
+}
+
+func TestExtractCommand(t *testing.T) {
+	root, rules := cliProject(t)
+	for _, format := range []string{shotgun.FormatShotgun, shotgun.FormatJSON, shotgun.FormatXML} {
+		t.Run(format, func(t *testing.T) {
+			payload := filepath.Join(t.TempDir(), "payload")
+			if code, _, stderr := runCommand(t, "context", "-ignore-rules", rules, "-format", format, "-omit", "docs/guide.md", "-o", payload, root); code != 0 {
+				t.Fatalf("context: %d\n%s", code, stderr)
+			}
+
+			code, stdout, stderr := runCommand(t, "extract", "-list", payload)
+			want := "docs/guide.md (omitted)\ninternal/a.go (17 bytes)\ninternal/a_test (10 bytes)\n.gitignore (6 bytes)\nmain.go (45 bytes)\n"
+			if code != 0 || stdout != want {
+				t.Errorf("extract -list = %d\n%s\nwant\n%s%s", code, stdout, want, stderr)
+			}
+
+			out := t.TempDir()
+			code, _, stderr = runCommand(t, "extract", "-o", out, payload)
+			if code != 0 || !strings.Contains(stderr, "Wrote 4 files to "+out+" (1 placeholders skipped)") {
+				t.Fatalf("extract = %d\n%s", code, stderr)
+			}
+			for _, name := range []string{".gitignore", "main.go", "internal/a.go", "internal/a_test"} {
+				got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+				want, _ := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+				if err != nil || !bytes.Equal(got, want) {
+					t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(out, "docs", "guide.md")); !os.IsNotExist(err) {
+				t.Errorf("the omitted file was written: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractCommandStdin(t *testing.T) {
+	payload := "project/\n└── a.txt\n\n<file path=\"a.txt\">\nhello\n</file>"
+	out := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := runExtractCommand([]string{"-o", out, "-"}, strings.NewReader(payload), &stdout, &stderr); code != 0 {
+		t.Fatalf("extract - = %d\n%s", code, stderr.String())
+	}
+	if got, err := os.ReadFile(filepath.Join(out, "a.txt")); err != nil || string(got) != "hello" {
+		t.Errorf("a.txt = %q, %v", got, err)
+	}
+
+	tests := []struct {
+		args  []string
+		stdin string
+		code  int
+		msg   string
+	}{
+		{[]string{"a", "b"}, "", 2, "Usage:"},
+		{[]string{filepath.Join(out, "missing")}, "", 1, "failed to read payload"},
+		{[]string{"-"}, "# Project tree\n\n```text\nproject\n```\n", 1, "error:"},
+		{[]string{"-o", out, "-"}, "project/\n└── x\n\n<file path=\"../escape.txt\">\nx\n</file>", 1, "error:"},
+	}
+	for _, tt := range tests {
+		stderr.Reset()
+		if code := runExtractCommand(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr); code != tt.code || !strings.Contains(stderr.String(), tt.msg) {
+			t.Errorf("extract %q = %d\n%s\nwant %d and %q", tt.args, code, stderr.String(), tt.code, tt.msg)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "..", "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("a file was written outside the output directory: %v", err)
+	}
+}
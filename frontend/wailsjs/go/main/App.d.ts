@@ -2,50 +2,107 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 import {provider} from '../models';
+import {shotgun} from '../models';
 import {context} from '../models';
 
+export function CancelLLMExecution(arg1:string):Promise<void>;
+
+export function CancelTreeIndex():Promise<void>;
+
 export function ClearPromptHistory():Promise<void>;
 
+export function DiscoverLocalLlmModels(arg1:string):Promise<Array<provider.ModelInfo>>;
+
 export function ExecuteLLMPrompt(arg1:string,arg2:string):Promise<main.PromptHistoryItem>;
 
+export function ExpandDependencies(arg1:string,arg2:Array<string>,arg3:number):Promise<Array<shotgun.Dependency>>;
+
 export function GetAutoContextButtonTexture():Promise<string>;
 
+export function GetContextFormats():Promise<Array<string>>;
+
 export function GetCustomIgnoreRules():Promise<string>;
 
 export function GetCustomPromptRules():Promise<string>;
 
+export function GetEffectivePromptRules():Promise<string>;
+
+export function GetFileSymbols(arg1:string,arg2:string):Promise<Array<string>>;
+
+export function GetFitTokenBudget():Promise<boolean>;
+
+export function GetGitSelectionModes():Promise<Array<string>>;
+
+export function GetLLMSpend():Promise<Array<main.LLMSpend>>;
+
 export function GetLlmSettings():Promise<main.LLMSettings>;
 
+export function GetProjectConfig():Promise<shotgun.ProjectConfig>;
+
 export function GetPromptHistory():Promise<Array<main.PromptHistoryItem>>;
 
+export function GetReencodeText():Promise<boolean>;
+
+export function GetRunningLLMExecutions():Promise<Array<main.LLMExecution>>;
+
+export function GetShotgunContextManifest():Promise<shotgun.Manifest>;
+
+export function GetSymlinkPolicies():Promise<Array<string>>;
+
+export function GetSymlinkPolicy():Promise<string>;
+
 export function HasActiveLlmKey():Promise<boolean>;
 
-export function ListFiles(arg1:string):Promise<Array<main.FileNode>>;
+export function ListDirectory(arg1:string,arg2:string,arg3:boolean,arg4:boolean,arg5:number,arg6:number):Promise<shotgun.DirectoryPage>;
+
+export function ListFiles(arg1:string):Promise<Array<shotgun.FileNode>>;
 
 export function ListLlmModels(arg1:string):Promise<Array<provider.ModelInfo>>;
 
 export function LoadRepoScan(arg1:string):Promise<string>;
 
+export function MaterializeShotgunContext(arg1:string,arg2:string):Promise<Array<string>>;
+
+export function ParseShotgunContext(arg1:string):Promise<shotgun.ParsedPayload>;
+
 export function RequestAutoContextSelection(arg1:string,arg2:Array<string>,arg3:string):Promise<Array<string>>;
 
 export function RequestShotgunContextGeneration(arg1:string,arg2:Array<string>):Promise<void>;
 
+export function RequestShotgunContextGenerationWithOptions(arg1:string,arg2:Array<string>,arg3:main.ContextGenerationOptions):Promise<void>;
+
 export function SaveRepoScan(arg1:string,arg2:string):Promise<void>;
 
 export function SelectDirectory():Promise<string>;
 
+export function SelectGitFiles(arg1:string,arg2:Array<string>,arg3:string):Promise<Array<string>>;
+
 export function SetCustomIgnoreRules(arg1:string):Promise<void>;
 
 export function SetCustomPromptRules(arg1:string):Promise<void>;
 
+export function SetFitTokenBudget(arg1:boolean):Promise<void>;
+
 export function SetLlmApiKey(arg1:string,arg2:string):Promise<void>;
 
 export function SetLlmBaseURL(arg1:string):Promise<void>;
 
+export function SetLlmLocalBaseURL(arg1:string):Promise<void>;
+
+export function SetLlmLocalContextLength(arg1:number):Promise<void>;
+
 export function SetLlmModel(arg1:string,arg2:string):Promise<void>;
 
 export function SetLlmProvider(arg1:string):Promise<void>;
 
+export function SetLlmThinkingBudget(arg1:number):Promise<void>;
+
+export function SetLlmTimeout(arg1:number):Promise<void>;
+
+export function SetReencodeText(arg1:boolean):Promise<void>;
+
+export function SetSymlinkPolicy(arg1:string):Promise<void>;
+
 export function SetUseCustomIgnore(arg1:boolean):Promise<void>;
 
 export function SetUseGitignore(arg1:boolean):Promise<void>;
@@ -54,6 +111,8 @@ export function SplitShotgunDiff(arg1:string,arg2:number):Promise<Array<string>>
 
 export function StartFileWatcher(arg1:string):Promise<void>;
 
+export function StartTreeIndex(arg1:string,arg2:boolean,arg3:boolean):Promise<void>;
+
 export function StartupTest(arg1:context.Context):Promise<void>;
 
 export function StopFileWatcher():Promise<void>;
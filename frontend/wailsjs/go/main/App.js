@@ -2,18 +2,38 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CancelLLMExecution(arg1) {
+  return window['go']['main']['App']['CancelLLMExecution'](arg1);
+}
+
+export function CancelTreeIndex() {
+  return window['go']['main']['App']['CancelTreeIndex']();
+}
+
 export function ClearPromptHistory() {
   return window['go']['main']['App']['ClearPromptHistory']();
 }
 
+export function DiscoverLocalLlmModels(arg1) {
+  return window['go']['main']['App']['DiscoverLocalLlmModels'](arg1);
+}
+
 export function ExecuteLLMPrompt(arg1, arg2) {
   return window['go']['main']['App']['ExecuteLLMPrompt'](arg1, arg2);
 }
 
+export function ExpandDependencies(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExpandDependencies'](arg1, arg2, arg3);
+}
+
 export function GetAutoContextButtonTexture() {
   return window['go']['main']['App']['GetAutoContextButtonTexture']();
 }
 
+export function GetContextFormats() {
+  return window['go']['main']['App']['GetContextFormats']();
+}
+
 export function GetCustomIgnoreRules() {
   return window['go']['main']['App']['GetCustomIgnoreRules']();
 }
@@ -22,18 +42,66 @@ export function GetCustomPromptRules() {
   return window['go']['main']['App']['GetCustomPromptRules']();
 }
 
+export function GetEffectivePromptRules() {
+  return window['go']['main']['App']['GetEffectivePromptRules']();
+}
+
+export function GetFileSymbols(arg1, arg2) {
+  return window['go']['main']['App']['GetFileSymbols'](arg1, arg2);
+}
+
+export function GetFitTokenBudget() {
+  return window['go']['main']['App']['GetFitTokenBudget']();
+}
+
+export function GetGitSelectionModes() {
+  return window['go']['main']['App']['GetGitSelectionModes']();
+}
+
+export function GetLLMSpend() {
+  return window['go']['main']['App']['GetLLMSpend']();
+}
+
 export function GetLlmSettings() {
   return window['go']['main']['App']['GetLlmSettings']();
 }
 
+export function GetProjectConfig() {
+  return window['go']['main']['App']['GetProjectConfig']();
+}
+
 export function GetPromptHistory() {
   return window['go']['main']['App']['GetPromptHistory']();
 }
 
+export function GetReencodeText() {
+  return window['go']['main']['App']['GetReencodeText']();
+}
+
+export function GetRunningLLMExecutions() {
+  return window['go']['main']['App']['GetRunningLLMExecutions']();
+}
+
+export function GetShotgunContextManifest() {
+  return window['go']['main']['App']['GetShotgunContextManifest']();
+}
+
+export function GetSymlinkPolicies() {
+  return window['go']['main']['App']['GetSymlinkPolicies']();
+}
+
+export function GetSymlinkPolicy() {
+  return window['go']['main']['App']['GetSymlinkPolicy']();
+}
+
 export function HasActiveLlmKey() {
   return window['go']['main']['App']['HasActiveLlmKey']();
 }
 
+export function ListDirectory(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['ListDirectory'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
 export function ListFiles(arg1) {
   return window['go']['main']['App']['ListFiles'](arg1);
 }
@@ -46,6 +114,14 @@ export function LoadRepoScan(arg1) {
   return window['go']['main']['App']['LoadRepoScan'](arg1);
 }
 
+export function MaterializeShotgunContext(arg1, arg2) {
+  return window['go']['main']['App']['MaterializeShotgunContext'](arg1, arg2);
+}
+
+export function ParseShotgunContext(arg1) {
+  return window['go']['main']['App']['ParseShotgunContext'](arg1);
+}
+
 export function RequestAutoContextSelection(arg1, arg2, arg3) {
   return window['go']['main']['App']['RequestAutoContextSelection'](arg1, arg2, arg3);
 }
@@ -54,6 +130,10 @@ export function RequestShotgunContextGeneration(arg1, arg2) {
   return window['go']['main']['App']['RequestShotgunContextGeneration'](arg1, arg2);
 }
 
+export function RequestShotgunContextGenerationWithOptions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RequestShotgunContextGenerationWithOptions'](arg1, arg2, arg3);
+}
+
 export function SaveRepoScan(arg1, arg2) {
   return window['go']['main']['App']['SaveRepoScan'](arg1, arg2);
 }
@@ -62,6 +142,10 @@ export function SelectDirectory() {
   return window['go']['main']['App']['SelectDirectory']();
 }
 
+export function SelectGitFiles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SelectGitFiles'](arg1, arg2, arg3);
+}
+
 export function SetCustomIgnoreRules(arg1) {
   return window['go']['main']['App']['SetCustomIgnoreRules'](arg1);
 }
@@ -70,6 +154,10 @@ export function SetCustomPromptRules(arg1) {
   return window['go']['main']['App']['SetCustomPromptRules'](arg1);
 }
 
+export function SetFitTokenBudget(arg1) {
+  return window['go']['main']['App']['SetFitTokenBudget'](arg1);
+}
+
 export function SetLlmApiKey(arg1, arg2) {
   return window['go']['main']['App']['SetLlmApiKey'](arg1, arg2);
 }
@@ -78,6 +166,14 @@ export function SetLlmBaseURL(arg1) {
   return window['go']['main']['App']['SetLlmBaseURL'](arg1);
 }
 
+export function SetLlmLocalBaseURL(arg1) {
+  return window['go']['main']['App']['SetLlmLocalBaseURL'](arg1);
+}
+
+export function SetLlmLocalContextLength(arg1) {
+  return window['go']['main']['App']['SetLlmLocalContextLength'](arg1);
+}
+
 export function SetLlmModel(arg1, arg2) {
   return window['go']['main']['App']['SetLlmModel'](arg1, arg2);
 }
@@ -86,6 +182,22 @@ export function SetLlmProvider(arg1) {
   return window['go']['main']['App']['SetLlmProvider'](arg1);
 }
 
+export function SetLlmThinkingBudget(arg1) {
+  return window['go']['main']['App']['SetLlmThinkingBudget'](arg1);
+}
+
+export function SetLlmTimeout(arg1) {
+  return window['go']['main']['App']['SetLlmTimeout'](arg1);
+}
+
+export function SetReencodeText(arg1) {
+  return window['go']['main']['App']['SetReencodeText'](arg1);
+}
+
+export function SetSymlinkPolicy(arg1) {
+  return window['go']['main']['App']['SetSymlinkPolicy'](arg1);
+}
+
 export function SetUseCustomIgnore(arg1) {
   return window['go']['main']['App']['SetUseCustomIgnore'](arg1);
 }
@@ -102,6 +214,10 @@ export function StartFileWatcher(arg1) {
   return window['go']['main']['App']['StartFileWatcher'](arg1);
 }
 
+export function StartTreeIndex(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StartTreeIndex'](arg1, arg2, arg3);
+}
+
 export function StartupTest(arg1) {
   return window['go']['main']['App']['StartupTest'](arg1);
 }
@@ -1,27 +1,94 @@
 export namespace main {
 	
-	export class FileNode {
-	    name: string;
-	    path: string;
-	    relPath: string;
-	    isDir: boolean;
-	    children?: FileNode[];
-	    isGitignored: boolean;
-	    isCustomIgnored: boolean;
+	export class ContextGenerationOptions {
+	    truncateToBudget: boolean;
+	    format: string;
+	    includePatterns: string[];
+	    excludePatterns: string[];
+	    forceInclude: string[];
+	    gitDiff: string;
+	    gitBaseRef: string;
+	    gitHistory: number;
+	    gitHistoryBytes: number;
+	    gitBlame: boolean;
+	    gitBlameBytes: number;
+	    symbols: Record<string, Array<string>>;
+	    renderModes: Record<string, string>;
 	
 	    static createFrom(source: any = {}) {
-	        return new FileNode(source);
+	        return new ContextGenerationOptions(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.path = source["path"];
-	        this.relPath = source["relPath"];
-	        this.isDir = source["isDir"];
-	        this.children = this.convertValues(source["children"], FileNode);
-	        this.isGitignored = source["isGitignored"];
-	        this.isCustomIgnored = source["isCustomIgnored"];
+	        this.truncateToBudget = source["truncateToBudget"];
+	        this.format = source["format"];
+	        this.includePatterns = source["includePatterns"];
+	        this.excludePatterns = source["excludePatterns"];
+	        this.forceInclude = source["forceInclude"];
+	        this.gitDiff = source["gitDiff"];
+	        this.gitBaseRef = source["gitBaseRef"];
+	        this.gitHistory = source["gitHistory"];
+	        this.gitHistoryBytes = source["gitHistoryBytes"];
+	        this.gitBlame = source["gitBlame"];
+	        this.gitBlameBytes = source["gitBlameBytes"];
+	        this.symbols = source["symbols"];
+	        this.renderModes = source["renderModes"];
+	    }
+	}
+	export class LLMCallStats {
+	    provider: string;
+	    model: string;
+	    inputTokens: number;
+	    outputTokens: number;
+	    reasoningTokens?: number;
+	    cacheReadTokens?: number;
+	    usageEstimated?: boolean;
+	    latencyMs: number;
+	    costUSD?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LLMCallStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.inputTokens = source["inputTokens"];
+	        this.outputTokens = source["outputTokens"];
+	        this.reasoningTokens = source["reasoningTokens"];
+	        this.cacheReadTokens = source["cacheReadTokens"];
+	        this.usageEstimated = source["usageEstimated"];
+	        this.latencyMs = source["latencyMs"];
+	        this.costUSD = source["costUSD"];
+	    }
+	}
+	export class LLMExecution {
+	    id: string;
+	    kind: string;
+	    provider: string;
+	    model: string;
+	    status: string;
+	    error?: string;
+	    // Go type: time
+	    startedAt: any;
+	    elapsedMs?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LLMExecution(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.elapsedMs = source["elapsedMs"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -48,7 +115,12 @@ export namespace main {
 	    openAIKey: string;
 	    openRouterKey: string;
 	    geminiKey: string;
+	    anthropicKey: string;
 	    baseURL: string;
+	    timeoutSeconds: number;
+	    anthropicThinkingBudget: number;
+	    localBaseURL: string;
+	    localContextLength: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new LLMSettings(source);
@@ -61,7 +133,42 @@ export namespace main {
 	        this.openAIKey = source["openAIKey"];
 	        this.openRouterKey = source["openRouterKey"];
 	        this.geminiKey = source["geminiKey"];
+	        this.anthropicKey = source["anthropicKey"];
 	        this.baseURL = source["baseURL"];
+	        this.timeoutSeconds = source["timeoutSeconds"];
+	        this.anthropicThinkingBudget = source["anthropicThinkingBudget"];
+	        this.localBaseURL = source["localBaseURL"];
+	        this.localContextLength = source["localContextLength"];
+	    }
+	}
+	export class LLMSpend {
+	    day: string;
+	    provider: string;
+	    model: string;
+	    calls: number;
+	    inputTokens: number;
+	    outputTokens: number;
+	    reasoningTokens: number;
+	    costUSD: number;
+	    unpricedCalls: number;
+	    estimatedCalls: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LLMSpend(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.day = source["day"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.calls = source["calls"];
+	        this.inputTokens = source["inputTokens"];
+	        this.outputTokens = source["outputTokens"];
+	        this.reasoningTokens = source["reasoningTokens"];
+	        this.costUSD = source["costUSD"];
+	        this.unpricedCalls = source["unpricedCalls"];
+	        this.estimatedCalls = source["estimatedCalls"];
 	    }
 	}
 	export class PromptHistoryItem {
@@ -72,6 +179,7 @@ export namespace main {
 	    constructedPrompt: string;
 	    response: string;
 	    apiCall?: string;
+	    stats?: LLMCallStats;
 	
 	    static createFrom(source: any = {}) {
 	        return new PromptHistoryItem(source);
@@ -85,6 +193,7 @@ export namespace main {
 	        this.constructedPrompt = source["constructedPrompt"];
 	        this.response = source["response"];
 	        this.apiCall = source["apiCall"];
+	        this.stats = this.convertValues(source["stats"], LLMCallStats);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -113,6 +222,7 @@ export namespace provider {
 	export class ModelInfo {
 	    name: string;
 	    description?: string;
+	    contextWindow?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new ModelInfo(source);
@@ -122,8 +232,286 @@ export namespace provider {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.name = source["name"];
 	        this.description = source["description"];
+	        this.contextWindow = source["contextWindow"];
 	    }
 	}
 
 }
 
+export namespace shotgun {
+	
+	export class Dependency {
+	    path: string;
+	    from: string;
+	    import: string;
+	    depth: number;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Dependency(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.from = source["from"];
+	        this.import = source["import"];
+	        this.depth = source["depth"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class FileNode {
+	    name: string;
+	    path: string;
+	    relPath: string;
+	    isDir: boolean;
+	    children?: FileNode[];
+	    isGitignored: boolean;
+	    isCustomIgnored: boolean;
+	    symlinkTarget?: string;
+	    childCount?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.relPath = source["relPath"];
+	        this.isDir = source["isDir"];
+	        this.children = this.convertValues(source["children"], FileNode);
+	        this.isGitignored = source["isGitignored"];
+	        this.isCustomIgnored = source["isCustomIgnored"];
+	        this.symlinkTarget = source["symlinkTarget"];
+	        this.childCount = source["childCount"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DirectoryPage {
+	    folder?: FileNode;
+	    offset: number;
+	    total: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DirectoryPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = this.convertValues(source["folder"], FileNode);
+	        this.offset = source["offset"];
+	        this.total = source["total"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ManifestEntry {
+	    path: string;
+	    bytes: number;
+	    lines: number;
+	    tokens: number;
+	    truncated: boolean;
+	    binary: boolean;
+	    encoding?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ManifestEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.bytes = source["bytes"];
+	        this.lines = source["lines"];
+	        this.tokens = source["tokens"];
+	        this.truncated = source["truncated"];
+	        this.binary = source["binary"];
+	        this.encoding = source["encoding"];
+	    }
+	}
+	export class SectionEntry {
+	    name: string;
+	    bytes: number;
+	    tokens: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SectionEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.bytes = source["bytes"];
+	        this.tokens = source["tokens"];
+	    }
+	}
+	export class Manifest {
+	    rootDir: string;
+	    files: ManifestEntry[];
+	    treeTokens: number;
+	    totalBytes: number;
+	    totalTokens: number;
+	    sections?: SectionEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Manifest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rootDir = source["rootDir"];
+	        this.files = this.convertValues(source["files"], ManifestEntry);
+	        this.treeTokens = source["treeTokens"];
+	        this.totalBytes = source["totalBytes"];
+	        this.totalTokens = source["totalTokens"];
+	        this.sections = this.convertValues(source["sections"], SectionEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ParsedFile {
+	    path: string;
+	    content: string;
+	    omitted?: boolean;
+	    partial?: boolean;
+	    attrs?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new ParsedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.content = source["content"];
+	        this.omitted = source["omitted"];
+	        this.partial = source["partial"];
+	        this.attrs = source["attrs"];
+	    }
+	}
+	export class ParsedSection {
+	    name: string;
+	    content: string;
+	    attrs?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new ParsedSection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.content = source["content"];
+	        this.attrs = source["attrs"];
+	    }
+	}
+	export class ParsedPayload {
+	    tree: string;
+	    files: ParsedFile[];
+	    sections?: ParsedSection[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ParsedPayload(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tree = source["tree"];
+	        this.files = this.convertValues(source["files"], ParsedFile);
+	        this.sections = this.convertValues(source["sections"], ParsedSection);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProjectConfig {
+	    ignoreRules?: string;
+	    include?: string[];
+	    exclude?: string[];
+	    promptRules?: string;
+	    provider?: string;
+	    model?: string;
+	    sources?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ignoreRules = source["ignoreRules"];
+	        this.include = source["include"];
+	        this.exclude = source["exclude"];
+	        this.promptRules = source["promptRules"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.sources = source["sources"];
+	    }
+	}
+}
@@ -19,6 +19,8 @@ type PromptHistoryItem struct {
 	ConstructedPrompt string    `json:"constructedPrompt"`
 	Response          string    `json:"response"`
 	APICall           string    `json:"apiCall,omitempty"`
+	// Stats are the usage numbers of the LLM call; nil for items recorded before they were kept.
+	Stats *LLMCallStats `json:"stats,omitempty"`
 }
 
 type PromptHistory struct {
@@ -96,7 +98,7 @@ func (hm *HistoryManager) SaveHistory() error {
 	return os.WriteFile(path, data, 0644)
 }
 
-func (hm *HistoryManager) AddItem(userTask, constructedPrompt, response, apiCall string) PromptHistoryItem {
+func (hm *HistoryManager) AddItem(userTask, constructedPrompt, response, apiCall string, stats *LLMCallStats) PromptHistoryItem {
 	hm.mu.Lock()
 	// Generate simple ID based on timestamp
 	now := time.Now()
@@ -107,6 +109,7 @@ func (hm *HistoryManager) AddItem(userTask, constructedPrompt, response, apiCall
 		ConstructedPrompt: constructedPrompt,
 		Response:          response,
 		APICall:           apiCall,
+		Stats:             stats,
 	}
 	// Prepend to keep newest first
 	hm.history.Items = append([]PromptHistoryItem{item}, hm.history.Items...)
@@ -145,7 +148,7 @@ func (a *App) ExecuteLLMPrompt(userTask, finalPrompt string) (PromptHistoryItem,
 		return PromptHistoryItem{}, errors.New("no active LLM configuration found")
 	}
 
-	cfg := buildProviderConfig(a.settings.LLMSettings)
+	cfg := buildProviderConfig(a.effectiveLLMSettings())
 	providerInstance, err := a.getOrCreateProvider(cfg)
 	if err != nil {
 		return PromptHistoryItem{}, fmt.Errorf("failed to create provider: %w", err)
@@ -153,16 +156,22 @@ func (a *App) ExecuteLLMPrompt(userTask, finalPrompt string) (PromptHistoryItem,
 
 	wailsRuntime.LogInfof(a.ctx, "Executing LLM prompt via %s (%s)...", cfg.Provider, cfg.Model)
 
-	// Use provider.Generate. Note: we don't have streaming here yet, so it waits for full response.
-	response, apiCall, err := providerInstance.Generate(a.ctx, finalPrompt)
+	// The answer is streamed to the frontend as "llmResponseChunk" events, tagged with the
+	// execution ID, while it arrives; the returned history item carries the complete text.
+	// CancelLLMExecution stops the call.
+	callCtx, executionID, finish := a.startLLMExecution(LLMExecutionPrompt, cfg)
+	result, err := providerInstance.GenerateStream(callCtx, finalPrompt, func(chunk string) {
+		wailsRuntime.EventsEmit(a.ctx, "llmResponseChunk", LLMResponseChunk{ID: executionID, Chunk: chunk})
+	})
+	err = finish(err)
 
 	var historyItem PromptHistoryItem
 	if a.historyManager != nil {
-		historyResponse := response
+		historyResponse := result.Text
 		if err != nil {
 			historyResponse = fmt.Sprintf("ERROR during prompt execution: %v", err)
 		}
-		historyItem = a.historyManager.AddItem(userTask, finalPrompt, historyResponse, apiCall)
+		historyItem = a.historyManager.AddItem(userTask, finalPrompt, historyResponse, result.APICall, newLLMCallStats(cfg, result, finalPrompt))
 	}
 
 	if err != nil {
@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+	// anthropicMaxTokens caps the answer, thinking included. All catalog models allow it.
+	anthropicMaxTokens = 32000
+	// MinAnthropicThinkingBudget is the smallest thinking budget the Messages API accepts, and
+	// MaxAnthropicThinkingBudget the largest that leaves room for the answer.
+	MinAnthropicThinkingBudget = 1024
+	MaxAnthropicThinkingBudget = anthropicMaxTokens - 1
+)
+
+type anthropicProvider struct {
+	model          string
+	apiKey         string
+	baseURL        string
+	thinkingBudget int
+}
+
+func newAnthropicProvider(cfg Config) (LLMProvider, error) {
+	if strings.TrimSpace(cfg.APIKey) == "" {
+		return nil, errors.New("anthropic provider requires an API key")
+	}
+	if strings.TrimSpace(cfg.Model) == "" {
+		return nil, errors.New("anthropic provider requires a model")
+	}
+	if cfg.ThinkingBudget != 0 && (cfg.ThinkingBudget < MinAnthropicThinkingBudget || cfg.ThinkingBudget > MaxAnthropicThinkingBudget) {
+		return nil, fmt.Errorf("anthropic thinking budget must be between %d and %d tokens", MinAnthropicThinkingBudget, MaxAnthropicThinkingBudget)
+	}
+
+	baseURL := defaultAnthropicBaseURL
+	if trimmed := strings.TrimSpace(cfg.BaseURL); trimmed != "" {
+		baseURL = trimmed
+	}
+
+	return &anthropicProvider{
+		model:          strings.TrimSpace(cfg.Model),
+		apiKey:         strings.TrimSpace(cfg.APIKey),
+		baseURL:        baseURL,
+		thinkingBudget: cfg.ThinkingBudget,
+	}, nil
+}
+
+func (a *anthropicProvider) ListModels(_ context.Context) ([]ModelInfo, error) {
+	return ModelCatalog("anthropic")
+}
+
+func (a *anthropicProvider) Generate(ctx context.Context, prompt string) (Result, error) {
+	return a.generate(ctx, prompt, nil)
+}
+
+func (a *anthropicProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
+	return a.generate(ctx, prompt, onChunk)
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+type anthropicContentBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicThinkingConfig struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicMessagesRequest struct {
+	Model       string                   `json:"model"`
+	MaxTokens   int                      `json:"max_tokens"`
+	Messages    []anthropicMessage       `json:"messages"`
+	Thinking    *anthropicThinkingConfig `json:"thinking,omitempty"`
+	Temperature *float64                 `json:"temperature,omitempty"` // Not allowed with thinking
+	Stream      bool                     `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// usage converts the usage block. Anthropic counts cached input apart from input_tokens;
+// Usage.InputTokens includes it.
+func (u *anthropicUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:      u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type anthropicMessagesResponse struct {
+	Model      string                  `json:"model"`
+	Content    []anthropicContentBlock `json:"content"` // Thinking blocks come before the text
+	StopReason string                  `json:"stop_reason"`
+	Usage      *anthropicUsage         `json:"usage"`
+}
+
+// anthropicStreamEvent is the data of any Messages API stream event; the fields that apply
+// depend on Type.
+type anthropicStreamEvent struct {
+	Type    string                     `json:"type"`
+	Message *anthropicMessagesResponse `json:"message"` // message_start
+	Delta   struct {
+		Type       string `json:"type"` // text_delta, thinking_delta, signature_delta, ...
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"` // message_delta
+	} `json:"delta"` // content_block_delta and message_delta
+	Usage *anthropicUsage `json:"usage"` // message_delta, with the final output token count
+	Error *anthropicError `json:"error"` // error
+}
+
+// anthropicContentBlocks splits prompt, in its original order, into the content blocks of the
+// user message. When the prompt carries the project context (<file path="..."> blocks, then any
+// sections), Anthropic's prompt cache breakpoint is put at the end of the context, wherever it
+// sits: the block up to there is cached, so sending the same prompt again (a retry, another
+// model run of the same task) or another task after a context placed first reads that prefix
+// from the cache. Prompts without a context are sent as a single block without caching.
+func anthropicContentBlocks(prompt string) []anthropicContentBlock {
+	end := contextEnd(prompt)
+	if end < 0 {
+		return []anthropicContentBlock{{Type: "text", Text: prompt}}
+	}
+	blocks := []anthropicContentBlock{{Type: "text", Text: prompt[:end], CacheControl: &anthropicCacheControl{Type: "ephemeral"}}}
+	if end < len(prompt) {
+		blocks = append(blocks, anthropicContentBlock{Type: "text", Text: prompt[end:]})
+	}
+	return blocks
+}
+
+// contextEnd returns the offset just past the last closing </file> or </section> tag of the
+// project context in prompt, or -1 when prompt carries no file blocks.
+func contextEnd(prompt string) int {
+	start := strings.Index(prompt, "<file path=")
+	closing := max(strings.LastIndex(prompt, "</file"), strings.LastIndex(prompt, "</section"))
+	if start < 0 || closing < start {
+		return -1
+	}
+	if i := strings.Index(prompt[closing:], ">"); i >= 0 {
+		return closing + i + 1
+	}
+	return len(prompt)
+}
+
+// generate calls the Messages API, streaming the answer when onChunk is set.
+func (a *anthropicProvider) generate(ctx context.Context, prompt string, onChunk func(string)) (result Result, err error) {
+	defer finishResult(&result, a.model, time.Now())
+	endpoint := strings.TrimRight(a.baseURL, "/") + "/v1/messages"
+
+	payload := anthropicMessagesRequest{
+		Model:     a.model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: anthropicContentBlocks(prompt)}},
+		Stream:    onChunk != nil,
+	}
+	if a.thinkingBudget > 0 {
+		payload.Thinking = &anthropicThinkingConfig{Type: "enabled", BudgetTokens: a.thinkingBudget}
+	} else {
+		temperature := 0.1
+		payload.Temperature = &temperature
+	}
+
+	// Build sanitized debug view BEFORE marshalling real payload.
+	debugPayload := payload
+	debugPayload.Messages = []anthropicMessage{{Role: "user", Content: make([]anthropicContentBlock, len(payload.Messages[0].Content))}}
+	for i, block := range payload.Messages[0].Content {
+		block.Text = "[request_text]"
+		if block.CacheControl != nil {
+			block.Text = "[cached_request_text]"
+		}
+		debugPayload.Messages[0].Content[i] = block
+	}
+	debug := map[string]any{
+		"provider": "anthropic",
+		"endpoint": endpoint,
+		"method":   http.MethodPost,
+		"headers": map[string]string{
+			"x-api-key":         "[apikey]",
+			"anthropic-version": anthropicAPIVersion,
+			"Content-Type":      "application/json",
+		},
+		"body": debugPayload,
+	}
+	debugBytes, _ := json.MarshalIndent(debug, "", "  ")
+	result.APICall = string(debugBytes)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal Anthropic messages payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("failed to create Anthropic messages request: %w", err)
+	}
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+	req.Header.Set("Content-Type", "application/json")
+	if onChunk != nil {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("anthropic messages request failed (model=%s): %v", a.model, err)
+		return result, fmt.Errorf("anthropic messages request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		limitedBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("anthropic messages returned status %d for model %s: %s", resp.StatusCode, a.model, string(limitedBody))
+		var decoded struct {
+			Error *anthropicError `json:"error"`
+		}
+		if json.Unmarshal(limitedBody, &decoded) == nil && decoded.Error != nil {
+			return result, fmt.Errorf("anthropic messages API returned status %d: %s", resp.StatusCode, decoded.Error.Message)
+		}
+		return result, fmt.Errorf("anthropic messages API returned non-2xx status %d", resp.StatusCode)
+	}
+
+	var decoded anthropicMessagesResponse
+	if onChunk != nil {
+		decoded, err = readAnthropicStream(resp.Body, onChunk)
+		if err != nil {
+			log.Printf("anthropic messages stream failed for model %s: %v", a.model, err)
+		}
+	} else if err = json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		log.Printf("failed to decode anthropic messages payload for model %s: %v", a.model, err)
+		err = fmt.Errorf("failed to decode anthropic messages payload: %w", err)
+	}
+	result.Model = decoded.Model
+	result.Usage = decoded.Usage.usage()
+	if err != nil {
+		return result, err
+	}
+
+	var sb strings.Builder
+	for _, block := range decoded.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	result.Text = strings.TrimSpace(sb.String())
+	if result.Text == "" {
+		log.Printf("anthropic messages response contained no text for model %s (stop reason %q)", a.model, decoded.StopReason)
+		return result, fmt.Errorf("anthropic messages response did not contain text output (stop reason %q)", decoded.StopReason)
+	}
+	return result, nil
+}
+
+// readAnthropicStream collects a streamed message, passing each text delta to onChunk. Thinking
+// deltas are dropped. The returned message has a single text block with the whole answer, and
+// the usage of message_start updated with the output count of the last message_delta.
+func readAnthropicStream(body io.Reader, onChunk func(string)) (anthropicMessagesResponse, error) {
+	var message anthropicMessagesResponse
+	var sb strings.Builder
+	err := readSSE(body, func(e sseEvent) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(e.data), &event); err != nil {
+			return fmt.Errorf("failed to decode anthropic stream event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				message.Model = event.Message.Model
+				message.Usage = event.Message.Usage
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				sb.WriteString(event.Delta.Text)
+				onChunk(event.Delta.Text)
+			}
+		case "message_delta":
+			message.StopReason = event.Delta.StopReason
+			if event.Usage != nil {
+				if message.Usage == nil {
+					message.Usage = &anthropicUsage{}
+				}
+				message.Usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("anthropic stream error: %s", event.Error.Message)
+			}
+			return errors.New("anthropic stream error")
+		}
+		return nil
+	})
+	message.Content = []anthropicContentBlock{{Type: "text", Text: sb.String()}}
+	return message, err
+}
@@ -0,0 +1,314 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// anthropicStandIn serves the Messages API: it checks the headers, hands the decoded request
+// body to inspect and answers with respond.
+func anthropicStandIn(t *testing.T, inspect func(body map[string]any), respond func(w http.ResponseWriter)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/messages" {
+			t.Errorf("request %s %s, want POST /v1/messages", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != anthropicAPIVersion {
+			t.Errorf("anthropic-version = %q, want %s", got, anthropicAPIVersion)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		inspect(body)
+		respond(w)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAnthropicProvider(t *testing.T, baseURL string, thinkingBudget int) LLMProvider {
+	t.Helper()
+	p, err := Factory(Config{Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "test-key", BaseURL: baseURL, ThinkingBudget: thinkingBudget})
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	return p
+}
+
+// contentBlocks returns the content blocks of the single user message of a request body.
+func contentBlocks(t *testing.T, body map[string]any) []map[string]any {
+	t.Helper()
+	messages, _ := body["messages"].([]any)
+	if len(messages) != 1 {
+		t.Fatalf("messages = %v, want one", body["messages"])
+	}
+	content, _ := messages[0].(map[string]any)["content"].([]any)
+	blocks := make([]map[string]any, len(content))
+	for i, c := range content {
+		blocks[i] = c.(map[string]any)
+	}
+	return blocks
+}
+
+const testPayloadPrompt = "project/\n├── a.go\n└── b.go\n\n<file path=\"a.go\">\npackage a\n</file>\n<file path=\"b.go\">\npackage b\n</file>\nTask: explain b.go"
+
+func TestAnthropicGenerate(t *testing.T) {
+	server := anthropicStandIn(t, func(body map[string]any) {
+		thinking, _ := body["thinking"].(map[string]any)
+		if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(2048) {
+			t.Errorf("thinking = %v, want enabled with 2048 tokens", body["thinking"])
+		}
+		if _, ok := body["temperature"]; ok {
+			t.Errorf("temperature = %v, want none with thinking", body["temperature"])
+		}
+		if _, ok := body["stream"]; ok {
+			t.Errorf("stream = %v, want none", body["stream"])
+		}
+		blocks := contentBlocks(t, body)
+		if len(blocks) != 2 {
+			t.Fatalf("content = %v, want the context and the task", blocks)
+		}
+		if cache, _ := blocks[0]["cache_control"].(map[string]any); cache["type"] != "ephemeral" {
+			t.Errorf("first block cache_control = %v, want ephemeral", blocks[0]["cache_control"])
+		}
+		if _, ok := blocks[1]["cache_control"]; ok {
+			t.Errorf("second block is marked for caching")
+		}
+		if got := blocks[0]["text"].(string) + blocks[1]["text"].(string); got != testPayloadPrompt {
+			t.Errorf("blocks join to %q, want the prompt unchanged", got)
+		}
+	}, func(w http.ResponseWriter) {
+		io.WriteString(w, `{"model":"claude-sonnet-4-5-20250929","content":[{"type":"thinking","thinking":"..."},{"type":"text","text":" b.go declares package b. "}],"stop_reason":"end_turn","usage":{"input_tokens":20,"cache_creation_input_tokens":3000,"cache_read_input_tokens":0,"output_tokens":12}}`)
+	})
+
+	result, err := newTestAnthropicProvider(t, server.URL, 2048).Generate(context.Background(), testPayloadPrompt)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if result.Text != "b.go declares package b." {
+		t.Errorf("Text = %q", result.Text)
+	}
+	if result.Model != "claude-sonnet-4-5-20250929" {
+		t.Errorf("Model = %q", result.Model)
+	}
+	want := Usage{InputTokens: 3020, OutputTokens: 12, CacheWriteTokens: 3000}
+	if result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+	if strings.Contains(result.APICall, "test-key") || strings.Contains(result.APICall, "package a") {
+		t.Errorf("APICall leaks the key or the prompt:\n%s", result.APICall)
+	}
+}
+
+func TestAnthropicGenerateWithoutThinking(t *testing.T) {
+	server := anthropicStandIn(t, func(body map[string]any) {
+		if _, ok := body["thinking"]; ok {
+			t.Errorf("thinking = %v, want none", body["thinking"])
+		}
+		if body["temperature"] != 0.1 {
+			t.Errorf("temperature = %v, want 0.1", body["temperature"])
+		}
+	}, func(w http.ResponseWriter) {
+		io.WriteString(w, `{"model":"claude-sonnet-4-5","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":3,"output_tokens":1}}`)
+	})
+
+	if _, err := newTestAnthropicProvider(t, server.URL, 0).Generate(context.Background(), "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+}
+
+func TestAnthropicGenerateStream(t *testing.T) {
+	server := anthropicStandIn(t, func(body map[string]any) {
+		if body["stream"] != true {
+			t.Errorf("stream = %v, want true", body["stream"])
+		}
+	}, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-sonnet-4-5-20250929\",\"usage\":{\"input_tokens\":5,\"cache_read_input_tokens\":3000,\"output_tokens\":1}}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":4}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	})
+
+	var chunks []string
+	result, err := newTestAnthropicProvider(t, server.URL, 0).GenerateStream(context.Background(), "hi", func(c string) { chunks = append(chunks, c) })
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if result.Text != "Hi" || strings.Join(chunks, "|") != "Hi" {
+		t.Errorf("Text = %q, chunks = %q", result.Text, chunks)
+	}
+	want := Usage{InputTokens: 3005, OutputTokens: 4, CacheReadTokens: 3000}
+	if result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+}
+
+func TestAnthropicGenerateErrorStatus(t *testing.T) {
+	server := anthropicStandIn(t, func(map[string]any) {}, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: too large"}}`)
+	})
+
+	result, err := newTestAnthropicProvider(t, server.URL, 0).Generate(context.Background(), "hi")
+	if err == nil || !strings.Contains(err.Error(), "max_tokens: too large") {
+		t.Fatalf("err = %v, want the API error message", err)
+	}
+	if result.APICall == "" {
+		t.Errorf("APICall is empty after a failed call")
+	}
+}
+
+func TestReadAnthropicStream(t *testing.T) {
+	stream := strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"model":"claude-haiku-4-5","usage":{"input_tokens":7,"cache_creation_input_tokens":100,"output_tokens":1}}}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hidden"}}`,
+		"",
+		"event: ping",
+		`data: {"type":"ping"}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hel"}}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"lo"}}`,
+		"",
+		"event: message_delta",
+		`data: {"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":42}}`,
+		"",
+	}, "\n")
+
+	var chunks []string
+	message, err := readAnthropicStream(strings.NewReader(stream), func(c string) { chunks = append(chunks, c) })
+	if err != nil {
+		t.Fatalf("readAnthropicStream: %v", err)
+	}
+	if strings.Join(chunks, "|") != "Hel|lo" {
+		t.Errorf("chunks = %q, want the text deltas only", chunks)
+	}
+	if len(message.Content) != 1 || message.Content[0].Text != "Hello" {
+		t.Errorf("Content = %+v", message.Content)
+	}
+	if message.Model != "claude-haiku-4-5" || message.StopReason != "max_tokens" {
+		t.Errorf("Model = %q, StopReason = %q", message.Model, message.StopReason)
+	}
+	want := Usage{InputTokens: 107, OutputTokens: 42, CacheWriteTokens: 100}
+	if got := message.Usage.usage(); got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadAnthropicStreamError(t *testing.T) {
+	stream := "event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"partial"}}` + "\n\n" +
+		"event: error\n" +
+		`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n"
+
+	message, err := readAnthropicStream(strings.NewReader(stream), func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "Overloaded") {
+		t.Fatalf("err = %v, want the stream error", err)
+	}
+	if message.Content[0].Text != "partial" {
+		t.Errorf("Content = %+v, want the text received before the error", message.Content)
+	}
+}
+
+func TestAnthropicUsageMapping(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage *anthropicUsage
+		want  Usage
+	}{
+		{"missing", nil, Usage{}},
+		{"uncached", &anthropicUsage{InputTokens: 10, OutputTokens: 5}, Usage{InputTokens: 10, OutputTokens: 5}},
+		{
+			"cache write and read",
+			&anthropicUsage{InputTokens: 10, OutputTokens: 5, CacheCreationInputTokens: 200, CacheReadInputTokens: 3000},
+			Usage{InputTokens: 3210, OutputTokens: 5, CacheReadTokens: 3000, CacheWriteTokens: 200},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.usage.usage(); got != tt.want {
+			t.Errorf("%s: usage() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnthropicContentBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		cached string // Text of the cached first block, "" when nothing is cached
+	}{
+		{"context first", testPayloadPrompt, strings.TrimSuffix(testPayloadPrompt, "\nTask: explain b.go")},
+		{"context only", "<file path=\"a.go\">\nx\n</file>", "<file path=\"a.go\">\nx\n</file>"},
+		{"task first", "Task: explain b.go\n\n" + testPayloadPrompt, strings.TrimSuffix("Task: explain b.go\n\n"+testPayloadPrompt, "\nTask: explain b.go")},
+		{"sections after files", "<file path=\"a.go\">\nx\n</file>\n<section name=\"git-diff\">\nd\n</section>\nExplain.", "<file path=\"a.go\">\nx\n</file>\n<section name=\"git-diff\">\nd\n</section>"},
+		{"task between files", "<file path=\"a.go\">\nx\n</file>\nWhat about this?\n<file path=\"b.go\">\ny\n</file>", "<file path=\"a.go\">\nx\n</file>\nWhat about this?\n<file path=\"b.go\">\ny\n</file>"},
+		{"no files", "Just a question", ""},
+	}
+	for _, tt := range tests {
+		blocks := anthropicContentBlocks(tt.prompt)
+		var joined strings.Builder
+		for _, b := range blocks {
+			joined.WriteString(b.Text)
+		}
+		if joined.String() != tt.prompt {
+			t.Errorf("%s: blocks join to %q, want the prompt unchanged", tt.name, joined.String())
+		}
+		cached := ""
+		for i, b := range blocks {
+			if b.CacheControl != nil {
+				if i != 0 {
+					t.Errorf("%s: block %d is cached, only the first may be", tt.name, i)
+				}
+				cached = b.Text
+			}
+		}
+		if cached != tt.cached {
+			t.Errorf("%s: cached block = %q, want %q", tt.name, cached, tt.cached)
+		}
+	}
+}
+
+// TestAnthropicContentBlocksShippedTemplate builds a prompt the way the compose step does, from
+// a shipped template that puts the project context after the task and the rules.
+func TestAnthropicContentBlocksShippedTemplate(t *testing.T) {
+	template, err := os.ReadFile(filepath.Join("..", "..", "..", "design", "prompts", "prompt_makeDiffGitFormat.md"))
+	if err != nil {
+		t.Fatalf("reading template: %v", err)
+	}
+	payload := strings.TrimSuffix(testPayloadPrompt, "\nTask: explain b.go")
+	prompt := strings.NewReplacer("{TASK}", "Rename package b.", "{RULES}", "Keep it short.", "{FILE_STRUCTURE}", payload).Replace(string(template))
+
+	blocks := anthropicContentBlocks(prompt)
+	if blocks[0].CacheControl == nil {
+		t.Fatalf("first block is not cached: %+v", blocks)
+	}
+	cached := blocks[0].Text
+	if !strings.HasSuffix(cached, payload) || !strings.Contains(cached, "Rename package b.") {
+		t.Errorf("cached block does not run from the template head to the end of the context:\n%s", cached)
+	}
+	var joined strings.Builder
+	for _, b := range blocks {
+		joined.WriteString(b.Text)
+	}
+	if joined.String() != prompt {
+		t.Errorf("blocks do not join to the prompt")
+	}
+}
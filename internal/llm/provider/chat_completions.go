@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// chatStreamOptions asks an OpenAI-compatible server to end a streamed chat completion with a
+// chunk carrying the usage of the call; without it, streams report no usage.
+type chatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatCompletionRequest struct {
+	Model         string                  `json:"model"`
+	Messages      []openRouterChatMessage `json:"messages"`
+	Temperature   float64                 `json:"temperature"`
+	Stream        bool                    `json:"stream"`
+	StreamOptions *chatStreamOptions      `json:"stream_options,omitempty"`
+}
+
+// streamChatCompletion streams a chat completion of the OpenAI-compatible API at baseURL for the
+// named provider. The langchaingo client drops the usage chunk of streams, so streamed calls of
+// SDK-based models are made here instead.
+func streamChatCompletion(ctx context.Context, name, baseURL, apiKey, model, prompt string, onChunk func(string)) (Result, error) {
+	endpoint := strings.TrimRight(baseURL, "/") + "/chat/completions"
+	payload := chatCompletionRequest{
+		Model:         model,
+		Messages:      []openRouterChatMessage{{Role: "user", Content: prompt}},
+		Temperature:   0.1,
+		Stream:        true,
+		StreamOptions: &chatStreamOptions{IncludeUsage: true},
+	}
+
+	// Build sanitized debug view BEFORE marshalling real payload.
+	debugPayload := payload
+	debugPayload.Messages = []openRouterChatMessage{{Role: "user", Content: "[request_text]"}}
+	debug := map[string]any{
+		"provider": name,
+		"endpoint": endpoint,
+		"method":   http.MethodPost,
+		"headers": map[string]string{
+			"Authorization": "Bearer [apikey]",
+			"Content-Type":  "application/json",
+		},
+		"body": debugPayload,
+	}
+	debugBytes, _ := json.MarshalIndent(debug, "", "  ")
+	debugString := string(debugBytes)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Result{APICall: debugString}, fmt.Errorf("failed to marshal %s chat payload: %w", name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Result{APICall: debugString}, fmt.Errorf("failed to create %s chat request: %w", name, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("%s chat request failed (model=%s): %v", name, model, err)
+		return Result{APICall: debugString}, fmt.Errorf("%s chat request failed: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		limitedBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("%s chat returned status %d for model %s: %s", name, resp.StatusCode, model, string(limitedBody))
+		var decoded openRouterChatResponse
+		if json.Unmarshal(limitedBody, &decoded) == nil && decoded.Error != nil {
+			return Result{APICall: debugString}, fmt.Errorf("%s chat API returned status %d: %s", name, resp.StatusCode, decoded.Error.Message)
+		}
+		return Result{APICall: debugString}, fmt.Errorf("%s chat API returned non-2xx status %d", name, resp.StatusCode)
+	}
+
+	result, err := readChatCompletionStream(resp.Body, name, onChunk)
+	result.APICall = debugString
+	if err != nil {
+		log.Printf("%s chat stream failed for model %s: %v", name, model, err)
+	}
+	return result, err
+}
+
+// readChatCompletionStream collects the content deltas of a streamed chat completion, passing
+// each to onChunk, and the model and usage the chunks report. Errors reported in the middle of
+// the stream end it.
+func readChatCompletionStream(body io.Reader, name string, onChunk func(string)) (Result, error) {
+	var sb strings.Builder
+	var result Result
+	err := readSSE(body, func(e sseEvent) error {
+		var chunk openRouterChatResponse
+		if err := json.Unmarshal([]byte(e.data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode %s chat stream chunk: %w", name, err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("%s chat stream error: %s", name, chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage.usage()
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			sb.WriteString(chunk.Choices[0].Delta.Content)
+			onChunk(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Text = strings.TrimSpace(sb.String())
+	if result.Text == "" {
+		return result, fmt.Errorf("%s chat response did not contain text output", name)
+	}
+	return result, nil
+}
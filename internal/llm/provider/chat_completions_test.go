@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestStreamedChatCompletionsReportUsage checks that the OpenAI and OpenRouter providers ask
+// for the usage chunk when streaming and pass it on.
+func TestStreamedChatCompletionsReportUsage(t *testing.T) {
+	for _, tt := range []struct{ provider, model string }{
+		{"openai", "gpt-4o"},
+		{"openrouter", "anthropic/claude-sonnet-4.5"},
+		{"openrouter", "openai/gpt-5"}, // Reasoning request of GPT-5 models
+	} {
+		t.Run(tt.provider+"/"+tt.model, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/chat/completions" {
+					t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+					t.Errorf("Authorization = %q", got)
+				}
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding request body: %v", err)
+				}
+				if options, _ := body["stream_options"].(map[string]any); body["stream"] != true || options["include_usage"] != true {
+					t.Errorf("stream = %v, stream_options = %v, want the usage chunk requested", body["stream"], body["stream_options"])
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				io.WriteString(w, ": OPENROUTER PROCESSING\n\n")
+				io.WriteString(w, `data: {"model":"m-1","choices":[{"delta":{"content":"Hel"}}]}`+"\n\n")
+				io.WriteString(w, `data: {"model":"m-1","choices":[{"delta":{"content":"lo"}}]}`+"\n\n")
+				io.WriteString(w, `data: {"model":"m-1","choices":[],"usage":{"prompt_tokens":50,"completion_tokens":7,"completion_tokens_details":{"reasoning_tokens":3}}}`+"\n\n")
+				io.WriteString(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			p, err := Factory(Config{Provider: tt.provider, Model: tt.model, APIKey: "test-key", BaseURL: server.URL + "/v1"})
+			if err != nil {
+				t.Fatalf("Factory: %v", err)
+			}
+			var chunks []string
+			result, err := p.GenerateStream(context.Background(), "Say hello", func(chunk string) {
+				chunks = append(chunks, chunk)
+			})
+			if err != nil {
+				t.Fatalf("GenerateStream: %v", err)
+			}
+			if strings.Join(chunks, "") != "Hello" || result.Text != "Hello" || result.Model != "m-1" {
+				t.Errorf("chunks = %q, Text = %q, Model = %q", chunks, result.Text, result.Model)
+			}
+			if want := (Usage{InputTokens: 50, OutputTokens: 7, ReasoningTokens: 3}); result.Usage != want {
+				t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+			}
+			if strings.Contains(result.APICall, "test-key") || strings.Contains(result.APICall, "Say hello") {
+				t.Errorf("APICall leaks the key or the prompt:\n%s", result.APICall)
+			}
+		})
+	}
+}
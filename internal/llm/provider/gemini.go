@@ -1,19 +1,25 @@
 package provider
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
 	"strings"
-
-	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/googleai"
-	"encoding/json"
+	"time"
 )
 
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 type geminiProvider struct {
-	model  string
-	client *googleai.GoogleAI
+	model   string
+	apiKey  string
+	baseURL string
 }
 
 func newGeminiProvider(cfg Config) (LLMProvider, error) {
@@ -26,18 +32,15 @@ func newGeminiProvider(cfg Config) (LLMProvider, error) {
 		model = "gemini-1.5-flash"
 	}
 
-	client, err := googleai.New(
-		context.Background(),
-		googleai.WithAPIKey(strings.TrimSpace(cfg.APIKey)),
-		googleai.WithDefaultModel(model),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to init gemini client: %w", err)
+	baseURL := defaultGeminiBaseURL
+	if trimmed := strings.TrimSpace(cfg.BaseURL); trimmed != "" {
+		baseURL = trimmed
 	}
 
 	return &geminiProvider{
-		client: client,
-		model:  model,
+		model:   model,
+		apiKey:  strings.TrimSpace(cfg.APIKey),
+		baseURL: baseURL,
 	}, nil
 }
 
@@ -45,28 +48,211 @@ func (g *geminiProvider) ListModels(_ context.Context) ([]ModelInfo, error) {
 	return ModelCatalog("gemini")
 }
 
-func (g *geminiProvider) Generate(ctx context.Context, prompt string) (string, string, error) {
-	if g.client == nil {
-		return "", "", errors.New("gemini client is not configured")
+func (g *geminiProvider) Generate(ctx context.Context, prompt string) (Result, error) {
+	return g.generate(ctx, prompt, nil)
+}
+
+func (g *geminiProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
+	return g.generate(ctx, prompt, onChunk)
+}
+
+type geminiPart struct {
+	Text    string `json:"text,omitempty"`
+	Thought bool   `json:"thought,omitempty"` // Set on thought summaries, which are not part of the answer
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature float64 `json:"temperature"`
+}
+
+type geminiGenerateRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+// usage converts the usage metadata. Gemini counts thinking apart from the candidates;
+// Usage.OutputTokens includes it, as it is billed as output.
+func (u *geminiUsageMetadata) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:     u.PromptTokenCount,
+		OutputTokens:    u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		ReasoningTokens: u.ThoughtsTokenCount,
+		CacheReadTokens: u.CachedContentTokenCount,
+	}
+}
+
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// geminiGenerateResponse is a whole answer, or one chunk of a streamed answer; the usage of the
+// last chunk covers the whole call.
+type geminiGenerateResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata  *geminiUsageMetadata `json:"usageMetadata"`
+	ModelVersion   string               `json:"modelVersion"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	Error *geminiError `json:"error"`
+}
+
+// text joins the answer parts of the first candidate.
+func (r *geminiGenerateResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		if !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// generate calls generateContent, or streamGenerateContent when onChunk is set. Unlike the
+// langchaingo SDK, the REST API passes on the token usage of the call.
+func (g *geminiProvider) generate(ctx context.Context, prompt string, onChunk func(string)) (result Result, err error) {
+	defer finishResult(&result, g.model, time.Now())
+	method := ":generateContent"
+	if onChunk != nil {
+		method = ":streamGenerateContent?alt=sse"
+	}
+	endpoint := strings.TrimRight(g.baseURL, "/") + "/models/" + url.PathEscape(g.model) + method
+
+	payload := geminiGenerateRequest{
+		Contents:         []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+		GenerationConfig: geminiGenerationConfig{Temperature: 0.1},
 	}
-	output, err := llms.GenerateFromSinglePrompt(ctx, g.client, prompt, llms.WithModel(g.model), llms.WithTemperature(0.1))
 
+	// Build sanitized debug view BEFORE marshalling real payload.
+	debugPayload := payload
+	debugPayload.Contents = []geminiContent{{Role: "user", Parts: []geminiPart{{Text: "[request_text]"}}}}
 	debug := map[string]any{
 		"provider": "gemini",
-		"model":    g.model,
-		"sdk":      "langchaingo/llms.googleai",
-		"call":     "llms.GenerateFromSinglePrompt",
-		"input":    "[request_text]",
+		"endpoint": endpoint,
+		"method":   http.MethodPost,
+		"headers": map[string]string{
+			"x-goog-api-key": "[apikey]",
+			"Content-Type":   "application/json",
+		},
+		"body": debugPayload,
+	}
+	debugBytes, _ := json.MarshalIndent(debug, "", "  ")
+	result.APICall = string(debugBytes)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal Gemini payload: %w", err)
 	}
 
-	data, mErr := json.MarshalIndent(debug, "", "  ")
-	debugString := ""
-	if mErr == nil {
-		debugString = string(data)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("failed to create Gemini request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", g.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if onChunk != nil {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("gemini request failed (model=%s): %v", g.model, err)
+		return result, fmt.Errorf("gemini request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		limitedBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("gemini returned status %d for model %s: %s", resp.StatusCode, g.model, string(limitedBody))
+		var decoded geminiGenerateResponse
+		if json.Unmarshal(limitedBody, &decoded) == nil && decoded.Error != nil {
+			return result, fmt.Errorf("gemini API returned status %d: %s", resp.StatusCode, decoded.Error.Message)
+		}
+		return result, fmt.Errorf("gemini API returned non-2xx status %d", resp.StatusCode)
 	}
 
+	var decoded geminiGenerateResponse
+	var text string
+	if onChunk != nil {
+		decoded, text, err = readGeminiStream(resp.Body, onChunk)
+		if err != nil {
+			log.Printf("gemini stream failed for model %s: %v", g.model, err)
+		}
+	} else if err = json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		log.Printf("failed to decode gemini payload for model %s: %v", g.model, err)
+		err = fmt.Errorf("failed to decode gemini payload: %w", err)
+	} else {
+		text = decoded.text()
+	}
+	result.Model = decoded.ModelVersion
+	result.Usage = decoded.UsageMetadata.usage()
 	if err != nil {
-		return "", debugString, err
+		return result, err
+	}
+
+	result.Text = strings.TrimSpace(text)
+	if result.Text == "" {
+		if decoded.PromptFeedback != nil && decoded.PromptFeedback.BlockReason != "" {
+			return result, fmt.Errorf("gemini blocked the prompt (%s)", decoded.PromptFeedback.BlockReason)
+		}
+		finishReason := ""
+		if len(decoded.Candidates) > 0 {
+			finishReason = decoded.Candidates[0].FinishReason
+		}
+		log.Printf("gemini response contained no text for model %s (finish reason %q)", g.model, finishReason)
+		return result, fmt.Errorf("gemini response did not contain text output (finish reason %q)", finishReason)
 	}
-	return output, debugString, nil
+	return result, nil
+}
+
+// readGeminiStream collects a streamed answer, passing the text of each chunk to onChunk. It
+// returns the last chunk, which carries the usage of the call, and the whole text.
+func readGeminiStream(body io.Reader, onChunk func(string)) (geminiGenerateResponse, string, error) {
+	var last geminiGenerateResponse
+	var sb strings.Builder
+	err := readSSE(body, func(e sseEvent) error {
+		var chunk geminiGenerateResponse
+		if err := json.Unmarshal([]byte(e.data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode gemini stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("gemini stream error: %s", chunk.Error.Message)
+		}
+		if text := chunk.text(); text != "" {
+			sb.WriteString(text)
+			onChunk(text)
+		}
+		if chunk.UsageMetadata == nil {
+			chunk.UsageMetadata = last.UsageMetadata
+		}
+		if chunk.ModelVersion == "" {
+			chunk.ModelVersion = last.ModelVersion
+		}
+		last = chunk
+		return nil
+	})
+	return last, sb.String(), err
 }
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// geminiStandIn serves the Gemini API: it checks the key header and the method path, then
+// answers with respond.
+func geminiStandIn(t *testing.T, wantPath string, respond func(w http.ResponseWriter)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != wantPath {
+			t.Errorf("request %s %s, want POST %s", r.Method, r.URL.Path, wantPath)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want test-key", got)
+		}
+		var body geminiGenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		if len(body.Contents) != 1 || body.Contents[0].Parts[0].Text != "Explain" {
+			t.Errorf("contents = %+v, want the prompt", body.Contents)
+		}
+		respond(w)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestGeminiProvider(t *testing.T, baseURL string) LLMProvider {
+	t.Helper()
+	p, err := Factory(Config{Provider: "gemini", Model: "gemini-2.5-pro", APIKey: "test-key", BaseURL: baseURL})
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	return p
+}
+
+func TestGeminiGenerateReportsUsage(t *testing.T) {
+	server := geminiStandIn(t, "/models/gemini-2.5-pro:generateContent", func(w http.ResponseWriter) {
+		io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking...","thought":true},{"text":" It works. "}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":1200,"candidatesTokenCount":40,"thoughtsTokenCount":300,"cachedContentTokenCount":1000,"totalTokenCount":1540},
+			"modelVersion":"gemini-2.5-pro-002"}`)
+	})
+
+	result, err := newTestGeminiProvider(t, server.URL).Generate(context.Background(), "Explain")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if result.Text != "It works." || result.Model != "gemini-2.5-pro-002" {
+		t.Errorf("Text = %q, Model = %q", result.Text, result.Model)
+	}
+	want := Usage{InputTokens: 1200, OutputTokens: 340, ReasoningTokens: 300, CacheReadTokens: 1000}
+	if result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+	if strings.Contains(result.APICall, "test-key") || strings.Contains(result.APICall, "Explain") {
+		t.Errorf("APICall leaks the key or the prompt:\n%s", result.APICall)
+	}
+}
+
+func TestGeminiGenerateStreamReportsUsage(t *testing.T) {
+	server := geminiStandIn(t, "/models/gemini-2.5-pro:streamGenerateContent", func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"It "}]}}],"usageMetadata":{"promptTokenCount":1200},"modelVersion":"gemini-2.5-pro-002"}`+"\n\n")
+		io.WriteString(w, `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"works."}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1200,"candidatesTokenCount":4}}`+"\n\n")
+	})
+
+	var chunks []string
+	result, err := newTestGeminiProvider(t, server.URL).GenerateStream(context.Background(), "Explain", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if strings.Join(chunks, "|") != "It |works." || result.Text != "It works." {
+		t.Errorf("chunks = %q, Text = %q", chunks, result.Text)
+	}
+	if result.Model != "gemini-2.5-pro-002" {
+		t.Errorf("Model = %q", result.Model)
+	}
+	if want := (Usage{InputTokens: 1200, OutputTokens: 4}); result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+}
+
+func TestGeminiGenerateErrorStatus(t *testing.T) {
+	server := geminiStandIn(t, "/models/gemini-2.5-pro:generateContent", func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`)
+	})
+	_, err := newTestGeminiProvider(t, server.URL).Generate(context.Background(), "Explain")
+	if err == nil || !strings.Contains(err.Error(), "API key not valid") {
+		t.Errorf("err = %v, want the API error message", err)
+	}
+}
@@ -0,0 +1,363 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLocalBaseURL is where Ollama listens by default.
+const defaultLocalBaseURL = "http://localhost:11434"
+
+// Kinds of local servers.
+const (
+	localServerOllama = "ollama" // Native API, which accepts the context length per request
+	localServerOpenAI = "openai" // Any OpenAI-compatible server: LM Studio, llama.cpp, vLLM, ...
+)
+
+// localProvider talks to an LLM server on the user's machine or network. It needs no API key
+// and no model to list the models the server offers.
+type localProvider struct {
+	model         string
+	baseURL       string // As configured; the OpenAI-compatible API is relative to it
+	contextLength int
+
+	mu         sync.Mutex
+	serverKind string // Detected on first use
+	ollamaRoot string // Where Ollama's native API answered, for localServerOllama
+}
+
+func newLocalProvider(cfg Config) (LLMProvider, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = defaultLocalBaseURL
+	}
+	return &localProvider{
+		model:         strings.TrimSpace(cfg.Model),
+		baseURL:       baseURL,
+		contextLength: max(cfg.ContextLength, 0),
+	}, nil
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name    string `json:"name"`
+		Details struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+	} `json:"models"`
+}
+
+type openAIModelsResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+// ListModels asks the server for its models: Ollama's /api/tags, or the /models of
+// OpenAI-compatible servers. The context window of every model is the configured length.
+func (l *localProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	root, tags, ollamaErr := l.probeOllama(ctx)
+	if ollamaErr == nil {
+		l.setServer(localServerOllama, root)
+		models := make([]ModelInfo, 0, len(tags.Models))
+		for _, m := range tags.Models {
+			var details []string
+			for _, d := range []string{m.Details.Family, m.Details.ParameterSize, m.Details.QuantizationLevel} {
+				if d != "" {
+					details = append(details, d)
+				}
+			}
+			models = append(models, ModelInfo{Name: m.Name, Description: strings.Join(append([]string{"Ollama"}, details...), ", "), ContextWindow: l.contextLength})
+		}
+		return models, nil
+	}
+
+	var list openAIModelsResponse
+	if err := l.getJSON(ctx, l.baseURL+"/models", &list); err != nil {
+		return nil, fmt.Errorf("local server at %s did not list models (Ollama: %v; OpenAI-compatible: %w)", l.baseURL, ollamaErr, err)
+	}
+	l.setServer(localServerOpenAI, "")
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		description := "OpenAI-compatible local server"
+		if m.OwnedBy != "" {
+			description += ", " + m.OwnedBy
+		}
+		models = append(models, ModelInfo{Name: m.ID, Description: description, ContextWindow: l.contextLength})
+	}
+	return models, nil
+}
+
+func (l *localProvider) getJSON(ctx context.Context, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ollamaRoots lists where Ollama's native API may live for a base URL: the URL itself, and the
+// server root when the URL names Ollama's OpenAI-compatible /v1 endpoints.
+func ollamaRoots(baseURL string) []string {
+	roots := []string{baseURL}
+	if root, ok := strings.CutSuffix(baseURL, "/v1"); ok && root != "" {
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// probeOllama looks for Ollama's model list at the roots of the base URL. Only a server that
+// answers /api/tags with a model list counts as Ollama; proxies may answer any path.
+func (l *localProvider) probeOllama(ctx context.Context) (string, ollamaTagsResponse, error) {
+	var errs []error
+	for _, root := range ollamaRoots(l.baseURL) {
+		var tags ollamaTagsResponse
+		err := l.getJSON(ctx, root+"/api/tags", &tags)
+		if err == nil && tags.Models == nil {
+			err = errors.New("no model list")
+		}
+		if err == nil {
+			return root, tags, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", ollamaTagsResponse{}, errors.Join(errs...)
+}
+
+func (l *localProvider) setServer(kind, ollamaRoot string) {
+	l.mu.Lock()
+	l.serverKind, l.ollamaRoot = kind, ollamaRoot
+	l.mu.Unlock()
+}
+
+// detectServerKind tells Ollama from OpenAI-compatible servers, returning the root of Ollama's
+// native API too. Servers that cannot be reached are probed again on the next call.
+func (l *localProvider) detectServerKind(ctx context.Context) (string, string, error) {
+	l.mu.Lock()
+	kind, root := l.serverKind, l.ollamaRoot
+	l.mu.Unlock()
+	if kind != "" {
+		return kind, root, nil
+	}
+	root, _, err := l.probeOllama(ctx)
+	if err == nil {
+		l.setServer(localServerOllama, root)
+		return localServerOllama, root, nil
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return "", "", fmt.Errorf("local server at %s is not reachable: %w", l.baseURL, err)
+	}
+	l.setServer(localServerOpenAI, "")
+	return localServerOpenAI, "", nil
+}
+
+func (l *localProvider) Generate(ctx context.Context, prompt string) (Result, error) {
+	return l.generate(ctx, prompt, nil)
+}
+
+func (l *localProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
+	return l.generate(ctx, prompt, onChunk)
+}
+
+type localChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	NumCtx      int     `json:"num_ctx,omitempty"`
+	Temperature float64 `json:"temperature"`
+}
+
+type ollamaChatRequest struct {
+	Model    string             `json:"model"`
+	Messages []localChatMessage `json:"messages"`
+	Stream   bool               `json:"stream"` // Ollama streams unless told otherwise
+	Options  ollamaOptions      `json:"options"`
+}
+
+// ollamaChatResponse is a whole answer, or one line of a streamed answer; the last line has
+// Done set and carries the token counts.
+type ollamaChatResponse struct {
+	Model           string           `json:"model"`
+	Message         localChatMessage `json:"message"`
+	Done            bool             `json:"done"`
+	PromptEvalCount int              `json:"prompt_eval_count"`
+	EvalCount       int              `json:"eval_count"`
+	Error           string           `json:"error"`
+}
+
+type localChatCompletionRequest struct {
+	Model         string             `json:"model"`
+	Messages      []localChatMessage `json:"messages"`
+	Temperature   float64            `json:"temperature"`
+	Stream        bool               `json:"stream,omitempty"`
+	StreamOptions *chatStreamOptions `json:"stream_options,omitempty"`
+}
+
+// generate uses Ollama's chat API, which applies the configured context length, or the chat
+// completions API of OpenAI-compatible servers, whose context length is set when the server
+// loads the model.
+func (l *localProvider) generate(ctx context.Context, prompt string, onChunk func(string)) (result Result, err error) {
+	defer finishResult(&result, l.model, time.Now())
+	if l.model == "" {
+		return result, errors.New("local provider requires a model")
+	}
+	kind, ollamaRoot, err := l.detectServerKind(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	var endpoint string
+	var payload, debugPayload any
+	messages := []localChatMessage{{Role: "user", Content: prompt}}
+	debugMessages := []localChatMessage{{Role: "user", Content: "[request_text]"}}
+	if kind == localServerOllama {
+		endpoint = ollamaRoot + "/api/chat"
+		request := ollamaChatRequest{Model: l.model, Messages: messages, Stream: onChunk != nil, Options: ollamaOptions{NumCtx: l.contextLength, Temperature: 0.1}}
+		payload = request
+		request.Messages = debugMessages
+		debugPayload = request
+	} else {
+		endpoint = l.baseURL + "/chat/completions"
+		request := localChatCompletionRequest{Model: l.model, Messages: messages, Temperature: 0.1}
+		if onChunk != nil {
+			request.Stream = true
+			request.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+		}
+		payload = request
+		request.Messages = debugMessages
+		debugPayload = request
+	}
+
+	debug := map[string]any{
+		"provider": "local",
+		"server":   kind,
+		"endpoint": endpoint,
+		"method":   http.MethodPost,
+		"headers": map[string]string{
+			"Content-Type": "application/json",
+		},
+		"body": debugPayload,
+	}
+	debugBytes, _ := json.MarshalIndent(debug, "", "  ")
+	result.APICall = string(debugBytes)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal local chat payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("failed to create local chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("local chat request failed (model=%s): %v", l.model, err)
+		return result, fmt.Errorf("local chat request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		limitedBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("local chat returned status %d for model %s: %s", resp.StatusCode, l.model, string(limitedBody))
+		return result, fmt.Errorf("local server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(limitedBody)))
+	}
+
+	var answer Result
+	if kind == localServerOllama {
+		answer, err = readOllamaChat(resp.Body, onChunk)
+	} else {
+		answer, err = readLocalChatCompletion(resp.Body, onChunk)
+	}
+	result.Model, result.Usage = answer.Model, answer.Usage
+	if err != nil {
+		log.Printf("local chat failed for model %s: %v", l.model, err)
+		return result, err
+	}
+	result.Text = strings.TrimSpace(answer.Text)
+	if result.Text == "" {
+		return result, errors.New("local chat response did not contain text output")
+	}
+	return result, nil
+}
+
+// readOllamaChat reads an Ollama chat answer: one JSON object, or one per line when streaming,
+// in which case every piece of the answer is passed to onChunk.
+func readOllamaChat(body io.Reader, onChunk func(string)) (Result, error) {
+	var result Result
+	var sb strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return result, fmt.Errorf("failed to decode ollama chat response: %w", err)
+		}
+		if chunk.Error != "" {
+			return result, fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Message.Content != "" {
+			sb.WriteString(chunk.Message.Content)
+			if onChunk != nil {
+				onChunk(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			result.Usage = Usage{InputTokens: chunk.PromptEvalCount, OutputTokens: chunk.EvalCount}
+		}
+	}
+	result.Text = sb.String()
+	return result, scanner.Err()
+}
+
+// readLocalChatCompletion reads a chat completion of an OpenAI-compatible server, streamed as
+// server-sent events when onChunk is set.
+func readLocalChatCompletion(body io.Reader, onChunk func(string)) (Result, error) {
+	var result Result
+	if onChunk == nil {
+		var decoded openRouterChatResponse
+		if err := json.NewDecoder(body).Decode(&decoded); err != nil {
+			return result, fmt.Errorf("failed to decode local chat response: %w", err)
+		}
+		result.Model, result.Usage = decoded.Model, decoded.Usage.usage()
+		if len(decoded.Choices) > 0 {
+			result.Text = decoded.Choices[0].Message.Content
+		}
+		return result, nil
+	}
+
+	return readChatCompletionStream(body, "local", onChunk)
+}
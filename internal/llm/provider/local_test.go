@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLocalOllama checks that Ollama is detected by its /api/tags, also behind the URL of its
+// OpenAI-compatible endpoints, and that answers stream from /api/chat with the context length.
+func TestLocalOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			io.WriteString(w, `{"models":[{"name":"qwen3:8b","details":{"family":"qwen3","parameter_size":"8.2B","quantization_level":"Q4_K_M"}}]}`)
+		case "/api/chat":
+			var body ollamaChatRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decoding request body: %v", err)
+			}
+			if !body.Stream || body.Model != "qwen3:8b" || body.Options.NumCtx != 32768 {
+				t.Errorf("stream = %v, model = %q, num_ctx = %d", body.Stream, body.Model, body.Options.NumCtx)
+			}
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			io.WriteString(w, `{"model":"qwen3:8b","message":{"role":"assistant","content":"Hel"},"done":false}`+"\n")
+			io.WriteString(w, `{"model":"qwen3:8b","message":{"role":"assistant","content":"lo"},"done":false}`+"\n")
+			io.WriteString(w, `{"model":"qwen3:8b","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":40,"eval_count":2}`+"\n")
+		default:
+			if r.URL.Path != "/v1/api/tags" { // Probed first for the "/v1" URL
+				t.Errorf("unexpected request for %s", r.URL.Path)
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, baseURL := range []string{server.URL, server.URL + "/", server.URL + "/v1"} {
+		t.Run(baseURL, func(t *testing.T) {
+			p, err := Factory(Config{Provider: "local", Model: "qwen3:8b", BaseURL: baseURL, ContextLength: 32768})
+			if err != nil {
+				t.Fatalf("Factory: %v", err)
+			}
+			models, err := p.ListModels(context.Background())
+			if err != nil {
+				t.Fatalf("ListModels: %v", err)
+			}
+			want := []ModelInfo{{Name: "qwen3:8b", Description: "Ollama, qwen3, 8.2B, Q4_K_M", ContextWindow: 32768}}
+			if !reflect.DeepEqual(models, want) {
+				t.Errorf("models = %+v, want %+v", models, want)
+			}
+
+			// A fresh provider detects the server on its first call.
+			p, _ = Factory(Config{Provider: "local", Model: "qwen3:8b", BaseURL: baseURL, ContextLength: 32768})
+			var chunks []string
+			result, err := p.GenerateStream(context.Background(), "Say hello", func(chunk string) {
+				chunks = append(chunks, chunk)
+			})
+			if err != nil {
+				t.Fatalf("GenerateStream: %v", err)
+			}
+			if strings.Join(chunks, "") != "Hello" || result.Text != "Hello" || result.Model != "qwen3:8b" {
+				t.Errorf("chunks = %q, Text = %q, Model = %q", chunks, result.Text, result.Model)
+			}
+			if want := (Usage{InputTokens: 40, OutputTokens: 2}); result.Usage != want {
+				t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+			}
+		})
+	}
+}
+
+// TestLocalOpenAICompatible checks that OpenAI-compatible servers are called at the URL the
+// user gave, whatever its prefix, including proxies that answer /api/tags with something else.
+func TestLocalOpenAICompatible(t *testing.T) {
+	for _, tt := range []struct {
+		name, prefix string
+		answerAll    bool // Like a proxy, answer every unknown path with 200
+	}{
+		{"LM Studio", "/v1", false},
+		{"prefixed", "/openai/v1", false},
+		{"proxy", "/proxy/openai/v1", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case tt.prefix + "/models":
+					io.WriteString(w, `{"data":[{"id":"llama-3.1-8b","owned_by":"organization_owner"}]}`)
+				case tt.prefix + "/chat/completions":
+					var body map[string]any
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Errorf("decoding request body: %v", err)
+					}
+					if options, _ := body["stream_options"].(map[string]any); body["stream"] != true || options["include_usage"] != true {
+						t.Errorf("stream = %v, stream_options = %v, want the usage chunk requested", body["stream"], body["stream_options"])
+					}
+					w.Header().Set("Content-Type", "text/event-stream")
+					io.WriteString(w, `data: {"model":"llama-3.1-8b","choices":[{"delta":{"content":"Hel"}}]}`+"\n\n")
+					io.WriteString(w, `data: {"model":"llama-3.1-8b","choices":[{"delta":{"content":"lo"}}]}`+"\n\n")
+					io.WriteString(w, `data: {"model":"llama-3.1-8b","choices":[],"usage":{"prompt_tokens":30,"completion_tokens":2}}`+"\n\n")
+					io.WriteString(w, "data: [DONE]\n\n")
+				default:
+					if !strings.HasSuffix(r.URL.Path, "/api/tags") {
+						t.Errorf("unexpected request for %s", r.URL.Path)
+					}
+					if tt.answerAll {
+						io.WriteString(w, `{"object":"error"}`)
+						return
+					}
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			baseURL := server.URL + tt.prefix
+			p, err := Factory(Config{Provider: "local", Model: "llama-3.1-8b", BaseURL: baseURL, ContextLength: 8192})
+			if err != nil {
+				t.Fatalf("Factory: %v", err)
+			}
+			models, err := p.ListModels(context.Background())
+			if err != nil {
+				t.Fatalf("ListModels: %v", err)
+			}
+			want := []ModelInfo{{Name: "llama-3.1-8b", Description: "OpenAI-compatible local server, organization_owner", ContextWindow: 8192}}
+			if !reflect.DeepEqual(models, want) {
+				t.Errorf("models = %+v, want %+v", models, want)
+			}
+
+			p, _ = Factory(Config{Provider: "local", Model: "llama-3.1-8b", BaseURL: baseURL, ContextLength: 8192})
+			var chunks []string
+			result, err := p.GenerateStream(context.Background(), "Say hello", func(chunk string) {
+				chunks = append(chunks, chunk)
+			})
+			if err != nil {
+				t.Fatalf("GenerateStream: %v", err)
+			}
+			if strings.Join(chunks, "") != "Hello" || result.Text != "Hello" {
+				t.Errorf("chunks = %q, Text = %q", chunks, result.Text)
+			}
+			if want := (Usage{InputTokens: 30, OutputTokens: 2}); result.Usage != want {
+				t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+			}
+		})
+	}
+}
+
+func TestLocalUnreachableServerIsProbedAgain(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	p, err := Factory(Config{Provider: "local", Model: "m", BaseURL: baseURL})
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	if _, err := p.Generate(context.Background(), "hi"); err == nil || !strings.Contains(err.Error(), "not reachable") {
+		t.Fatalf("Generate error = %v, want the server reported as not reachable", err)
+	}
+	if kind := p.(*localProvider).serverKind; kind != "" {
+		t.Errorf("serverKind = %q after a failed probe, want it detected again", kind)
+	}
+}
+
+func TestReadLocalChatCompletionStreamError(t *testing.T) {
+	stream := `data: {"choices":[{"delta":{"content":"partial"}}]}` + "\n\n" +
+		`data: {"error":{"message":"model unloaded"}}` + "\n\n"
+	if _, err := readLocalChatCompletion(strings.NewReader(stream), func(string) {}); err == nil || !strings.Contains(err.Error(), "local chat stream error: model unloaded") {
+		t.Fatalf("err = %v, want the stream error", err)
+	}
+}
@@ -4,36 +4,43 @@ import "fmt"
 
 var openAIModelCatalog = []ModelInfo{
 	// GPT-5 family (latest reasoning-capable models)
-	{Name: "gpt-5.1", Description: "Latest GPT-5.1 flagship for complex reasoning and coding tasks"},
-	{Name: "gpt-5", Description: "Previous GPT-5 flagship reasoning model"},
-	{Name: "gpt-5-mini", Description: "Cost-optimized GPT-5 mini model"},
-	{Name: "gpt-5-nano", Description: "High-throughput GPT-5 nano model"},
+	{Name: "gpt-5.1", Description: "Latest GPT-5.1 flagship for complex reasoning and coding tasks", ContextWindow: 272000},
+	{Name: "gpt-5", Description: "Previous GPT-5 flagship reasoning model", ContextWindow: 272000},
+	{Name: "gpt-5-mini", Description: "Cost-optimized GPT-5 mini model", ContextWindow: 272000},
+	{Name: "gpt-5-nano", Description: "High-throughput GPT-5 nano model", ContextWindow: 272000},
 
 	// GPT-4 family
-	{Name: "gpt-4o-mini", Description: "Latest GPT-4o mini for general reasoning"},
-	{Name: "gpt-4.1-mini", Description: "GPT-4.1 mini tier"},
-	{Name: "o4-mini", Description: "Reasoning optimized 04-mini"},
-	{Name: "gpt-4o", Description: "Full GPT-4o"},
-	{Name: "gpt-4.1", Description: "Full GPT-4.1"},
+	{Name: "gpt-4o-mini", Description: "Latest GPT-4o mini for general reasoning", ContextWindow: 128000},
+	{Name: "gpt-4.1-mini", Description: "GPT-4.1 mini tier", ContextWindow: 1047576},
+	{Name: "o4-mini", Description: "Reasoning optimized 04-mini", ContextWindow: 200000},
+	{Name: "gpt-4o", Description: "Full GPT-4o", ContextWindow: 128000},
+	{Name: "gpt-4.1", Description: "Full GPT-4.1", ContextWindow: 1047576},
 }
 
 var openRouterModelCatalog = []ModelInfo{
-	{Name: "openai/gpt-5", Description: "GPT-5 family routed via OpenRouter"},
-	{Name: "anthropic/claude-4.5-sonnet", Description: "Claude 4.5 Sonnet via OpenRouter"},
-	{Name: "google/gemini-2.5-pro", Description: "Gemini 2.5 Pro via OpenRouter"},
-	{Name: "google/gemini-2.5-flash", Description: "Gemini 2.5 Flash via OpenRouter"},
-	{Name: "google/gemini-2.0-flash", Description: "Gemini 2.0 Flash via OpenRouter"},
-	{Name: "openai/gpt-4o-mini", Description: "GPT-4o mini from OpenRouter catalog"},
-	{Name: "meta-llama/llama-3.1-70b-instruct", Description: "Llama 3.1 70B Instruct via OpenRouter"},
-	{Name: "x-ai/grok-code-fast-1", Description: "Grok Code Fast 1 via OpenRouter"},
-	{Name: "x-ai/grok-4-fast", Description: "Grok 4 Fast via OpenRouter"},
-	{Name: "minimax/minimax-m2", Description: "Minimax M2 via OpenRouter"},
-	{Name: "z-ai/glm-4.6", Description: "GLM 4.6 via OpenRouter"},
+	{Name: "openai/gpt-5", Description: "GPT-5 family routed via OpenRouter", ContextWindow: 272000},
+	{Name: "anthropic/claude-4.5-sonnet", Description: "Claude 4.5 Sonnet via OpenRouter", ContextWindow: 200000},
+	{Name: "google/gemini-2.5-pro", Description: "Gemini 2.5 Pro via OpenRouter", ContextWindow: 1048576},
+	{Name: "google/gemini-2.5-flash", Description: "Gemini 2.5 Flash via OpenRouter", ContextWindow: 1048576},
+	{Name: "google/gemini-2.0-flash", Description: "Gemini 2.0 Flash via OpenRouter", ContextWindow: 1048576},
+	{Name: "openai/gpt-4o-mini", Description: "GPT-4o mini from OpenRouter catalog", ContextWindow: 128000},
+	{Name: "meta-llama/llama-3.1-70b-instruct", Description: "Llama 3.1 70B Instruct via OpenRouter", ContextWindow: 131072},
+	{Name: "x-ai/grok-code-fast-1", Description: "Grok Code Fast 1 via OpenRouter", ContextWindow: 256000},
+	{Name: "x-ai/grok-4-fast", Description: "Grok 4 Fast via OpenRouter", ContextWindow: 2000000},
+	{Name: "minimax/minimax-m2", Description: "Minimax M2 via OpenRouter", ContextWindow: 204800},
+	{Name: "z-ai/glm-4.6", Description: "GLM 4.6 via OpenRouter", ContextWindow: 202752},
 }
 
 var geminiModelCatalog = []ModelInfo{
-	{Name: "gemini-2.5-pro", Description: "Most capable Gemini 2.5 Pro"},
-	{Name: "gemini-2.5-flash", Description: "Flash"},
+	{Name: "gemini-2.5-pro", Description: "Most capable Gemini 2.5 Pro", ContextWindow: 1048576},
+	{Name: "gemini-2.5-flash", Description: "Flash", ContextWindow: 1048576},
+}
+
+var anthropicModelCatalog = []ModelInfo{
+	{Name: "claude-sonnet-4-5", Description: "Claude Sonnet 4.5, best balance for coding and agents", ContextWindow: 200000},
+	{Name: "claude-opus-4-1", Description: "Claude Opus 4.1 for the hardest reasoning tasks", ContextWindow: 200000},
+	{Name: "claude-haiku-4-5", Description: "Fast and cheap Claude Haiku 4.5", ContextWindow: 200000},
+	{Name: "claude-sonnet-4-0", Description: "Previous Claude Sonnet 4", ContextWindow: 200000},
 }
 
 func cloneModelCatalog(models []ModelInfo) []ModelInfo {
@@ -45,6 +52,34 @@ func cloneModelCatalog(models []ModelInfo) []ModelInfo {
 	return out
 }
 
+// ContextWindow returns the context window (in tokens) of a catalog model, or 0 when the
+// model is not in the catalog of providerName. The local provider has no catalog: the window
+// of its models is the Config.ContextLength it is given, which callers use instead.
+func ContextWindow(providerName, model string) int {
+	models, err := ModelCatalog(providerName)
+	if err != nil {
+		return 0
+	}
+	for _, m := range models {
+		if m.Name == model {
+			return m.ContextWindow
+		}
+	}
+	return 0
+}
+
+// LookupModel searches every provider catalog for model and returns its metadata.
+func LookupModel(model string) (ModelInfo, bool) {
+	for _, models := range [][]ModelInfo{openAIModelCatalog, openRouterModelCatalog, geminiModelCatalog, anthropicModelCatalog} {
+		for _, m := range models {
+			if m.Name == model {
+				return m, true
+			}
+		}
+	}
+	return ModelInfo{}, false
+}
+
 // ModelCatalog returns a provider specific list of models without requiring the provider to be fully configured.
 func ModelCatalog(providerName string) ([]ModelInfo, error) {
 	switch providerName {
@@ -54,6 +89,8 @@ func ModelCatalog(providerName string) ([]ModelInfo, error) {
 		return cloneModelCatalog(openRouterModelCatalog), nil
 	case "gemini":
 		return cloneModelCatalog(geminiModelCatalog), nil
+	case "anthropic":
+		return cloneModelCatalog(anthropicModelCatalog), nil
 	default:
 		return nil, fmt.Errorf("provider %s is not supported", providerName)
 	}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/tmc/langchaingo/llms"
 	openai "github.com/tmc/langchaingo/llms/openai"
@@ -64,30 +65,48 @@ func (o *openAIProvider) ListModels(_ context.Context) ([]ModelInfo, error) {
 	return ModelCatalog("openai")
 }
 
-func (o *openAIProvider) Generate(ctx context.Context, prompt string) (string, string, error) {
+func (o *openAIProvider) Generate(ctx context.Context, prompt string) (Result, error) {
+	return o.generate(ctx, prompt, nil)
+}
+
+func (o *openAIProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
+	return o.generate(ctx, prompt, onChunk)
+}
+
+// generate streams the answer when onChunk is set.
+func (o *openAIProvider) generate(ctx context.Context, prompt string, onChunk func(string)) (result Result, err error) {
+	defer finishResult(&result, o.model, time.Now())
 	if o.client == nil {
-		return "", "", errors.New("openai client is not configured")
+		return result, errors.New("openai client is not configured")
 	}
 
 	// For GPT-5 family models, use the Responses API with reasoning and verbosity controls,
 	// and **never** send temperature/top_p/logprobs.
 	if isGPT5FamilyModel(o.model) {
-		return o.generateViaResponsesAPI(ctx, prompt)
+		return o.generateViaResponsesAPI(ctx, prompt, onChunk)
+	}
+
+	// Streams of other models are read directly so that they report usage.
+	if onChunk != nil {
+		return streamChatCompletion(ctx, "openai", o.baseURL, o.apiKey, o.model, prompt, onChunk)
 	}
 
 	// For non-GPT-5 models we keep the existing behaviour with a small temperature.
-	output, err := llms.GenerateFromSinglePrompt(ctx, o.client, prompt,
+	opts := []llms.CallOption{
 		llms.WithModel(o.model),
 		llms.WithTemperature(0.1),
-	)
+	}
+	output, usage, err := generateViaSDK(ctx, o.client, prompt, opts...)
 
 	// Build a generic debug representation for the SDK-based call (no API key / raw text).
-	debug := o.buildGenericAPICallDebug()
+	result.APICall = o.buildGenericAPICallDebug()
 
 	if err != nil {
-		return "", debug, err
+		return result, err
 	}
-	return output, debug, nil
+	result.Text = output
+	result.Usage = usage
+	return result, nil
 }
 
 type responsesAPIReasoningConfig struct {
@@ -104,17 +123,51 @@ type responsesAPIRequest struct {
 	Reasoning       responsesAPIReasoningConfig `json:"reasoning"`
 	Text            responsesAPITextConfig      `json:"text"`
 	MaxOutputTokens int                         `json:"max_output_tokens,omitempty"`
+	Stream          bool                        `json:"stream,omitempty"`
+}
+
+type responsesAPIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type responsesAPIUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+// usage converts the usage block; a missing block gives zero counts.
+func (u *responsesAPIUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, ReasoningTokens: u.OutputTokensDetails.ReasoningTokens}
 }
 
 type responsesAPIResponse struct {
-	Output     json.RawMessage `json:"output"`
-	OutputText string          `json:"output_text"`
+	Model      string             `json:"model"`
+	Output     json.RawMessage    `json:"output"`
+	OutputText string             `json:"output_text"`
+	Usage      *responsesAPIUsage `json:"usage"`
+	Error      *responsesAPIError `json:"error"`
 }
 
-func (o *openAIProvider) generateViaResponsesAPI(ctx context.Context, prompt string) (string, string, error) {
+// responsesStreamEvent holds the fields of streamed Responses API events that we use: text
+// deltas, the final response and errors.
+type responsesStreamEvent struct {
+	Type     string                `json:"type"`
+	Delta    string                `json:"delta"`
+	Response *responsesAPIResponse `json:"response"`
+	Message  string                `json:"message"`
+}
+
+func (o *openAIProvider) generateViaResponsesAPI(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
 	apiKey := strings.TrimSpace(o.apiKey)
 	if apiKey == "" {
-		return "", "", errors.New("openai API key is required for GPT-5 models")
+		return Result{}, errors.New("openai API key is required for GPT-5 models")
 	}
 
 	baseURL := strings.TrimSpace(o.baseURL)
@@ -135,6 +188,7 @@ func (o *openAIProvider) generateViaResponsesAPI(ctx context.Context, prompt str
 		// Явно ограничиваем длину ответа, чтобы модель уверенно возвращала сообщение.
 		// Значение можно будет вынести в настройки при необходимости.
 		MaxOutputTokens: 65536,
+		Stream:          onChunk != nil,
 	}
 
 	// Build sanitized debug view BEFORE marshalling real payload.
@@ -148,6 +202,7 @@ func (o *openAIProvider) generateViaResponsesAPI(ctx context.Context, prompt str
 			Verbosity: "high",
 		},
 		MaxOutputTokens: payload.MaxOutputTokens,
+		Stream:          payload.Stream,
 	}
 	debug := map[string]any{
 		"provider": "openai",
@@ -169,50 +224,118 @@ func (o *openAIProvider) generateViaResponsesAPI(ctx context.Context, prompt str
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return "", debugString, fmt.Errorf("failed to marshal OpenAI Responses payload: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("failed to marshal OpenAI Responses payload: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return "", debugString, fmt.Errorf("failed to create OpenAI Responses request: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("failed to create OpenAI Responses request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	// Newer Responses API may expect an explicit beta header; sending it is safe and explicit.
 	req.Header.Set("OpenAI-Beta", "responses=v1")
+	if onChunk != nil {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Printf("openai responses API request failed: %v", err)
-		return "", debugString, fmt.Errorf("openai responses API request failed: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("openai responses API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		limitedBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		log.Printf("openai responses API returned status %d: %s", resp.StatusCode, string(limitedBody))
-		return "", debugString, fmt.Errorf("openai responses API returned non-2xx status %d", resp.StatusCode)
+		return Result{APICall: debugString}, fmt.Errorf("openai responses API returned non-2xx status %d", resp.StatusCode)
+	}
+
+	if onChunk != nil {
+		text, final, err := readResponsesStream(resp.Body, onChunk)
+		if err != nil {
+			log.Printf("openai responses API stream failed for model %s: %v", o.model, err)
+			return Result{APICall: debugString}, err
+		}
+		result := Result{Text: text, APICall: debugString}
+		if final != nil {
+			result.Model = final.Model
+			result.Usage = final.Usage.usage()
+		}
+		return result, nil
 	}
 
 	var decoded responsesAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
 		log.Printf("failed to decode openai responses API payload: %v", err)
-		return "", debugString, fmt.Errorf("failed to decode openai responses API payload: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("failed to decode openai responses API payload: %w", err)
 	}
 
+	result := Result{APICall: debugString, Model: decoded.Model, Usage: decoded.Usage.usage()}
+
 	// 1) Сначала пытаемся использовать агрегированное поле output_text на верхнем уровне.
 	if txt := strings.TrimSpace(decoded.OutputText); txt != "" {
-		return txt, debugString, nil
+		result.Text = txt
+		return result, nil
 	}
 
 	// 2) Если его нет — извлекаем текст из массива output.
 	text, extractErr := extractTextFromResponsesOutput(decoded.Output)
 	if extractErr != nil {
 		log.Printf("failed to extract text from openai responses API output for model %s: %v", o.model, extractErr)
-		return "", debugString, extractErr
+		return result, extractErr
 	}
 
-	return text, debugString, nil
+	result.Text = text
+	return result, nil
+}
+
+// readResponsesStream collects the output_text deltas of a streamed Responses API call, passing
+// each to onChunk, and returns them with the final response, which carries the usage but no
+// text. A stream without deltas falls back to the text of the final response.
+func readResponsesStream(body io.Reader, onChunk func(string)) (string, *responsesAPIResponse, error) {
+	var sb strings.Builder
+	var final *responsesAPIResponse
+	err := readSSE(body, func(e sseEvent) error {
+		var event responsesStreamEvent
+		if err := json.Unmarshal([]byte(e.data), &event); err != nil {
+			return fmt.Errorf("failed to decode openai responses API stream event: %w", err)
+		}
+		switch event.Type {
+		case "response.output_text.delta":
+			sb.WriteString(event.Delta)
+			onChunk(event.Delta)
+		case "response.completed", "response.incomplete":
+			final = event.Response
+		case "response.failed":
+			if event.Response != nil && event.Response.Error != nil {
+				return fmt.Errorf("openai responses API call failed: %s", event.Response.Error.Message)
+			}
+			return errors.New("openai responses API call failed")
+		case "error":
+			return fmt.Errorf("openai responses API stream error: %s", event.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if text := strings.TrimSpace(sb.String()); text != "" {
+		return text, final, nil
+	}
+	if final == nil {
+		return "", nil, errors.New("openai responses API stream ended without a response")
+	}
+	text := strings.TrimSpace(final.OutputText)
+	if text == "" {
+		if text, err = extractTextFromResponsesOutput(final.Output); err != nil {
+			return "", nil, err
+		}
+	}
+	onChunk(text)
+	return text, final, nil
 }
 
 // extractTextFromResponsesOutput tries to handle current JSON shapes of the Responses API:
@@ -279,13 +402,12 @@ func (o *openAIProvider) buildGenericAPICallDebug() string {
 		"model":    o.model,
 		"baseURL":  o.baseURL,
 		"sdk":      "langchaingo/llms.openai",
-		"call":     "llms.GenerateFromSinglePrompt",
+		"call":     "llms.Model.GenerateContent",
 		"input":    "[request_text]",
 		"headers": map[string]string{
 			"Authorization": "Bearer [apikey]",
 		},
 	}
-
 	data, err := json.MarshalIndent(debug, "", "  ")
 	if err != nil {
 		return ""
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/tmc/langchaingo/llms"
 	openai "github.com/tmc/langchaingo/llms/openai"
@@ -60,29 +61,47 @@ func (o *openRouterProvider) ListModels(_ context.Context) ([]ModelInfo, error)
 	return ModelCatalog("openrouter")
 }
 
-func (o *openRouterProvider) Generate(ctx context.Context, prompt string) (string, string, error) {
+func (o *openRouterProvider) Generate(ctx context.Context, prompt string) (Result, error) {
+	return o.generate(ctx, prompt, nil)
+}
+
+func (o *openRouterProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
+	return o.generate(ctx, prompt, onChunk)
+}
+
+// generate streams the answer when onChunk is set.
+func (o *openRouterProvider) generate(ctx context.Context, prompt string, onChunk func(string)) (result Result, err error) {
+	defer finishResult(&result, o.model, time.Now())
 	if o.client == nil {
-		return "", "", errors.New("openrouter client is not configured")
+		return result, errors.New("openrouter client is not configured")
 	}
 
 	// Для моделей семейства GPT‑5 используем ручной вызов OpenRouter Chat Completions API
 	// с явным указанием reasoning.effort и text.verbosity и без передачи temperature.
 	if isGPT5FamilyModel(o.model) {
-		return o.generateViaOpenRouterAPI(ctx, prompt)
+		return o.generateViaOpenRouterAPI(ctx, prompt, onChunk)
+	}
+
+	// Streams of other models are read directly so that they report usage.
+	if onChunk != nil {
+		return streamChatCompletion(ctx, "openrouter", o.baseURL, o.apiKey, o.model, prompt, onChunk)
 	}
 
 	// Для остальных моделей сохраняем текущее поведение через langchaingo.
-	output, err := llms.GenerateFromSinglePrompt(ctx, o.client, prompt,
+	opts := []llms.CallOption{
 		llms.WithModel(o.model),
 		llms.WithTemperature(0.1),
-	)
+	}
+	output, usage, err := generateViaSDK(ctx, o.client, prompt, opts...)
 
-	debug := o.buildGenericAPICallDebug()
+	result.APICall = o.buildGenericAPICallDebug()
 
 	if err != nil {
-		return "", debug, err
+		return result, err
 	}
-	return output, debug, nil
+	result.Text = output
+	result.Usage = usage
+	return result, nil
 }
 
 type openRouterChatMessage struct {
@@ -92,10 +111,35 @@ type openRouterChatMessage struct {
 
 type openRouterChatChoice struct {
 	Message openRouterChatMessage `json:"message"`
+	Delta   openRouterChatMessage `json:"delta"` // Set instead of Message in streamed chunks
+}
+
+type openRouterError struct {
+	Code    any    `json:"code"`
+	Message string `json:"message"`
+}
+
+type openRouterUsage struct {
+	PromptTokens            int `json:"prompt_tokens"`
+	CompletionTokens        int `json:"completion_tokens"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// usage converts the usage block; a missing block gives zero counts.
+func (u *openRouterUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens, ReasoningTokens: u.CompletionTokensDetails.ReasoningTokens}
 }
 
 type openRouterChatResponse struct {
+	Model   string                 `json:"model"`
 	Choices []openRouterChatChoice `json:"choices"`
+	Usage   *openRouterUsage       `json:"usage"` // Sent with the last chunk when streaming
+	Error   *openRouterError       `json:"error"`
 }
 
 type openRouterReasoningConfig struct {
@@ -111,12 +155,15 @@ type openRouterChatRequest struct {
 	Messages  []openRouterChatMessage  `json:"messages"`
 	Reasoning openRouterReasoningConfig `json:"reasoning"`
 	Text      openRouterTextConfig      `json:"text"`
+	Stream    bool                      `json:"stream,omitempty"`
+	// StreamOptions asks for the usage chunk at the end of a stream.
+	StreamOptions *chatStreamOptions `json:"stream_options,omitempty"`
 }
 
-func (o *openRouterProvider) generateViaOpenRouterAPI(ctx context.Context, prompt string) (string, string, error) {
+func (o *openRouterProvider) generateViaOpenRouterAPI(ctx context.Context, prompt string, onChunk func(string)) (Result, error) {
 	apiKey := strings.TrimSpace(o.apiKey)
 	if apiKey == "" {
-		return "", "", errors.New("openrouter API key is required for GPT-5 models")
+		return Result{}, errors.New("openrouter API key is required for GPT-5 models")
 	}
 
 	baseURL := strings.TrimSpace(o.baseURL)
@@ -139,6 +186,10 @@ func (o *openRouterProvider) generateViaOpenRouterAPI(ctx context.Context, promp
 		Text: openRouterTextConfig{
 			Verbosity: "high",
 		},
+		Stream: onChunk != nil,
+	}
+	if payload.Stream {
+		payload.StreamOptions = &chatStreamOptions{IncludeUsage: true}
 	}
 
 	// Build sanitized debug view BEFORE marshalling real payload.
@@ -156,6 +207,8 @@ func (o *openRouterProvider) generateViaOpenRouterAPI(ctx context.Context, promp
 		Text: openRouterTextConfig{
 			Verbosity: "high",
 		},
+		Stream:        payload.Stream,
+		StreamOptions: payload.StreamOptions,
 	}
 
 	debug := map[string]any{
@@ -176,47 +229,60 @@ func (o *openRouterProvider) generateViaOpenRouterAPI(ctx context.Context, promp
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return "", debugString, fmt.Errorf("failed to marshal OpenRouter chat payload: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("failed to marshal OpenRouter chat payload: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return "", debugString, fmt.Errorf("failed to create OpenRouter chat request: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("failed to create OpenRouter chat request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
+	if onChunk != nil {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Printf("openrouter chat request failed (model=%s): %v", o.model, err)
-		return "", debugString, fmt.Errorf("openrouter chat request failed: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("openrouter chat request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		limitedBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		log.Printf("openrouter chat returned status %d for model %s: %s", resp.StatusCode, o.model, string(limitedBody))
-		return "", debugString, fmt.Errorf("openrouter chat API returned non-2xx status %d", resp.StatusCode)
+		return Result{APICall: debugString}, fmt.Errorf("openrouter chat API returned non-2xx status %d", resp.StatusCode)
+	}
+
+	if onChunk != nil {
+		result, err := readChatCompletionStream(resp.Body, "openrouter", onChunk)
+		result.APICall = debugString
+		if err != nil {
+			log.Printf("openrouter chat stream failed for model %s: %v", o.model, err)
+			return result, err
+		}
+		return result, nil
 	}
 
 	var decoded openRouterChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
 		log.Printf("failed to decode openrouter chat payload for model %s: %v", o.model, err)
-		return "", debugString, fmt.Errorf("failed to decode openrouter chat payload: %w", err)
+		return Result{APICall: debugString}, fmt.Errorf("failed to decode openrouter chat payload: %w", err)
 	}
 
 	if len(decoded.Choices) == 0 {
 		log.Printf("openrouter chat response did not contain any choices for model %s", o.model)
-		return "", debugString, errors.New("openrouter chat response did not contain any choices")
+		return Result{APICall: debugString}, errors.New("openrouter chat response did not contain any choices")
 	}
 
 	text := strings.TrimSpace(decoded.Choices[0].Message.Content)
 	if text == "" {
 		log.Printf("openrouter chat response contained empty message content for model %s", o.model)
-		return "", debugString, errors.New("openrouter chat response did not contain text output")
+		return Result{APICall: debugString}, errors.New("openrouter chat response did not contain text output")
 	}
 
-	return text, debugString, nil
+	return Result{Text: text, APICall: debugString, Model: decoded.Model, Usage: decoded.Usage.usage()}, nil
 }
 
 // buildGenericAPICallDebug builds a high-level debug representation for SDK-based calls (non‑GPT‑5).
@@ -226,13 +292,12 @@ func (o *openRouterProvider) buildGenericAPICallDebug() string {
 		"model":    o.model,
 		"baseURL":  o.baseURL,
 		"sdk":      "langchaingo/llms.openai",
-		"call":     "llms.GenerateFromSinglePrompt",
+		"call":     "llms.Model.GenerateContent",
 		"input":    "[request_text]",
 		"headers": map[string]string{
 			"Authorization": "Bearer [apikey]",
 		},
 	}
-
 	data, err := json.MarshalIndent(debug, "", "  ")
 	if err != nil {
 		return ""
@@ -0,0 +1,87 @@
+package provider
+
+// Price is the list price of a model in US dollars per million tokens. Reasoning tokens are
+// billed as output.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+	// CacheRead and CacheWrite, when set, bill the input tokens read from and written to the
+	// prompt cache; otherwise they are billed as Input.
+	CacheRead  float64 `json:"cacheRead,omitempty"`
+	CacheWrite float64 `json:"cacheWrite,omitempty"`
+	// LongContextAbove, when set, switches calls with more input tokens than that to the
+	// LongInput and LongOutput prices.
+	LongContextAbove int     `json:"longContextAbove,omitempty"`
+	LongInput        float64 `json:"longInput,omitempty"`
+	LongOutput       float64 `json:"longOutput,omitempty"`
+}
+
+// modelPrices follow the catalogs in model_catalog.go. OpenRouter prices are those of its
+// default routing and may differ per upstream provider.
+var modelPrices = map[string]map[string]Price{
+	"openai": {
+		"gpt-5.1":      {Input: 1.25, Output: 10},
+		"gpt-5":        {Input: 1.25, Output: 10},
+		"gpt-5-mini":   {Input: 0.25, Output: 2},
+		"gpt-5-nano":   {Input: 0.05, Output: 0.40},
+		"gpt-4o-mini":  {Input: 0.15, Output: 0.60},
+		"gpt-4.1-mini": {Input: 0.40, Output: 1.60},
+		"o4-mini":      {Input: 1.10, Output: 4.40},
+		"gpt-4o":       {Input: 2.50, Output: 10},
+		"gpt-4.1":      {Input: 2, Output: 8},
+	},
+	"openrouter": {
+		"openai/gpt-5":                      {Input: 1.25, Output: 10},
+		"anthropic/claude-4.5-sonnet":       {Input: 3, Output: 15},
+		"google/gemini-2.5-pro":             {Input: 1.25, Output: 10, LongContextAbove: 200000, LongInput: 2.50, LongOutput: 15},
+		"google/gemini-2.5-flash":           {Input: 0.30, Output: 2.50},
+		"google/gemini-2.0-flash":           {Input: 0.10, Output: 0.40},
+		"openai/gpt-4o-mini":                {Input: 0.15, Output: 0.60},
+		"meta-llama/llama-3.1-70b-instruct": {Input: 0.40, Output: 0.40},
+		"x-ai/grok-code-fast-1":             {Input: 0.20, Output: 1.50},
+		"x-ai/grok-4-fast":                  {Input: 0.20, Output: 0.50},
+		"minimax/minimax-m2":                {Input: 0.30, Output: 1.20},
+		"z-ai/glm-4.6":                      {Input: 0.60, Output: 2.20},
+	},
+	"gemini": {
+		"gemini-2.5-pro":   {Input: 1.25, Output: 10, LongContextAbove: 200000, LongInput: 2.50, LongOutput: 15},
+		"gemini-2.5-flash": {Input: 0.30, Output: 2.50},
+	},
+	"anthropic": {
+		"claude-sonnet-4-5": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-opus-4-1":   {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+		"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.10, CacheWrite: 1.25},
+		"claude-sonnet-4-0": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	},
+}
+
+// ModelPrice returns the price of a model of providerName, and false when it is not in the
+// pricing table.
+func ModelPrice(providerName, model string) (Price, bool) {
+	price, ok := modelPrices[providerName][model]
+	return price, ok
+}
+
+// Cost returns the cost of a call in US dollars, and false when the model has no price. Calls
+// to local servers are free.
+func Cost(providerName, model string, usage Usage) (float64, bool) {
+	if providerName == "local" {
+		return 0, true
+	}
+	price, ok := ModelPrice(providerName, model)
+	if !ok {
+		return 0, false
+	}
+	input, output := price.Input, price.Output
+	if price.LongContextAbove > 0 && usage.InputTokens > price.LongContextAbove {
+		input, output = price.LongInput, price.LongOutput
+	}
+	cost := float64(usage.InputTokens)*input + float64(usage.OutputTokens)*output
+	if price.CacheRead > 0 {
+		cost += float64(usage.CacheReadTokens) * (price.CacheRead - input)
+	}
+	if price.CacheWrite > 0 {
+		cost += float64(usage.CacheWriteTokens) * (price.CacheWrite - input)
+	}
+	return cost / 1e6, true
+}
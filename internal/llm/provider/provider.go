@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Config describes the minimum information required to instantiate a provider implementation.
@@ -12,23 +13,58 @@ type Config struct {
 	Model    string
 	APIKey   string
 	BaseURL  string
+	// ThinkingBudget is the number of tokens a model may spend on extended thinking before it
+	// answers, for providers that support it; 0 disables thinking.
+	ThinkingBudget int
+	// ContextLength is the context window, in tokens, the local provider asks its server for;
+	// 0 keeps the server's default.
+	ContextLength int
 }
 
 // ModelInfo contains provider specific model metadata.
 type ModelInfo struct {
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
+	// ContextWindow is the maximum number of input tokens the model accepts; 0 if unknown.
+	ContextWindow int `json:"contextWindow,omitempty"`
+}
+
+// Usage is the token count of one call as reported by the provider. Counts the provider did
+// not report are zero.
+type Usage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	// ReasoningTokens is the part of OutputTokens spent on hidden reasoning.
+	ReasoningTokens int `json:"reasoningTokens,omitempty"`
+	// CacheReadTokens and CacheWriteTokens are the parts of InputTokens read from and written to
+	// the provider's prompt cache.
+	CacheReadTokens  int `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens int `json:"cacheWriteTokens,omitempty"`
+}
+
+// Result is the outcome of a Generate or GenerateStream call.
+type Result struct {
+	// Text is the raw LLM text output.
+	Text string
+	// APICall is a sanitized debug representation of the API call (no API keys, no raw prompt;
+	// placeholders instead). It is set even when the call fails.
+	APICall string
+	// Model is the model that answered as reported by the provider, or the configured model.
+	Model   string
+	Usage   Usage
+	Latency time.Duration
 }
 
 // LLMProvider describes the common capabilities we need from each vendor specific client.
 type LLMProvider interface {
 	// ListModels returns the list of models available for the configured provider/key combination.
 	ListModels(ctx context.Context) ([]ModelInfo, error)
-	// Generate executes the provided prompt with the configured model and returns:
-	// - the raw LLM text output,
-	// - a sanitized debug representation of the API call (no API keys, no raw prompt; placeholders instead),
-	// - and an error if the call failed.
-	Generate(ctx context.Context, prompt string) (string, string, error)
+	// Generate executes the provided prompt with the configured model and returns the answer
+	// with its usage, or an error if the call failed.
+	Generate(ctx context.Context, prompt string) (Result, error)
+	// GenerateStream works like Generate but hands the answer to onChunk piece by piece as it
+	// arrives. It returns the same Result as Generate once the answer is complete.
+	GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string)) (Result, error)
 }
 
 // Factory builds provider implementations based on the given configuration.
@@ -42,6 +78,10 @@ func Factory(cfg Config) (LLMProvider, error) {
 		return newOpenRouterProvider(cfg)
 	case "gemini":
 		return newGeminiProvider(cfg)
+	case "anthropic":
+		return newAnthropicProvider(cfg)
+	case "local":
+		return newLocalProvider(cfg)
 	default:
 		return nil, fmt.Errorf("provider %s is not supported", cfg.Provider)
 	}
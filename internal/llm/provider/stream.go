@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sseEvent is one server-sent event: its type (empty when the stream does not name its events)
+// and its data lines joined by newlines.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// errStreamDone stops readSSE at the "[DONE]" sentinel of chat completion streams.
+var errStreamDone = errors.New("stream done")
+
+// readSSE calls onEvent for every event of a text/event-stream body until the body ends, an
+// event carries "[DONE]" or onEvent returns an error. Comment lines, such as OpenRouter's
+// ": OPENROUTER PROCESSING" keep-alives, are skipped.
+func readSSE(body io.Reader, onEvent func(sseEvent) error) error {
+	reader := bufio.NewReader(body)
+	var event sseEvent
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = sseEvent{}
+			return nil
+		}
+		event.data = strings.Join(data, "\n")
+		current := event
+		event, data = sseEvent{}, nil
+		if current.data == "[DONE]" {
+			return errStreamDone
+		}
+		return onEvent(current)
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return ignoreStreamDone(err)
+			}
+		case strings.HasPrefix(line, ":"):
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event.name = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+		if readErr == io.EOF {
+			return ignoreStreamDone(dispatch())
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+func ignoreStreamDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// finishResult stamps the latency of a call that started at start and falls back to the
+// configured model when the provider did not name the one that answered.
+func finishResult(result *Result, model string, start time.Time) {
+	result.Latency = time.Since(start)
+	if result.Model == "" {
+		result.Model = model
+	}
+}
+
+// generateViaSDK runs a single prompt through a langchaingo model like
+// llms.GenerateFromSinglePrompt, and also returns the token usage for backends whose SDK
+// passes it on.
+func generateViaSDK(ctx context.Context, llm llms.Model, prompt string, opts ...llms.CallOption) (string, Usage, error) {
+	resp, err := llm.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(schema.ChatMessageTypeHuman, prompt)}, opts...)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return "", Usage{}, errors.New("empty response from model")
+	}
+	choice := resp.Choices[0]
+	usage := Usage{
+		InputTokens:     intInfo(choice.GenerationInfo, "PromptTokens"),
+		OutputTokens:    intInfo(choice.GenerationInfo, "CompletionTokens"),
+		ReasoningTokens: intInfo(choice.GenerationInfo, "ReasoningTokens"),
+	}
+	return choice.Content, usage, nil
+}
+
+func intInfo(info map[string]any, key string) int {
+	switch v := info[key].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
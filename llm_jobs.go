@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"shotgun_code/internal/llm/provider"
+)
+
+// States of an LLM execution, as reported by "llmExecutionStatus" events.
+const (
+	LLMExecutionRunning   = "running"
+	LLMExecutionCompleted = "completed"
+	LLMExecutionFailed    = "failed"
+	LLMExecutionCancelled = "cancelled"
+	LLMExecutionTimedOut  = "timedOut"
+)
+
+// Kinds of LLM executions.
+const (
+	LLMExecutionPrompt      = "prompt"
+	LLMExecutionAutoContext = "autoContext"
+)
+
+// defaultLLMTimeout bounds an LLM call when LLMSettings.TimeoutSeconds is zero.
+const defaultLLMTimeout = 10 * time.Minute
+
+// LLMExecution describes one LLM call. It is the payload of the "llmExecutionStatus" events
+// sent when the call starts and when it ends.
+type LLMExecution struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"` // LLMExecutionPrompt or LLMExecutionAutoContext
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	// ElapsedMs is the duration of the call, set once it has ended.
+	ElapsedMs int64 `json:"elapsedMs,omitempty"`
+}
+
+// LLMResponseChunk is the payload of the "llmResponseChunk" events: a piece of the answer of the
+// prompt execution with the given ID.
+type LLMResponseChunk struct {
+	ID    string `json:"id"`
+	Chunk string `json:"chunk"`
+}
+
+type llmJob struct {
+	execution LLMExecution
+	cancel    context.CancelFunc
+}
+
+// LLMJobRegistry tracks the LLM calls in flight so that each can be cancelled by ID. Unlike
+// ContextGenerator, a new call does not cancel the running ones.
+type LLMJobRegistry struct {
+	ctx    context.Context // parent of the calls' contexts
+	logf   func(format string, args ...interface{})
+	emit   func(LLMExecution) // reports a status change
+	mu     sync.Mutex
+	jobs   map[string]*llmJob
+	nextID int
+}
+
+func NewLLMJobRegistry(app *App) *LLMJobRegistry {
+	return &LLMJobRegistry{
+		ctx: app.ctx,
+		logf: func(format string, args ...interface{}) {
+			runtime.LogInfof(app.ctx, format, args...)
+		},
+		emit: func(execution LLMExecution) {
+			runtime.EventsEmit(app.ctx, "llmExecutionStatus", execution)
+		},
+		jobs: make(map[string]*llmJob),
+	}
+}
+
+// start registers a call and returns the context to run it with, bounded by timeout, the call's
+// execution ID and the function to call with the call's error once it returns. That function ends the job, reports
+// its final status and returns the error to surface: cancellations and timeouts are reported as
+// such rather than as whatever the provider made of the cancelled context.
+func (r *LLMJobRegistry) start(kind string, cfg provider.Config, timeout time.Duration) (context.Context, string, func(error) error) {
+	ctx, cancel := context.WithTimeout(r.ctx, timeout)
+	r.mu.Lock()
+	r.nextID++
+	job := &llmJob{
+		execution: LLMExecution{
+			ID:        strconv.Itoa(r.nextID),
+			Kind:      kind,
+			Provider:  cfg.Provider,
+			Model:     cfg.Model,
+			Status:    LLMExecutionRunning,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+	r.jobs[job.execution.ID] = job
+	r.mu.Unlock()
+	r.logf("LLM execution %s (%s) started via %s (%s), timeout %s", job.execution.ID, kind, cfg.Provider, cfg.Model, timeout)
+	r.emit(job.execution)
+
+	finish := func(err error) error {
+		defer cancel()
+		r.mu.Lock()
+		delete(r.jobs, job.execution.ID)
+		r.mu.Unlock()
+
+		execution := job.execution
+		execution.ElapsedMs = time.Since(execution.StartedAt).Milliseconds()
+		switch {
+		case err == nil:
+			execution.Status = LLMExecutionCompleted
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			execution.Status = LLMExecutionTimedOut
+			err = fmt.Errorf("LLM call timed out after %s", timeout)
+		case ctx.Err() != nil:
+			execution.Status = LLMExecutionCancelled
+			err = errors.New("LLM call cancelled")
+		default:
+			execution.Status = LLMExecutionFailed
+		}
+		if err != nil {
+			execution.Error = err.Error()
+		}
+		r.logf("LLM execution %s %s after %dms", execution.ID, execution.Status, execution.ElapsedMs)
+		r.emit(execution)
+		return err
+	}
+	return ctx, job.execution.ID, finish
+}
+
+// cancel stops the call with the given ID. It returns false if no such call is running.
+func (r *LLMJobRegistry) cancel(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if ok {
+		job.cancel()
+	}
+	return ok
+}
+
+// running lists the calls in flight, oldest first.
+func (r *LLMJobRegistry) running() []LLMExecution {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	executions := make([]LLMExecution, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		executions = append(executions, job.execution)
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].StartedAt.Before(executions[j].StartedAt)
+	})
+	return executions
+}
+
+// CancelLLMExecution aborts the LLM call with the given ID (see the "llmExecutionStatus" events).
+// The call then ends with the "cancelled" status.
+func (a *App) CancelLLMExecution(id string) error {
+	if a.llmJobs == nil || !a.llmJobs.cancel(id) {
+		return fmt.Errorf("no running LLM execution with ID %q", id)
+	}
+	runtime.LogInfof(a.ctx, "Cancelling LLM execution %s", id)
+	return nil
+}
+
+// GetRunningLLMExecutions lists the LLM calls in flight, oldest first.
+func (a *App) GetRunningLLMExecutions() []LLMExecution {
+	if a.llmJobs == nil {
+		return []LLMExecution{}
+	}
+	return a.llmJobs.running()
+}
+
+// startLLMExecution registers an LLM call with the configured timeout, see LLMJobRegistry.start.
+func (a *App) startLLMExecution(kind string, cfg provider.Config) (context.Context, string, func(error) error) {
+	return a.llmJobs.start(kind, cfg, a.settings.LLMSettings.timeout())
+}
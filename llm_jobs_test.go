@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shotgun_code/internal/llm/provider"
+)
+
+// testRegistry returns a registry running its calls under parent and the function listing the
+// status events it emitted.
+func testRegistry(t *testing.T, parent context.Context) (*LLMJobRegistry, func() []LLMExecution) {
+	var mu sync.Mutex
+	var events []LLMExecution
+	r := &LLMJobRegistry{
+		ctx:  parent,
+		logf: t.Logf,
+		emit: func(execution LLMExecution) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, execution)
+		},
+		jobs: make(map[string]*llmJob),
+	}
+	return r, func() []LLMExecution {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]LLMExecution(nil), events...)
+	}
+}
+
+var testLLMConfig = provider.Config{Provider: "openai", Model: "gpt-test"}
+
+func TestLLMJobRegistryFinish(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    string
+		wantError string
+	}{
+		{"completed", nil, LLMExecutionCompleted, ""},
+		{"failed", errors.New("rate limited"), LLMExecutionFailed, "rate limited"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, events := testRegistry(t, context.Background())
+			_, id, finish := r.start(LLMExecutionPrompt, testLLMConfig, time.Minute)
+			if running := r.running(); len(running) != 1 || running[0].ID != id || running[0].Status != LLMExecutionRunning {
+				t.Fatalf("running = %+v", running)
+			}
+			err := finish(tt.err)
+			if (err == nil) != (tt.wantError == "") || (err != nil && err.Error() != tt.wantError) {
+				t.Errorf("finish = %v, want %q", err, tt.wantError)
+			}
+			if running := r.running(); len(running) != 0 {
+				t.Errorf("a finished job is still running: %+v", running)
+			}
+			if r.cancel(id) {
+				t.Errorf("a finished job was cancelled")
+			}
+			got := events()
+			if len(got) != 2 || got[0].Status != LLMExecutionRunning || got[1].Status != tt.status || got[1].Error != tt.wantError {
+				t.Fatalf("events = %+v, want running then %s", got, tt.status)
+			}
+			if got[1].ID != id || got[1].Kind != LLMExecutionPrompt || got[1].Provider != "openai" || got[1].Model != "gpt-test" {
+				t.Errorf("final event = %+v", got[1])
+			}
+		})
+	}
+}
+
+func TestLLMJobRegistryCancel(t *testing.T) {
+	r, events := testRegistry(t, context.Background())
+	ctx, id, finish := r.start(LLMExecutionAutoContext, testLLMConfig, time.Minute)
+	_, other, finishOther := r.start(LLMExecutionPrompt, testLLMConfig, time.Minute)
+
+	if r.cancel("no-such-id") {
+		t.Errorf("an unknown ID was cancelled")
+	}
+	if !r.cancel(id) {
+		t.Fatalf("the running job %s was not cancelled", id)
+	}
+	<-ctx.Done()
+	// Whatever error the provider makes of the cancelled context, the call is reported as cancelled.
+	if err := finish(ctx.Err()); err == nil || err.Error() != "LLM call cancelled" {
+		t.Errorf("finish = %v, want the cancellation", err)
+	}
+	if running := r.running(); len(running) != 1 || running[0].ID != other {
+		t.Errorf("running = %+v, want only the other job", running)
+	}
+	if got := events(); got[len(got)-1].ID != id || got[len(got)-1].Status != LLMExecutionCancelled {
+		t.Errorf("last event = %+v, want %s cancelled", got[len(got)-1], id)
+	}
+	if err := finishOther(nil); err != nil {
+		t.Errorf("the other job failed: %v", err)
+	}
+}
+
+func TestLLMJobRegistryParentCancelled(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	r, events := testRegistry(t, parent)
+	ctx, _, finish := r.start(LLMExecutionPrompt, testLLMConfig, time.Minute)
+	cancel()
+	<-ctx.Done()
+	if err := finish(errors.New("request aborted")); err == nil || err.Error() != "LLM call cancelled" {
+		t.Errorf("finish = %v, want the cancellation", err)
+	}
+	if got := events(); got[1].Status != LLMExecutionCancelled {
+		t.Errorf("final event = %+v", got[1])
+	}
+}
+
+func TestLLMJobRegistryTimeout(t *testing.T) {
+	r, events := testRegistry(t, context.Background())
+	ctx, _, finish := r.start(LLMExecutionPrompt, testLLMConfig, 10*time.Millisecond)
+	<-ctx.Done()
+	err := finish(ctx.Err())
+	if err == nil || err.Error() != "LLM call timed out after 10ms" {
+		t.Errorf("finish = %v, want the timeout", err)
+	}
+	got := events()
+	if got[1].Status != LLMExecutionTimedOut || got[1].Error != err.Error() || got[1].ElapsedMs < 10 {
+		t.Errorf("final event = %+v", got[1])
+	}
+	if running := r.running(); len(running) != 0 {
+		t.Errorf("a timed out job is still running: %+v", running)
+	}
+}
+
+func TestLLMJobRegistryRunningOrder(t *testing.T) {
+	r, _ := testRegistry(t, context.Background())
+	var finishes []func(error) error
+	for i := 0; i < 3; i++ {
+		_, _, finish := r.start(LLMExecutionPrompt, testLLMConfig, time.Minute)
+		finishes = append(finishes, finish)
+		time.Sleep(time.Millisecond)
+	}
+	finishes[1](nil)
+	var ids []string
+	for _, execution := range r.running() {
+		ids = append(ids, execution.ID)
+	}
+	if strings.Join(ids, ",") != "1,3" {
+		t.Errorf("running IDs = %v, want 1,3", ids)
+	}
+	finishes[0](nil)
+	finishes[2](nil)
+}
+
+// TestLLMJobRegistryConcurrent starts, lists, cancels and finishes jobs from many goroutines;
+// run it with -race.
+func TestLLMJobRegistryConcurrent(t *testing.T) {
+	r, events := testRegistry(t, context.Background())
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, id, finish := r.start(LLMExecutionPrompt, testLLMConfig, time.Minute)
+			r.running()
+			if i%2 == 0 {
+				r.cancel(id)
+				<-ctx.Done()
+			}
+			finish(ctx.Err())
+		}(i)
+	}
+	wg.Wait()
+
+	if running := r.running(); len(running) != 0 {
+		t.Errorf("%d jobs are still running", len(running))
+	}
+	statuses := map[string]int{}
+	ids := map[string]bool{}
+	for _, execution := range events() {
+		statuses[execution.Status]++
+		ids[execution.ID] = true
+	}
+	want := map[string]int{LLMExecutionRunning: n, LLMExecutionCompleted: n / 2, LLMExecutionCancelled: n / 2}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	for i := 1; i <= n; i++ {
+		if !ids[strconv.Itoa(i)] {
+			t.Errorf("no events for job %d", i)
+		}
+	}
+}
+
+func TestAppWithoutLLMJobs(t *testing.T) {
+	a := &App{}
+	if running := a.GetRunningLLMExecutions(); running == nil || len(running) != 0 {
+		t.Errorf("GetRunningLLMExecutions = %#v, want an empty list", running)
+	}
+	if err := a.CancelLLMExecution("1"); err == nil {
+		t.Errorf("a job was cancelled without a registry")
+	}
+}
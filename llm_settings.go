@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
@@ -23,6 +25,10 @@ func normalizeProviderName(name string) string {
 		return LLMProviderOpenRouter
 	case LLMProviderGemini:
 		return LLMProviderGemini
+	case LLMProviderAnthropic:
+		return LLMProviderAnthropic
+	case LLMProviderLocal:
+		return LLMProviderLocal
 	default:
 		return ""
 	}
@@ -36,6 +42,8 @@ func defaultModelForProvider(providerName string) string {
 		return "gemini-2.5-pro"
 	case LLMProviderOpenRouter:
 		return "openai/gpt-5"
+	case LLMProviderAnthropic:
+		return "claude-sonnet-4-5"
 	default:
 		return ""
 	}
@@ -49,11 +57,27 @@ func (l LLMSettings) keyForProvider(providerName string) string {
 		return strings.TrimSpace(l.OpenRouterKey)
 	case LLMProviderGemini:
 		return strings.TrimSpace(l.GeminiKey)
+	case LLMProviderAnthropic:
+		return strings.TrimSpace(l.AnthropicKey)
 	default:
 		return ""
 	}
 }
 
+// isConfigured reports whether providerName can be used: it has an API key, or needs none.
+func (l LLMSettings) isConfigured(providerName string) bool {
+	providerName = normalizeProviderName(providerName)
+	return providerName == LLMProviderLocal || (providerName != "" && l.keyForProvider(providerName) != "")
+}
+
+// timeout returns the limit of a single LLM call.
+func (l LLMSettings) timeout() time.Duration {
+	if l.TimeoutSeconds > 0 {
+		return time.Duration(l.TimeoutSeconds) * time.Second
+	}
+	return defaultLLMTimeout
+}
+
 func (a *App) ensureLLMSettingsDefaults() {
 	settings := &a.settings.LLMSettings
 	settings.ActiveProvider = normalizeProviderName(settings.ActiveProvider)
@@ -62,8 +86,12 @@ func (a *App) ensureLLMSettingsDefaults() {
 	settings.OpenAIKey = strings.TrimSpace(settings.OpenAIKey)
 	settings.OpenRouterKey = strings.TrimSpace(settings.OpenRouterKey)
 	settings.GeminiKey = strings.TrimSpace(settings.GeminiKey)
+	settings.AnthropicKey = strings.TrimSpace(settings.AnthropicKey)
+	settings.LocalBaseURL = strings.TrimSpace(settings.LocalBaseURL)
+	settings.LocalContextLength = max(settings.LocalContextLength, 0)
+	settings.TimeoutSeconds = max(settings.TimeoutSeconds, 0)
 
-	if settings.ActiveProvider != "" && settings.keyForProvider(settings.ActiveProvider) == "" {
+	if settings.ActiveProvider != "" && !settings.isConfigured(settings.ActiveProvider) {
 		runtime.LogWarning(a.ctx, "Active LLM provider is missing an API key; disabling auto-context.")
 		settings.ActiveProvider = ""
 		settings.Model = ""
@@ -75,7 +103,7 @@ func (a *App) ensureLLMSettingsDefaults() {
 
 func (a *App) HasActiveLlmKey() bool {
 	settings := a.settings.LLMSettings
-	return settings.ActiveProvider != "" && settings.keyForProvider(settings.ActiveProvider) != ""
+	return settings.ActiveProvider != "" && settings.isConfigured(settings.ActiveProvider)
 }
 
 func (a *App) GetLlmSettings() LLMSettings {
@@ -87,6 +115,9 @@ func (a *App) SetLlmApiKey(providerName, apiKey string) error {
 	if providerName == "" {
 		return errors.New("unknown provider")
 	}
+	if providerName == LLMProviderLocal {
+		return errors.New("the local provider does not use an API key")
+	}
 	apiKey = strings.TrimSpace(apiKey)
 	switch providerName {
 	case LLMProviderOpenAI:
@@ -95,6 +126,8 @@ func (a *App) SetLlmApiKey(providerName, apiKey string) error {
 		a.settings.LLMSettings.OpenRouterKey = apiKey
 	case LLMProviderGemini:
 		a.settings.LLMSettings.GeminiKey = apiKey
+	case LLMProviderAnthropic:
+		a.settings.LLMSettings.AnthropicKey = apiKey
 	}
 	if a.settings.LLMSettings.ActiveProvider == providerName && strings.TrimSpace(a.settings.LLMSettings.Model) == "" {
 		a.settings.LLMSettings.Model = defaultModelForProvider(providerName)
@@ -115,7 +148,7 @@ func (a *App) SetLlmProvider(providerName string) error {
 		a.invalidateProviderCache()
 		return a.saveSettings()
 	}
-	if a.settings.LLMSettings.keyForProvider(providerName) == "" {
+	if !a.settings.LLMSettings.isConfigured(providerName) {
 		return fmt.Errorf("set API key for %s before activating it", providerName)
 	}
 	a.settings.LLMSettings.ActiveProvider = providerName
@@ -127,6 +160,7 @@ func (a *App) SetLlmProvider(providerName string) error {
 		return fmt.Errorf("failed to save provider: %w", err)
 	}
 	a.invalidateProviderCache()
+	a.preloadTokenEncoding()
 	return nil
 }
 
@@ -135,7 +169,7 @@ func (a *App) SetLlmModel(providerName, model string) error {
 	if providerName == "" {
 		return errors.New("unknown provider")
 	}
-	if a.settings.LLMSettings.keyForProvider(providerName) == "" {
+	if !a.settings.LLMSettings.isConfigured(providerName) {
 		return fmt.Errorf("set API key for %s before selecting a model", providerName)
 	}
 	if strings.TrimSpace(model) == "" {
@@ -149,6 +183,7 @@ func (a *App) SetLlmModel(providerName, model string) error {
 		return fmt.Errorf("failed to save model selection: %w", err)
 	}
 	a.invalidateProviderCache()
+	a.preloadTokenEncoding()
 	return nil
 }
 
@@ -161,20 +196,98 @@ func (a *App) SetLlmBaseURL(baseURL string) error {
 	return nil
 }
 
+// SetLlmTimeout sets the limit of a single LLM call in seconds; zero restores the default.
+func (a *App) SetLlmTimeout(seconds int) error {
+	if seconds < 0 {
+		return errors.New("timeout cannot be negative")
+	}
+	a.settings.LLMSettings.TimeoutSeconds = seconds
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save timeout: %w", err)
+	}
+	runtime.LogInfof(a.ctx, "LLM call timeout set to %s", a.settings.LLMSettings.timeout())
+	return nil
+}
+
+// SetLlmThinkingBudget sets the extended thinking budget of Anthropic models in tokens; zero
+// disables thinking.
+func (a *App) SetLlmThinkingBudget(tokens int) error {
+	if tokens != 0 && (tokens < provider.MinAnthropicThinkingBudget || tokens > provider.MaxAnthropicThinkingBudget) {
+		return fmt.Errorf("thinking budget must be 0 or between %d and %d tokens", provider.MinAnthropicThinkingBudget, provider.MaxAnthropicThinkingBudget)
+	}
+	a.settings.LLMSettings.AnthropicThinkingBudget = tokens
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save thinking budget: %w", err)
+	}
+	a.invalidateProviderCache()
+	runtime.LogInfof(a.ctx, "Anthropic thinking budget set to %d tokens", tokens)
+	return nil
+}
+
+// SetLlmLocalBaseURL sets the address of the local provider's server, such as
+// http://localhost:11434 for Ollama or http://localhost:1234/v1 for LM Studio.
+func (a *App) SetLlmLocalBaseURL(baseURL string) error {
+	a.settings.LLMSettings.LocalBaseURL = strings.TrimSpace(baseURL)
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save local server URL: %w", err)
+	}
+	a.invalidateProviderCache()
+	return nil
+}
+
+// SetLlmLocalContextLength sets the context window of local models in tokens; zero keeps the
+// server's default.
+func (a *App) SetLlmLocalContextLength(tokens int) error {
+	if tokens < 0 {
+		return errors.New("context length cannot be negative")
+	}
+	a.settings.LLMSettings.LocalContextLength = tokens
+	if err := a.saveSettings(); err != nil {
+		return fmt.Errorf("failed to save local context length: %w", err)
+	}
+	a.invalidateProviderCache()
+	runtime.LogInfof(a.ctx, "Local model context length set to %d tokens", tokens)
+	return nil
+}
+
+// localModelDiscoveryTimeout bounds the model listing of a local server.
+const localModelDiscoveryTimeout = 10 * time.Second
+
+// ListLlmModels lists the models of a provider: its catalog, or for the local provider the
+// models its configured server offers.
 func (a *App) ListLlmModels(providerName string) ([]provider.ModelInfo, error) {
 	providerName = normalizeProviderName(providerName)
 	if providerName == "" {
 		return nil, errors.New("unknown provider")
 	}
+	if providerName == LLMProviderLocal {
+		return a.DiscoverLocalLlmModels(a.settings.LLMSettings.LocalBaseURL)
+	}
 	return provider.ModelCatalog(providerName)
 }
 
+// DiscoverLocalLlmModels lists the models of the local server at baseURL (Ollama's default when
+// empty), so that a server address can be checked before it is saved.
+func (a *App) DiscoverLocalLlmModels(baseURL string) ([]provider.ModelInfo, error) {
+	instance, err := provider.Factory(provider.Config{
+		Provider:      LLMProviderLocal,
+		BaseURL:       strings.TrimSpace(baseURL),
+		ContextLength: a.settings.LLMSettings.LocalContextLength,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, localModelDiscoveryTimeout)
+	defer cancel()
+	return instance.ListModels(ctx)
+}
+
 func (a *App) invalidateProviderCache() {
 	a.llmCache = cachedProvider{}
 }
 
 func (a *App) getOrCreateProvider(cfg provider.Config) (provider.LLMProvider, error) {
-	if cfg.Provider == "" || cfg.APIKey == "" || cfg.Model == "" {
+	if cfg.Provider == "" || cfg.Model == "" || (cfg.APIKey == "" && cfg.Provider != LLMProviderLocal) {
 		return nil, errors.New("incomplete provider configuration")
 	}
 	if a.llmCache.instance != nil && a.llmCache.cfg == cfg {
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"sort"
+
+	"shotgun_code/internal/llm/provider"
+	"shotgun_code/pkg/shotgun"
+)
+
+// LLMCallStats are the usage numbers of the LLM call behind a history item.
+type LLMCallStats struct {
+	Provider        string `json:"provider"`
+	Model           string `json:"model"` // Model that answered, as reported by the provider
+	InputTokens     int    `json:"inputTokens"`
+	OutputTokens    int    `json:"outputTokens"`
+	ReasoningTokens int    `json:"reasoningTokens,omitempty"`
+	// CacheReadTokens is the part of InputTokens read from the provider's prompt cache.
+	CacheReadTokens int `json:"cacheReadTokens,omitempty"`
+	// UsageEstimated is set when the provider did not report usage and the counts were
+	// estimated locally from the prompt and the answer.
+	UsageEstimated bool  `json:"usageEstimated,omitempty"`
+	LatencyMs      int64 `json:"latencyMs"`
+	// CostUSD is nil when the model is not in the pricing table.
+	CostUSD *float64 `json:"costUSD,omitempty"`
+}
+
+// newLLMCallStats measures a call made with cfg. Usage is estimated for answers of providers
+// that do not report it; failed calls keep zero counts.
+func newLLMCallStats(cfg provider.Config, result provider.Result, prompt string) *LLMCallStats {
+	usage := result.Usage
+	estimated := false
+	if usage == (provider.Usage{}) && result.Text != "" {
+		counter := shotgun.NewTokenCounter(cfg.Model)
+		usage = provider.Usage{InputTokens: counter.CountTokens(prompt), OutputTokens: counter.CountTokens(result.Text)}
+		estimated = true
+	}
+	stats := &LLMCallStats{
+		Provider:        cfg.Provider,
+		Model:           result.Model,
+		InputTokens:     usage.InputTokens,
+		OutputTokens:    usage.OutputTokens,
+		ReasoningTokens: usage.ReasoningTokens,
+		CacheReadTokens: usage.CacheReadTokens,
+		UsageEstimated:  estimated,
+		LatencyMs:       result.Latency.Milliseconds(),
+	}
+	// Prices are keyed by catalog names; providers may answer with a dated snapshot name.
+	for _, model := range []string{cfg.Model, result.Model} {
+		if cost, ok := provider.Cost(cfg.Provider, model, usage); ok {
+			stats.CostUSD = &cost
+			break
+		}
+	}
+	return stats
+}
+
+// LLMSpend sums the LLM calls of one day, provider and model.
+type LLMSpend struct {
+	Day             string  `json:"day"` // Local date, YYYY-MM-DD
+	Provider        string  `json:"provider"`
+	Model           string  `json:"model"`
+	Calls           int     `json:"calls"`
+	InputTokens     int     `json:"inputTokens"`
+	OutputTokens    int     `json:"outputTokens"`
+	ReasoningTokens int     `json:"reasoningTokens"`
+	CostUSD         float64 `json:"costUSD"`
+	// UnpricedCalls counts the calls left out of CostUSD because their model has no price.
+	UnpricedCalls int `json:"unpricedCalls"`
+	// EstimatedCalls counts the calls whose token counts are estimated (LLMCallStats.UsageEstimated).
+	EstimatedCalls int `json:"estimatedCalls"`
+}
+
+// GetLLMSpend aggregates the LLM calls recorded in the prompt history per day, provider and
+// model, newest day first. Clearing the history clears the spend as well.
+func (a *App) GetLLMSpend() []LLMSpend {
+	type key struct{ day, provider, model string }
+	totals := make(map[key]*LLMSpend)
+	for _, item := range a.GetPromptHistory() {
+		stats := item.Stats
+		if stats == nil {
+			continue
+		}
+		k := key{item.Timestamp.Local().Format("2006-01-02"), stats.Provider, stats.Model}
+		spend := totals[k]
+		if spend == nil {
+			spend = &LLMSpend{Day: k.day, Provider: k.provider, Model: k.model}
+			totals[k] = spend
+		}
+		spend.Calls++
+		spend.InputTokens += stats.InputTokens
+		spend.OutputTokens += stats.OutputTokens
+		spend.ReasoningTokens += stats.ReasoningTokens
+		if stats.UsageEstimated {
+			spend.EstimatedCalls++
+		}
+		if stats.CostUSD != nil {
+			spend.CostUSD += *stats.CostUSD
+		} else {
+			spend.UnpricedCalls++
+		}
+	}
+
+	spends := make([]LLMSpend, 0, len(totals))
+	for _, spend := range totals {
+		spends = append(spends, *spend)
+	}
+	sort.Slice(spends, func(i, j int) bool {
+		if spends[i].Day != spends[j].Day {
+			return spends[i].Day > spends[j].Day
+		}
+		if spends[i].Provider != spends[j].Provider {
+			return spends[i].Provider < spends[j].Provider
+		}
+		return spends[i].Model < spends[j].Model
+	})
+	return spends
+}
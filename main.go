@@ -2,45 +2,30 @@ package main
 
 import (
 	"embed"
-	// "io/ioutil" // Deprecated, replaced by os
 	"log"
-	"os"                // Added for os.ReadFile
+	"os"
 	goruntime "runtime" // Alias for standard library runtime
 
-	// Required for runtime.OpenDirectoryDialog wrapper if used
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/menu" // Import menu package
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
 	"github.com/wailsapp/wails/v2/pkg/options/linux"
-	// Alias for Wails runtime package
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
-// Add this SelectDirectory method to the App struct in app.go
-// Or, if you prefer to keep app.go clean of Wails runtime imports for some reason,
-// you can define it here and pass `app.ctx` if needed, though it's better in app.go.
-// For this example, let's assume it's added to app.go and `app.ctx` is used.
-// Example of how it would look in app.go:
-/*
-func (a *App) SelectDirectory() (string, error) {
-	selectedDirectory, err := wailsRuntime.OpenDirectoryDialog(a.ctx, wailsRuntime.OpenDialogOptions{
-		Title: "Select Project Directory",
-	})
-	if err != nil {
-		return "", err
+func main() {
+	// Headless subcommands (e.g. `shotgun-code context <dir>`) run without starting Wails.
+	if exitCode, handled := runCLI(os.Args[1:], os.Stdout, os.Stderr); handled {
+		os.Exit(exitCode)
 	}
-	return selectedDirectory, nil
-}
-*/
 
-func main() {
 	app := NewApp() // Creates an instance of App from app.go
 	// Load icons
 
-	iconPNG, errPNG := os.ReadFile("appicon.png") // Changed from ioutil.ReadFile
+	iconPNG, errPNG := os.ReadFile("appicon.png")
 	if errPNG != nil {
 		log.Println("Warning: Could not load appicon.png:", errPNG)
 	}
@@ -0,0 +1,39 @@
+package gitrepo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// budgetWriter collects per-file entries of a section until maxBytes is reached; the entries that
+// do not fit are named in a closing note instead.
+type budgetWriter struct {
+	maxBytes int
+	sb       strings.Builder
+	skipped  []string
+}
+
+// add appends entry unless it would overflow the budget, in which case name is remembered.
+// It reports whether the entry was written.
+func (w *budgetWriter) add(name, entry string) bool {
+	if w.sb.Len()+len(entry) > w.maxBytes {
+		w.skipped = append(w.skipped, name)
+		return false
+	}
+	w.sb.WriteString(entry)
+	return true
+}
+
+// full reports whether nothing more fits, so callers can skip producing further entries.
+func (w *budgetWriter) full() bool {
+	return w.sb.Len() >= w.maxBytes
+}
+
+// String returns the entries followed by the note about skipped ones, without a trailing newline.
+func (w *budgetWriter) String() string {
+	out := w.sb.String()
+	if len(w.skipped) > 0 {
+		out += fmt.Sprintf("# Budget of %d bytes reached, left out: %s\n", w.maxBytes, strings.Join(w.skipped, ", "))
+	}
+	return strings.TrimRight(out, "\n")
+}
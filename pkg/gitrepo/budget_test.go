@@ -0,0 +1,41 @@
+package gitrepo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBudgetWriter(t *testing.T) {
+	w := &budgetWriter{maxBytes: 10}
+	if !w.add("a", "aaaa\n") {
+		t.Errorf("a did not fit")
+	}
+	if w.add("b", "bbbbbbbb\n") {
+		t.Errorf("b fit although it overflows the budget")
+	}
+	// A smaller entry after a skipped one still fits.
+	if !w.add("c", "ccc\n") {
+		t.Errorf("c did not fit")
+	}
+	if w.full() {
+		t.Errorf("full with 9 of 10 bytes used")
+	}
+	if w.add("d", "dd\n") || !reflect.DeepEqual(w.skipped, []string{"b", "d"}) {
+		t.Errorf("skipped = %v, want b and d", w.skipped)
+	}
+	if got, want := w.String(), "aaaa\nccc\n# Budget of 10 bytes reached, left out: b, d"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	w = &budgetWriter{maxBytes: 4}
+	w.add("a", "bbb\n")
+	if !w.full() {
+		t.Errorf("not full with the budget used up")
+	}
+	if got := w.String(); got != "bbb" {
+		t.Errorf("String() = %q, want the trailing newline trimmed", got)
+	}
+	if got := (&budgetWriter{maxBytes: 10}).String(); got != "" {
+		t.Errorf("empty writer = %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package gitrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"shotgun_code/pkg/shotgun"
+)
+
+// DefaultDiffBytes caps the diff section when no budget is given.
+const DefaultDiffBytes = 200_000
+
+// Diff returns the unified diff of mode (Modified, Staged or Changed against base), limited to
+// paths (relative to RootDir, forward slashes). A nil paths keeps every file. Once maxBytes is
+// reached the remaining files are listed in a closing note instead; maxBytes <= 0 means
+// DefaultDiffBytes.
+func (r *Repo) Diff(ctx context.Context, mode, base string, paths []string, maxBytes int) (string, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultDiffBytes
+	}
+	args, err := r.diffArgs(ctx, mode, base)
+	if err != nil {
+		return "", err
+	}
+	names, err := r.run(ctx, append(args, "--name-only", "--relative", "-z")...)
+	if err != nil {
+		return "", err
+	}
+	out, err := r.run(ctx, append(args, "--relative", "--no-color", "--no-ext-diff")...)
+	if err != nil {
+		return "", err
+	}
+	return packDiff(splitDiff(string(out)), splitNUL(names), paths, maxBytes), nil
+}
+
+// packDiff writes the per-file chunks of a diff within maxBytes, keeping those of paths only
+// (all of them when paths is nil). files names the chunk at the same index.
+func packDiff(chunks, files, paths []string, maxBytes int) string {
+	if len(chunks) != len(files) {
+		// Both listings come from the same diff, so this only happens with exotic entries
+		// (e.g. submodule summaries); keep the diff whole rather than guessing.
+		files = nil
+	}
+
+	var keep map[string]bool
+	if paths != nil && files != nil {
+		keep = make(map[string]bool, len(paths))
+		for _, p := range paths {
+			keep[p] = true
+		}
+	}
+
+	w := &budgetWriter{maxBytes: maxBytes}
+	for i, chunk := range chunks {
+		if keep != nil && !keep[files[i]] {
+			continue
+		}
+		name := fmt.Sprintf("chunk %d", i+1)
+		if files != nil {
+			name = files[i]
+		}
+		w.add(name, chunk)
+	}
+	return w.String()
+}
+
+// splitDiff cuts a unified diff into its per-file chunks, each starting with "diff --git".
+func splitDiff(diff string) []string {
+	var chunks []string
+	start := -1
+	for pos := 0; pos < len(diff); {
+		end := strings.IndexByte(diff[pos:], '\n')
+		next := len(diff)
+		if end >= 0 {
+			next = pos + end + 1
+		}
+		if strings.HasPrefix(diff[pos:], "diff --git ") {
+			if start >= 0 {
+				chunks = append(chunks, diff[start:pos])
+			}
+			start = pos
+		}
+		pos = next
+	}
+	if start >= 0 {
+		chunks = append(chunks, diff[start:])
+	}
+	return chunks
+}
+
+// DiffSection returns a payload section with the diff of mode, limited to the packed files.
+// It fails when the project is not a git working tree.
+func DiffSection(mode, base string, maxBytes int) shotgun.SectionFunc {
+	return func(ctx context.Context, rootDir string, files []string) (shotgun.Section, error) {
+		repo, err := Open(rootDir)
+		if err != nil {
+			return shotgun.Section{}, err
+		}
+		diff, err := repo.Diff(ctx, mode, base, files, maxBytes)
+		if err != nil {
+			return shotgun.Section{}, err
+		}
+		attrs := []shotgun.Attr{{Name: "mode", Value: mode}}
+		if mode == Changed {
+			attrs = append(attrs, shotgun.Attr{Name: "base", Value: base})
+		}
+		return shotgun.Section{Name: "git-diff", Content: diff, Language: "diff", Attrs: attrs}, nil
+	}
+}
@@ -0,0 +1,113 @@
+package gitrepo
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"shotgun_code/pkg/shotgun"
+)
+
+const testDiff = "diff --git a/a.txt b/a.txt\n" +
+	"--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+diff --git looks like a header but is a line\n" +
+	"diff --git a/b.txt b/b.txt\n" +
+	"--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+b2\n"
+
+func TestSplitDiff(t *testing.T) {
+	chunks := splitDiff(testDiff)
+	if len(chunks) != 2 || !strings.HasPrefix(chunks[0], "diff --git a/a.txt") || !strings.HasPrefix(chunks[1], "diff --git a/b.txt") {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	if strings.Join(chunks, "") != testDiff {
+		t.Errorf("chunks do not join to the diff")
+	}
+	if chunks := splitDiff(""); chunks != nil {
+		t.Errorf("splitDiff(\"\") = %q, want none", chunks)
+	}
+	if chunks := splitDiff("warning: no header\n"); chunks != nil {
+		t.Errorf("text before the first header was kept: %q", chunks)
+	}
+}
+
+func TestPackDiff(t *testing.T) {
+	chunks := splitDiff(testDiff)
+	files := []string{"a.txt", "b.txt"}
+	tests := []struct {
+		name     string
+		files    []string
+		paths    []string
+		maxBytes int
+		want     string
+	}{
+		{"everything", files, nil, 1000, strings.TrimRight(testDiff, "\n")},
+		{"path filter", files, []string{"b.txt"}, 1000, strings.TrimRight(chunks[1], "\n")},
+		{"budget", files, nil, len(chunks[0]), chunks[0] + "# Budget of " + strconv.Itoa(len(chunks[0])) + " bytes reached, left out: b.txt"},
+		// Without a name per chunk the filter cannot apply: the whole diff is kept, chunks are
+		// named by position.
+		{"count mismatch", []string{"a.txt"}, []string{"b.txt"}, len(chunks[0]), chunks[0] + "# Budget of " + strconv.Itoa(len(chunks[0])) + " bytes reached, left out: chunk 2"},
+	}
+	for _, tt := range tests {
+		if got := packDiff(chunks, tt.files, tt.paths, tt.maxBytes); got != tt.want {
+			t.Errorf("%s: packDiff =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	root := workingTree(t)
+	repo, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	diff, err := repo.Diff(ctx, Modified, "", nil, 0)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	for _, want := range []string{"diff --git a/a.txt b/a.txt", "+a changed", "diff --git a/b.txt b/b.txt", "deleted file"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff lacks %q:\n%s", want, diff)
+		}
+	}
+
+	diff, err = repo.Diff(ctx, Staged, "", []string{"sub/c.txt"}, 0)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !strings.Contains(diff, "+c changed") || strings.Contains(diff, "staged.txt") {
+		t.Errorf("staged diff is not limited to sub/c.txt:\n%s", diff)
+	}
+
+	diff, err = repo.Diff(ctx, Modified, "", nil, 10)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if diff != "# Budget of 10 bytes reached, left out: a.txt, b.txt, gone.txt" {
+		t.Errorf("diff over budget = %q", diff)
+	}
+
+	if _, err := repo.Diff(ctx, Untracked, "", nil, 0); err == nil {
+		t.Errorf("a diff of untracked files was produced")
+	}
+}
+
+func TestDiffSection(t *testing.T) {
+	root := workingTree(t)
+	section, err := DiffSection(Modified, "", 0)(context.Background(), root, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("DiffSection: %v", err)
+	}
+	if section.Name != "git-diff" || section.Language != "diff" || !reflect.DeepEqual(section.Attrs, []shotgun.Attr{{Name: "mode", Value: Modified}}) {
+		t.Errorf("section = %+v", section)
+	}
+	if !strings.Contains(section.Content, "a/a.txt") || strings.Contains(section.Content, "b.txt") {
+		t.Errorf("section is not limited to the packed files:\n%s", section.Content)
+	}
+
+	if _, err := DiffSection(Modified, "", 0)(context.Background(), t.TempDir(), nil); err == nil {
+		t.Errorf("a diff section was produced outside a repository")
+	}
+}
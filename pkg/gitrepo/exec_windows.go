@@ -0,0 +1,15 @@
+//go:build windows
+
+package gitrepo
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// createNoWindow keeps git from flashing a console window when run from the GUI.
+const createNoWindow = 0x08000000
+
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+}
@@ -0,0 +1,163 @@
+// Package gitrepo reads the state of a git working tree by running the local git binary:
+// the files touched by the working tree diff, the index or a branch, and the diff itself.
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Selection modes understood by Files and Diff.
+const (
+	// Modified selects files with unstaged changes in the working tree.
+	Modified = "modified"
+	// Staged selects files with changes in the index.
+	Staged = "staged"
+	// Untracked selects files git does not know about, minus the ignored ones.
+	Untracked = "untracked"
+	// Changed selects files that differ from the merge base of a base ref (e.g. origin/main) and HEAD,
+	// including uncommitted changes.
+	Changed = "changed"
+)
+
+// ErrNotRepository is returned by Open when the directory is not inside a git working tree.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Modes returns the selection modes in display order.
+func Modes() []string {
+	return []string{Modified, Staged, Untracked, Changed}
+}
+
+// ValidateMode returns an error if mode is not one of Modes.
+func ValidateMode(mode string) error {
+	for _, m := range Modes() {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown git selection mode %q (expected one of %s)", mode, strings.Join(Modes(), ", "))
+}
+
+// Repo is a git working tree seen from RootDir, which may be a subfolder of the repository.
+// Paths are reported relative to RootDir with forward slashes.
+type Repo struct {
+	rootDir string
+}
+
+// Open checks that rootDir is inside a git working tree.
+func Open(rootDir string) (*Repo, error) {
+	r := &Repo{rootDir: rootDir}
+	out, err := r.run(context.Background(), "rev-parse", "--is-inside-work-tree")
+	if err != nil || strings.TrimSpace(string(out)) != "true" {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("git executable not found: %w", err)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, rootDir)
+	}
+	return r, nil
+}
+
+// RootDir returns the directory the repository was opened at.
+func (r *Repo) RootDir() string {
+	return r.rootDir
+}
+
+// run executes git in RootDir and returns its standard output.
+func (r *Repo) run(ctx context.Context, args ...string) ([]byte, error) {
+	args = append([]string{"-C", r.rootDir, "-c", "core.quotepath=off"}, args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	hideWindow(cmd)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[4], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[4], err)
+	}
+	return out, nil
+}
+
+// Files returns the union of the files selected by modes, sorted, relative to RootDir.
+// base is the ref compared against by Changed and is ignored by the other modes.
+// Deleted files are left out since there is nothing to pack.
+func (r *Repo) Files(ctx context.Context, modes []string, base string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, mode := range modes {
+		if err := ValidateMode(mode); err != nil {
+			return nil, err
+		}
+		var args []string
+		if mode == Untracked {
+			args = []string{"ls-files", "--others", "--exclude-standard", "-z"}
+		} else {
+			diff, err := r.diffArgs(ctx, mode, base)
+			if err != nil {
+				return nil, err
+			}
+			args = append(diff, "--name-only", "--relative", "-z", "--diff-filter=d")
+		}
+		out, err := r.run(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range splitNUL(out) {
+			seen[p] = true
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for p := range seen {
+		// A file can be staged and then removed from the working tree.
+		if _, err := os.Lstat(filepath.Join(r.rootDir, filepath.FromSlash(p))); err != nil {
+			continue
+		}
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// diffArgs returns the git diff invocation that compares what mode selects.
+func (r *Repo) diffArgs(ctx context.Context, mode, base string) ([]string, error) {
+	switch mode {
+	case Modified:
+		return []string{"diff", "--no-renames"}, nil
+	case Staged:
+		return []string{"diff", "--cached", "--no-renames"}, nil
+	case Changed:
+		if base == "" {
+			return nil, errors.New("a base ref is required to select changed files")
+		}
+		out, err := r.run(ctx, "merge-base", base, "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the merge base of %s and HEAD: %w", base, err)
+		}
+		return []string{"diff", "--no-renames", strings.TrimSpace(string(out))}, nil
+	case Untracked:
+		return nil, errors.New("untracked files have no diff")
+	default:
+		return nil, ValidateMode(mode)
+	}
+}
+
+func splitNUL(out []byte) []string {
+	var paths []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
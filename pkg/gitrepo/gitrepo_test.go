@@ -0,0 +1,178 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRepo creates a git repository holding files in a first commit and returns its root.
+func testRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	git(t, root, "init", "-q")
+	writeFiles(t, root, files)
+	commit(t, root, "2024-05-01T12:00:00Z", "Initial commit")
+	return root
+}
+
+// git runs git in dir with a fixed identity and no user or system configuration.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	return gitWithEnv(t, dir, nil, args...)
+}
+
+func gitWithEnv(t *testing.T, dir string, env []string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Ada", "GIT_AUTHOR_EMAIL=ada@example.com",
+		"GIT_COMMITTER_NAME=Ada", "GIT_COMMITTER_EMAIL=ada@example.com",
+	)
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// commit stages everything and commits it at date.
+func commit(t *testing.T, dir, date, subject string) {
+	t.Helper()
+	git(t, dir, "add", "-A")
+	gitWithEnv(t, dir, []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, "commit", "-q", "-m", subject)
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// workingTree sets up a repository with a file in every state Files distinguishes.
+func workingTree(t *testing.T) string {
+	t.Helper()
+	root := testRepo(t, map[string]string{
+		"a.txt":     "a\n",
+		"b.txt":     "b\n",
+		"sub/c.txt": "c\n",
+		"sub/d.txt": "d\n",
+	})
+	writeFiles(t, root, map[string]string{
+		"a.txt":      "a changed\n",
+		"staged.txt": "staged\n",
+		"sub/c.txt":  "c changed\n",
+		"gone.txt":   "staged, then deleted\n",
+	})
+	git(t, root, "add", "staged.txt", "sub/c.txt", "gone.txt")
+	writeFiles(t, root, map[string]string{"new.txt": "untracked\n", "sub/u.txt": "untracked\n"})
+	for _, name := range []string{"b.txt", "gone.txt"} {
+		if err := os.Remove(filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestOpen(t *testing.T) {
+	root := testRepo(t, map[string]string{"a.txt": "a\n"})
+	repo, err := Open(root)
+	if err != nil || repo.RootDir() != root {
+		t.Fatalf("Open = %v, %v", repo, err)
+	}
+	if _, err := Open(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("Open outside a repository = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	root := workingTree(t)
+	repo, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		modes []string
+		want  []string
+	}{
+		// Deleted files (b.txt in the working tree, gone.txt after staging it) are left out.
+		{[]string{Modified}, []string{"a.txt"}},
+		{[]string{Staged}, []string{"staged.txt", "sub/c.txt"}},
+		{[]string{Untracked}, []string{"new.txt", "sub/u.txt"}},
+		{[]string{Modified, Untracked}, []string{"a.txt", "new.txt", "sub/u.txt"}},
+		{[]string{Untracked, Staged, Modified}, []string{"a.txt", "new.txt", "staged.txt", "sub/c.txt", "sub/u.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := repo.Files(context.Background(), tt.modes, "")
+		if err != nil {
+			t.Fatalf("Files(%v): %v", tt.modes, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Files(%v) = %v, want %v", tt.modes, got, tt.want)
+		}
+	}
+
+	if _, err := repo.Files(context.Background(), []string{"dirty"}, ""); err == nil {
+		t.Errorf("an unknown mode was accepted")
+	}
+	if _, err := repo.Files(context.Background(), []string{Changed}, ""); err == nil {
+		t.Errorf("changed files were listed without a base ref")
+	}
+}
+
+func TestFilesFromSubfolder(t *testing.T) {
+	root := workingTree(t)
+	repo, err := Open(filepath.Join(root, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Files(context.Background(), Modes()[:3], "")
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if want := []string{"c.txt", "u.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files = %v, want %v relative to the subfolder", got, want)
+	}
+}
+
+func TestFilesChanged(t *testing.T) {
+	root := testRepo(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	git(t, root, "branch", "base")
+	writeFiles(t, root, map[string]string{"b.txt": "b changed\n"})
+	commit(t, root, "2024-05-02T12:00:00Z", "Change b")
+	writeFiles(t, root, map[string]string{"a.txt": "a changed, not committed\n"})
+
+	repo, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Files(context.Background(), []string{Changed}, "base")
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if want := []string{"a.txt", "b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files = %v, want %v", got, want)
+	}
+	if _, err := repo.Files(context.Background(), []string{Changed}, "no-such-ref"); err == nil {
+		t.Errorf("an unknown base ref was accepted")
+	}
+}
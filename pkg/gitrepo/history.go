@@ -0,0 +1,229 @@
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shotgun_code/pkg/shotgun"
+)
+
+// Defaults of the history and blame sections.
+const (
+	DefaultHistoryCommits = 5
+	DefaultHistoryBytes   = 20_000
+	DefaultBlameBytes     = 30_000
+	// DefaultBlameRegions caps the regions listed per file; the rest are counted in a note.
+	DefaultBlameRegions = 20
+)
+
+// Commit is a commit touching a file, as listed by History.
+type Commit struct {
+	Hash    string // Abbreviated hash
+	Author  string
+	Date    time.Time
+	Subject string
+}
+
+// History returns the last n commits touching path (relative to RootDir), newest first.
+// Untracked files have no history.
+func (r *Repo) History(ctx context.Context, path string, n int) ([]Commit, error) {
+	out, err := r.run(ctx, "log", "-n", strconv.Itoa(n), "--no-merges", "--format=%h%x1f%an%x1f%at%x1f%s", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, Commit{Hash: fields[0], Author: fields[1], Date: unixDate(fields[2]), Subject: fields[3]})
+	}
+	return commits, nil
+}
+
+// BlameRegion is a run of consecutive lines last changed by the same commit.
+type BlameRegion struct {
+	Start, End int // 1-based, inclusive
+	Commit     Commit
+	// Uncommitted marks lines changed in the working tree or the index.
+	Uncommitted bool
+}
+
+// Blame returns the regions of path (relative to RootDir) in line order, including uncommitted
+// changes.
+func (r *Repo) Blame(ctx context.Context, path string) ([]BlameRegion, error) {
+	out, err := r.run(ctx, "blame", "--porcelain", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out), nil
+}
+
+// parseBlame reads `git blame --porcelain`: a "<sha> <orig> <final> [<count>]" header per line,
+// the commit details the first time a commit appears, then the line itself prefixed by a tab.
+func parseBlame(out []byte) []BlameRegion {
+	commits := make(map[string]*Commit)
+	var regions []BlameRegion
+	var shas []string // Full hash of every region
+	var sha string
+	var line int
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for expectHeader := true; scanner.Scan(); {
+		text := scanner.Text()
+		if expectHeader {
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				continue
+			}
+			sha = fields[0]
+			line, _ = strconv.Atoi(fields[2])
+			if commits[sha] == nil {
+				commits[sha] = &Commit{Hash: sha[:min(len(sha), 7)]}
+			}
+			expectHeader = false
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			if n := len(regions); n > 0 && shas[n-1] == sha && regions[n-1].End == line-1 {
+				regions[n-1].End = line
+			} else {
+				regions = append(regions, BlameRegion{Start: line, End: line, Uncommitted: strings.Trim(sha, "0") == ""})
+				shas = append(shas, sha)
+			}
+			expectHeader = true
+			continue
+		}
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			commits[sha].Author = value
+		case "author-time":
+			commits[sha].Date = unixDate(value)
+		case "summary":
+			commits[sha].Subject = value
+		}
+	}
+
+	// Details follow the first header of a commit only, so fill them in once everything is read.
+	for i := range regions {
+		regions[i].Commit = *commits[shas[i]]
+	}
+	return regions
+}
+
+func unixDate(value string) time.Time {
+	sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// HistorySection returns a payload section with the last perFile commit subjects of every packed
+// file, within maxBytes. Zero values mean DefaultHistoryCommits and DefaultHistoryBytes.
+func HistorySection(perFile, maxBytes int) shotgun.SectionFunc {
+	if perFile <= 0 {
+		perFile = DefaultHistoryCommits
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultHistoryBytes
+	}
+	return func(ctx context.Context, rootDir string, files []string) (shotgun.Section, error) {
+		repo, err := Open(rootDir)
+		if err != nil {
+			return shotgun.Section{}, err
+		}
+		w := &budgetWriter{maxBytes: maxBytes}
+		for _, path := range files {
+			if w.full() {
+				w.skipped = append(w.skipped, path)
+				continue
+			}
+			commits, err := repo.History(ctx, path, perFile)
+			if err != nil {
+				if ctx.Err() != nil {
+					return shotgun.Section{}, ctx.Err()
+				}
+				continue // Not readable by git, e.g. a followed link outside the repository
+			}
+			if len(commits) == 0 {
+				continue
+			}
+			var sb strings.Builder
+			sb.WriteString(path + "\n")
+			for _, c := range commits {
+				sb.WriteString(fmt.Sprintf("  %s %s %s: %s\n", c.Hash, c.Date.Format("2006-01-02"), c.Author, c.Subject))
+			}
+			w.add(path, sb.String())
+		}
+		return shotgun.Section{
+			Name:    "git-history",
+			Content: w.String(),
+			Attrs:   []shotgun.Attr{{Name: "commits-per-file", Value: strconv.Itoa(perFile)}},
+		}, nil
+	}
+}
+
+// BlameSection returns a payload section summarizing who last changed each region of every
+// packed file and when, within maxBytes. Zero means DefaultBlameBytes.
+func BlameSection(maxBytes int) shotgun.SectionFunc {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBlameBytes
+	}
+	return func(ctx context.Context, rootDir string, files []string) (shotgun.Section, error) {
+		repo, err := Open(rootDir)
+		if err != nil {
+			return shotgun.Section{}, err
+		}
+		w := &budgetWriter{maxBytes: maxBytes}
+		for _, path := range files {
+			if w.full() {
+				w.skipped = append(w.skipped, path)
+				continue
+			}
+			regions, err := repo.Blame(ctx, path)
+			if err != nil {
+				if ctx.Err() != nil {
+					return shotgun.Section{}, ctx.Err()
+				}
+				continue // Untracked or not readable by git
+			}
+			if len(regions) == 0 {
+				continue
+			}
+			w.add(path, formatBlame(path, regions, DefaultBlameRegions))
+		}
+		return shotgun.Section{Name: "git-blame", Content: w.String()}, nil
+	}
+}
+
+// formatBlame renders one file of the blame section, listing at most maxRegions regions.
+func formatBlame(path string, regions []BlameRegion, maxRegions int) string {
+	var sb strings.Builder
+	sb.WriteString(path + "\n")
+	for i, region := range regions {
+		if i == maxRegions {
+			sb.WriteString(fmt.Sprintf("  ... %d more regions up to line %d\n", len(regions)-i, regions[len(regions)-1].End))
+			break
+		}
+		lines := strconv.Itoa(region.Start)
+		if region.End != region.Start {
+			lines += "-" + strconv.Itoa(region.End)
+		}
+		if region.Uncommitted {
+			sb.WriteString(fmt.Sprintf("  %s not committed yet\n", lines))
+			continue
+		}
+		c := region.Commit
+		sb.WriteString(fmt.Sprintf("  %s %s %s %s: %s\n", lines, c.Hash, c.Date.Format("2006-01-02"), c.Author, c.Subject))
+	}
+	return sb.String()
+}
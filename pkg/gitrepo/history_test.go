@@ -0,0 +1,170 @@
+package gitrepo
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyRepo commits a.txt twice and b.txt once; a.txt then has an uncommitted line.
+func historyRepo(t *testing.T) string {
+	t.Helper()
+	root := testRepo(t, map[string]string{"a.txt": "one\ntwo\nthree\n", "b.txt": "b\n"})
+	writeFiles(t, root, map[string]string{"a.txt": "one\ntwo changed\nthree\n"})
+	commit(t, root, "2024-05-02T12:00:00Z", "Change the second line")
+	writeFiles(t, root, map[string]string{"a.txt": "one\ntwo changed\nthree\nfour\n", "new.txt": "untracked\n"})
+	return root
+}
+
+func TestHistory(t *testing.T) {
+	repo, err := Open(historyRepo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, err := repo.History(context.Background(), "a.txt", 5)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("commits = %+v, want two", commits)
+	}
+	want := Commit{Hash: commits[0].Hash, Author: "Ada", Date: time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC), Subject: "Change the second line"}
+	if commits[0] != want || len(commits[0].Hash) < 7 {
+		t.Errorf("newest commit = %+v, want %+v", commits[0], want)
+	}
+	if commits[1].Subject != "Initial commit" {
+		t.Errorf("oldest commit = %+v", commits[1])
+	}
+
+	if commits, _ := repo.History(context.Background(), "a.txt", 1); len(commits) != 1 {
+		t.Errorf("History(1) = %+v, want one commit", commits)
+	}
+	if commits, err := repo.History(context.Background(), "new.txt", 5); err != nil || len(commits) != 0 {
+		t.Errorf("History of an untracked file = %+v, %v; want none", commits, err)
+	}
+}
+
+func TestBlame(t *testing.T) {
+	repo, err := Open(historyRepo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := repo.Blame(context.Background(), "a.txt")
+	if err != nil {
+		t.Fatalf("Blame: %v", err)
+	}
+	type span struct {
+		start, end  int
+		subject     string
+		uncommitted bool
+	}
+	var got []span
+	for _, r := range regions {
+		subject := r.Commit.Subject
+		if r.Uncommitted {
+			subject = "" // Named by git after the file
+		}
+		got = append(got, span{r.Start, r.End, subject, r.Uncommitted})
+	}
+	want := []span{
+		{1, 1, "Initial commit", false},
+		{2, 2, "Change the second line", false},
+		{3, 3, "Initial commit", false},
+		{4, 4, "", true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("regions = %+v, want %+v", got, want)
+	}
+	if _, err := repo.Blame(context.Background(), "new.txt"); err == nil {
+		t.Errorf("an untracked file was blamed")
+	}
+}
+
+func TestParseBlame(t *testing.T) {
+	const full = "1234567890abcdef1234567890abcdef12345678"
+	porcelain := strings.Join([]string{
+		full + " 1 1 2",
+		"author Ada",
+		"author-time 1714564800",
+		"summary Add the file",
+		"\tline one",
+		full + " 2 2",
+		"\tline two",
+		"0000000000000000000000000000000000000000 3 3 1",
+		"author Not Committed Yet",
+		"summary Version of a.txt from a.txt",
+		"\tline three",
+		full + " 3 4 1",
+		"\tline four",
+	}, "\n")
+
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := Commit{Hash: "1234567", Author: "Ada", Date: date, Subject: "Add the file"}
+	want := []BlameRegion{
+		{Start: 1, End: 2, Commit: c},
+		{Start: 3, End: 3, Commit: Commit{Hash: "0000000", Author: "Not Committed Yet", Subject: "Version of a.txt from a.txt"}, Uncommitted: true},
+		{Start: 4, End: 4, Commit: c},
+	}
+	if got := parseBlame([]byte(porcelain)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBlame =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFormatBlame(t *testing.T) {
+	c := Commit{Hash: "1234567", Author: "Ada", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Subject: "Add"}
+	regions := []BlameRegion{
+		{Start: 1, End: 3, Commit: c},
+		{Start: 4, End: 4, Uncommitted: true},
+		{Start: 5, End: 9, Commit: c},
+	}
+	want := "a.go\n" +
+		"  1-3 1234567 2024-05-01 Ada: Add\n" +
+		"  4 not committed yet\n" +
+		"  ... 1 more regions up to line 9\n"
+	if got := formatBlame("a.go", regions, 2); got != want {
+		t.Errorf("formatBlame =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistorySection(t *testing.T) {
+	root := historyRepo(t)
+	ctx := context.Background()
+
+	section, err := HistorySection(1, 0)(ctx, root, []string{"a.txt", "new.txt", "b.txt"})
+	if err != nil {
+		t.Fatalf("HistorySection: %v", err)
+	}
+	lines := strings.Split(section.Content, "\n")
+	if section.Name != "git-history" || len(lines) != 4 || lines[0] != "a.txt" || lines[2] != "b.txt" {
+		t.Fatalf("section = %+v", section)
+	}
+	if !strings.HasSuffix(lines[1], " 2024-05-02 Ada: Change the second line") || !strings.HasSuffix(lines[3], " 2024-05-01 Ada: Initial commit") {
+		t.Errorf("history lines = %q", lines)
+	}
+
+	// Once the budget is used up, the remaining files are named in the note.
+	section, err = HistorySection(1, 10)(ctx, root, []string{"a.txt", "b.txt"})
+	if err != nil {
+		t.Fatalf("HistorySection: %v", err)
+	}
+	if section.Content != "# Budget of 10 bytes reached, left out: a.txt, b.txt" {
+		t.Errorf("section over budget = %q", section.Content)
+	}
+}
+
+func TestBlameSection(t *testing.T) {
+	root := historyRepo(t)
+	section, err := BlameSection(0)(context.Background(), root, []string{"new.txt", "b.txt"})
+	if err != nil {
+		t.Fatalf("BlameSection: %v", err)
+	}
+	// The untracked file has no blame and is left out.
+	if section.Name != "git-blame" || !strings.HasPrefix(section.Content, "b.txt\n  1 ") || strings.Contains(section.Content, "new.txt") {
+		t.Errorf("section = %+v", section)
+	}
+	if _, err := BlameSection(0)(context.Background(), t.TempDir(), nil); err == nil {
+		t.Errorf("a blame section was produced outside a repository")
+	}
+}
@@ -0,0 +1,221 @@
+package shotgun
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Source encodings reported by sniffContent. UTF-8 is reported as an empty string.
+const (
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingLatin1  = "latin-1"
+)
+
+// sniffLimit bounds how much of a file is scanned for NUL bytes.
+const sniffLimit = 8 * 1024
+
+// binaryMagics are file signatures of common binary formats. Most of them would also be caught
+// by the NUL byte check, but several (some archives, media containers) can start with a long
+// NUL-free prefix.
+var binaryMagics = [][]byte{
+	[]byte("\x89PNG\r\n\x1a\n"),
+	[]byte("\xff\xd8\xff"), // JPEG
+	[]byte("GIF87a"),       // GIF
+	[]byte("GIF89a"),       // GIF
+	[]byte("PK\x03\x04"),   // ZIP, JAR, DOCX, ...
+	[]byte("\x1f\x8b"),     // gzip
+	[]byte("\xfd7zXZ\x00"), // xz
+	[]byte("7z\xbc\xaf\x27\x1c"),
+	[]byte("Rar!\x1a\x07"),     // RAR
+	[]byte("\x7fELF"),          // ELF
+	[]byte("\xfe\xed\xfa\xce"), // Mach-O 32
+	[]byte("\xfe\xed\xfa\xcf"), // Mach-O 64
+	[]byte("\xce\xfa\xed\xfe"), // Mach-O 32 (reverse)
+	[]byte("\xcf\xfa\xed\xfe"), // Mach-O 64 (reverse)
+	[]byte("\xca\xfe\xba\xbe"), // Java class / Mach-O fat
+	[]byte("\x00asm"),          // WebAssembly
+	[]byte("SQLite format 3\x00"),
+}
+
+// containerMagics are signatures that begin with printable text, which a text file may start
+// with as well ("RIFF" in a note, "ID3 tags" in a README, "%PDF-" in notes on the format).
+// They only count together with the bytes that follow them in the real format.
+var containerMagics = []struct {
+	magic []byte
+	// matches checks the bytes after the magic.
+	matches func(rest []byte) bool
+}{
+	{[]byte("RIFF"), func(rest []byte) bool { // RIFF, 4-byte size, form type
+		return len(rest) >= 8 && hasAnyPrefix(rest[4:], "WAVE", "AVI ", "WEBP", "RMID", "ACON")
+	}},
+	{[]byte("OggS"), func(rest []byte) bool { return len(rest) > 0 && rest[0] == 0 }},      // Version 0
+	{[]byte("fLaC"), func(rest []byte) bool { return len(rest) > 0 && rest[0]&0x7f == 0 }}, // STREAMINFO block
+	{[]byte("ID3"), func(rest []byte) bool { // ID3v2 tag of an MP3: version 2-4, revision
+		return len(rest) >= 2 && rest[0] >= 2 && rest[0] <= 4 && rest[1] < 0xff
+	}},
+	{[]byte("wOFF"), isFontFlavor}, // WOFF
+	{[]byte("wOF2"), isFontFlavor}, // WOFF2
+	{[]byte("%PDF-"), isPDFBody},
+}
+
+// isPDFBody reports whether the bytes after "%PDF-" belong to a PDF document: one that ends with
+// the %%EOF trailer or holds binary bytes, which real PDFs put in the comment on their second
+// line and in their streams.
+func isPDFBody(rest []byte) bool {
+	if bytes.HasSuffix(bytes.TrimRight(rest, " \t\r\n\x00"), []byte("%%EOF")) {
+		return true
+	}
+	head := rest
+	if len(head) > sniffLimit {
+		head = head[:sniffLimit]
+	}
+	for i := 0; i < len(head); {
+		if !utf8.FullRune(head[i:]) {
+			break // Cut by the sniff limit
+		}
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size == 1 {
+			return true // Not UTF-8
+		}
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f') || r == 0x7f {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// isFontFlavor reports whether rest starts with the sfnt version of a TrueType or OpenType font.
+func isFontFlavor(rest []byte) bool {
+	return hasAnyPrefix(rest, "\x00\x01\x00\x00", "OTTO", "true")
+}
+
+func hasAnyPrefix(data []byte, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(data, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBinarySignature reports whether data starts with the signature of a known binary format.
+func hasBinarySignature(data []byte) bool {
+	for _, magic := range binaryMagics {
+		if bytes.HasPrefix(data, magic) {
+			return true
+		}
+	}
+	for _, container := range containerMagics {
+		if bytes.HasPrefix(data, container.magic) && container.matches(data[len(container.magic):]) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// sniffContent classifies data. binary is true for NUL bytes or a known binary signature;
+// otherwise encoding names the source encoding ("" for UTF-8).
+func sniffContent(data []byte) (binary bool, encoding string) {
+	// UTF-16 text is full of NUL bytes, so it is checked for NUL code units instead. A file
+	// starting with a BOM but of odd length is not UTF-16 either.
+	for _, bom := range []struct {
+		bom      []byte
+		encoding string
+	}{{bomUTF16LE, EncodingUTF16LE}, {bomUTF16BE, EncodingUTF16BE}} {
+		if bytes.HasPrefix(data, bom.bom) {
+			if len(data)%2 != 0 || hasNULCodeUnit(data[2:]) {
+				return true, ""
+			}
+			return false, bom.encoding
+		}
+	}
+	if hasBinarySignature(data) {
+		return true, ""
+	}
+	head := data
+	if len(head) > sniffLimit {
+		head = head[:sniffLimit]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true, ""
+	}
+	if !utf8.Valid(data) {
+		return false, EncodingLatin1
+	}
+	return false, ""
+}
+
+// hasNULCodeUnit reports whether the UTF-16 text in data holds a U+0000 code unit within
+// sniffLimit bytes.
+func hasNULCodeUnit(data []byte) bool {
+	for i := 0; i+1 < min(len(data), sniffLimit); i += 2 {
+		if data[i] == 0 && data[i+1] == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeToUTF8 converts text in the given source encoding to UTF-8, dropping any BOM.
+func decodeToUTF8(data []byte, encoding string) []byte {
+	switch encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		data = data[2:] // BOM
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if encoding == EncodingUTF16LE {
+				units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+			} else {
+				units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+			}
+		}
+		text := []byte(string(utf16.Decode(units)))
+		if len(data)%2 != 0 {
+			// sniffContent flags such files as binary; should one get here, its last byte is
+			// kept as a replacement character rather than dropped.
+			text = utf8.AppendRune(text, utf8.RuneError)
+		}
+		return text
+	case EncodingLatin1:
+		// Every Latin-1 byte maps to the Unicode code point of the same value.
+		var buf bytes.Buffer
+		buf.Grow(len(data) + len(data)/8)
+		for _, b := range data {
+			buf.WriteRune(rune(b))
+		}
+		return buf.Bytes()
+	default:
+		return bytes.TrimPrefix(data, bomUTF8)
+	}
+}
+
+// binaryView is the placeholder rendered instead of the raw bytes of a binary file.
+func binaryView(relPath string, size int) FileView {
+	return FileView{
+		Path:    filepath.ToSlash(relPath),
+		Content: "[binary content omitted]",
+		Omitted: true,
+		Attrs:   []Attr{{"binary", "true"}, {"size", strconv.Itoa(size)}},
+	}
+}
+
+// undecodedView is the placeholder rendered for non-UTF-8 text when re-encoding is disabled.
+func undecodedView(relPath string, size int, encoding string) FileView {
+	return FileView{
+		Path:    filepath.ToSlash(relPath),
+		Content: "[non-UTF-8 content omitted]",
+		Omitted: true,
+		Attrs:   []Attr{{"encoding", encoding}, {"size", strconv.Itoa(size)}},
+	}
+}
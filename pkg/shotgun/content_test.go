@@ -0,0 +1,79 @@
+package shotgun
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniffContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		binary   bool
+		encoding string
+	}{
+		{"empty", "", false, ""},
+		{"utf-8 text", "héllo\n", false, ""},
+		{"utf-8 BOM", "\xef\xbb\xbfhello", false, ""},
+		{"utf-16le BOM", "\xff\xfeh\x00i\x00", false, EncodingUTF16LE},
+		{"utf-16be BOM", "\xfe\xff\x00h\x00i", false, EncodingUTF16BE},
+		{"utf-16le BOM, odd length", "\xff\xfeh\x00i\x00!", true, ""},
+		{"utf-16be BOM, odd length", "\xfe\xff\x00h\x00", true, ""},
+		{"utf-16 BOM before NUL code units", "\xff\xfeh\x00\x00\x00\x01\x02", true, ""},
+		{"utf-16 BOM only", "\xff\xfe", false, EncodingUTF16LE},
+		{"latin-1", "caf\xe9\n", false, EncodingLatin1},
+		{"NUL byte", "abc\x00def", true, ""},
+		{"NUL past the sniff limit", strings.Repeat("a", sniffLimit) + "\x00", false, ""},
+		{"PNG", "\x89PNG\r\n\x1a\n....", true, ""},
+		{"PDF", "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n", true, ""},
+		{"PDF stream", "%PDF-1.4\n1 0 obj\nstream\nx\x9c\x03\x00endstream\n", true, ""},
+		{"ASCII PDF with trailer", "%PDF-1.1\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n%%EOF\r\n", true, ""},
+		{"gzip", "\x1f\x8b\x08", true, ""},
+
+		// Media containers are recognised by their whole signature...
+		{"WAV", "RIFF\x24\x08\x01\x01WAVEfmt ", true, ""},
+		{"WebP", "RIFF\x1a\x1a\x1a\x1aWEBPVP8 ", true, ""},
+		{"AVI", "RIFF\x7f\x7f\x7f\x7fAVI LIST", true, ""},
+		{"MP3 with ID3v2 tag", "ID3\x04\x00\x7f\x7f\x7f\x7f", true, ""},
+		{"Ogg", "OggS\x00\x02", true, ""},
+		{"FLAC", "fLaC\x80\x22", true, ""},
+		{"WOFF", "wOFFOTTO....", true, ""},
+		{"WOFF2", "wOF2\x00\x01\x00\x00", true, ""},
+
+		// ...so that text starting with the same letters stays text.
+		{"RIFF in prose", "RIFF stands for Resource Interchange File Format.\n", false, ""},
+		{"RIFF with other form type", "RIFF1234NOTE: text\n", false, ""},
+		{"ID3 in prose", "ID3 tags store the title of a song.\n", false, ""},
+		{"ID3 heading", "ID3v2 parser\n============\n", false, ""},
+		{"OggS in prose", "OggS pages carry Vorbis audio.\n", false, ""},
+		{"fLaC in prose", "fLaC is the FLAC magic.\n", false, ""},
+		{"wOFF in prose", "wOFF fonts are compressed.\n", false, ""},
+		{"short RIFF", "RIFF", false, ""},
+		{"PDF magic in notes", "%PDF-1.7 is the version header; see the spec.\n", false, ""},
+		{"PDF magic with accents", "%PDF-x notes: café, naïve\n", false, ""},
+		{"bare PDF magic", "%PDF-", false, ""},
+		{"PDF magic, rune cut by the sniff limit", "%PDF-" + strings.Repeat("a", sniffLimit-1) + "é", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binary, encoding := sniffContent([]byte(tt.data))
+			if binary != tt.binary || encoding != tt.encoding {
+				t.Errorf("sniffContent(%q) = %v, %q, want %v, %q", tt.data, binary, encoding, tt.binary, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestDecodeToUTF8(t *testing.T) {
+	for _, tt := range []struct{ data, encoding, want string }{
+		{"\xef\xbb\xbfhello", "", "hello"},
+		{"\xff\xfeh\x00\xe9\x00", EncodingUTF16LE, "hé"},
+		{"\xfe\xff\x00h\x00\xe9", EncodingUTF16BE, "hé"},
+		{"caf\xe9", EncodingLatin1, "café"},
+		{"\xff\xfeh\x00!", EncodingUTF16LE, "h\uFFFD"},
+	} {
+		if got := string(decodeToUTF8([]byte(tt.data), tt.encoding)); got != tt.want {
+			t.Errorf("decodeToUTF8(%q, %q) = %q, want %q", tt.data, tt.encoding, got, tt.want)
+		}
+	}
+}
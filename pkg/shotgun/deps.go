@@ -0,0 +1,272 @@
+package shotgun
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultDependencyDepth is the expansion depth used when ExpandDependencies gets zero.
+const DefaultDependencyDepth = 1
+
+// MaxDependencyDepth bounds the expansion depth.
+const MaxDependencyDepth = 10
+
+// Dependency is a file added to a selection by ExpandDependencies.
+type Dependency struct {
+	Path   string `json:"path"`   // Relative path with forward slashes
+	From   string `json:"from"`   // Selected or added file whose import pulled Path in
+	Import string `json:"import"` // Import path or specifier as written in From
+	Depth  int    `json:"depth"`  // 1 for direct imports of the selection
+	Reason string `json:"reason"` // Human-readable summary of the above
+}
+
+// jsExtensions are the sources whose import statements are followed, in resolution order.
+var jsExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".vue"}
+
+var (
+	// import x from "y", import "y", export * from "y", including multi-line import lists.
+	jsImportPattern = regexp.MustCompile(`(?m)(?:^|[^\w$.])(?:import|export)\s+(?:[^'";]*?\s+from\s+)?['"]([^'"\n]+)['"]`)
+	// require("y") and dynamic import("y").
+	jsRequirePattern = regexp.MustCompile(`(?:^|[^\w$.])(?:require|import)\s*\(\s*['"]([^'"\n]+)['"]\s*\)`)
+)
+
+// ExpandDependencies follows the imports of the selected files (relative paths, either
+// separator) up to depth levels and returns the files they pull in, nearest first. Go imports
+// are resolved through the nearest go.mod to every non-test file of the imported package;
+// relative JS/TS/Vue imports to the file they name. Files outside rootDir, files matched by m
+// and files already selected are never added.
+func ExpandDependencies(ctx context.Context, rootDir string, selection []string, depth int, m Matcher) ([]Dependency, error) {
+	if depth <= 0 {
+		depth = DefaultDependencyDepth
+	}
+	if depth > MaxDependencyDepth {
+		return nil, fmt.Errorf("dependency depth %d is above the maximum of %d", depth, MaxDependencyDepth)
+	}
+
+	resolver := &depResolver{rootDir: rootDir, ignore: m, modules: make(map[string]goModule)}
+	seen := make(map[string]bool)
+	var frontier []string
+	for _, p := range selection {
+		rel := filepath.ToSlash(filepath.Clean(filepath.FromSlash(strings.TrimSpace(p))))
+		if rel == "." || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		if info, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(rel))); err == nil && !info.IsDir() {
+			frontier = append(frontier, rel)
+		}
+	}
+
+	var deps []Dependency
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, from := range frontier {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for _, imp := range resolver.imports(from) {
+				for _, target := range imp.targets {
+					if seen[target] {
+						continue
+					}
+					seen[target] = true
+					deps = append(deps, Dependency{
+						Path:   target,
+						From:   from,
+						Import: imp.spec,
+						Depth:  level,
+						Reason: fmt.Sprintf("imported by %s as %q", from, imp.spec),
+					})
+					next = append(next, target)
+				}
+			}
+		}
+		frontier = next
+	}
+	return deps, nil
+}
+
+// resolvedImport is an import statement and the project files it stands for.
+type resolvedImport struct {
+	spec    string
+	targets []string // Relative paths with forward slashes
+}
+
+// goModule is the module a folder belongs to; path is empty outside any module.
+type goModule struct {
+	dir, path string
+}
+
+// depResolver finds the project files imported by a file. Module lookups are cached per folder.
+type depResolver struct {
+	rootDir string
+	ignore  Matcher
+	modules map[string]goModule
+}
+
+func (r *depResolver) imports(rel string) []resolvedImport {
+	abs := filepath.Join(r.rootDir, filepath.FromSlash(rel))
+	ext := strings.ToLower(filepath.Ext(rel))
+	switch {
+	case ext == ".go":
+		return r.goImports(abs)
+	case slices.Contains(jsExtensions, ext), ext == ".mts", ext == ".cts":
+		return r.jsImports(abs)
+	default:
+		return nil
+	}
+}
+
+func (r *depResolver) goImports(abs string) []resolvedImport {
+	file, err := parser.ParseFile(token.NewFileSet(), abs, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	mod := r.module(filepath.Dir(abs))
+	if mod.path == "" {
+		return nil
+	}
+	var result []resolvedImport
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var pkgDir string
+		switch {
+		case importPath == mod.path:
+			pkgDir = mod.dir
+		case strings.HasPrefix(importPath, mod.path+"/"):
+			pkgDir = filepath.Join(mod.dir, filepath.FromSlash(strings.TrimPrefix(importPath, mod.path+"/")))
+		default:
+			continue // Standard library or another module
+		}
+		entries, err := os.ReadDir(pkgDir)
+		if err != nil {
+			continue
+		}
+		imp := resolvedImport{spec: importPath}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			if rel, ok := r.projectFile(filepath.Join(pkgDir, name)); ok {
+				imp.targets = append(imp.targets, rel)
+			}
+		}
+		if len(imp.targets) > 0 {
+			result = append(result, imp)
+		}
+	}
+	return result
+}
+
+// module returns the module of dir from the nearest go.mod at or above it, which may lie above
+// the project root.
+func (r *depResolver) module(dir string) goModule {
+	if mod, ok := r.modules[dir]; ok {
+		return mod
+	}
+	var mod goModule
+	if path := readModulePath(filepath.Join(dir, "go.mod")); path != "" {
+		mod = goModule{dir: dir, path: path}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = r.module(parent)
+	}
+	r.modules[dir] = mod
+	return mod
+}
+
+// readModulePath returns the module path declared by a go.mod file, or "" if there is none.
+func readModulePath(goMod string) string {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if rest, ok := strings.CutPrefix(line, "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			path := strings.TrimSpace(rest)
+			if unquoted, err := strconv.Unquote(path); err == nil {
+				path = unquoted
+			}
+			return path
+		}
+	}
+	return ""
+}
+
+func (r *depResolver) jsImports(abs string) []resolvedImport {
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil
+	}
+	var specs []string
+	for _, pattern := range []*regexp.Regexp{jsImportPattern, jsRequirePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(string(content), -1) {
+			specs = append(specs, match[1])
+		}
+	}
+	dir := filepath.Dir(abs)
+	var result []resolvedImport
+	done := make(map[string]bool)
+	for _, spec := range specs {
+		if done[spec] || !(strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../")) {
+			continue // Packages and bundler aliases cannot be resolved without their config
+		}
+		done[spec] = true
+		if target, ok := r.resolveJS(filepath.Join(dir, filepath.FromSlash(spec))); ok {
+			result = append(result, resolvedImport{spec: spec, targets: []string{target}})
+		}
+	}
+	return result
+}
+
+// resolveJS maps an import specifier to a file the way bundlers do: the exact file, the file
+// with a source extension, or an index file of the folder. TypeScript's "./x.js" may name x.ts.
+func (r *depResolver) resolveJS(base string) (string, bool) {
+	candidates := []string{base}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	if stem, ok := strings.CutSuffix(base, ".js"); ok {
+		candidates = append(candidates, stem+".ts", stem+".tsx")
+	}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return r.projectFile(candidate)
+		}
+	}
+	return "", false
+}
+
+// projectFile converts abs to a slash path relative to the project root, rejecting files
+// outside it and files matched by the ignore rules.
+func (r *depResolver) projectFile(abs string) (string, bool) {
+	rel, err := filepath.Rel(r.rootDir, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	if r.ignore.Ignored(rel, false) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,169 @@
+package shotgun
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// depsProject is a Go module with a web frontend:
+//
+//	cmd/app -> internal/store -> internal/model
+//	web/main.ts -> web/api (index.ts) -> web/util.ts
+func depsProject(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"go.mod":                       "// The module of the tests\nmodule example.com/app // trailing comment\n\ngo 1.24\n",
+		"cmd/app/main.go":              "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/internal/store\"\n\t\"github.com/other/module\"\n)\n",
+		"internal/store/store.go":      "package store\n\nimport \"example.com/app/internal/model\"\n",
+		"internal/store/cache.go":      "package store\n",
+		"internal/store/store_test.go": "package store\n",
+		"internal/model/model.go":      "package model\n",
+		"internal/model/generated.go":  "package model\n",
+		"web/main.ts":                  "import { get } from './api';\nimport {\n  a,\n  b,\n} from \"./util.js\";\nimport vue from 'vue';\nconst lazy = import('./lazy');\nconst old = require(\"../web/legacy\");\n",
+		"web/api/index.ts":             "export * from '../util';\n",
+		"web/util.ts":                  "export const a = 1;\n",
+		"web/lazy.tsx":                 "export default 1;\n",
+		"web/legacy.js":                "module.exports = {};\n",
+	})
+	return root
+}
+
+func TestExpandDependencies(t *testing.T) {
+	root := depsProject(t)
+	tests := []struct {
+		name      string
+		selection []string
+		depth     int
+		ignore    Matcher
+		want      []string // path@depth
+	}{
+		{
+			"go, one level",
+			[]string{"cmd/app/main.go"}, 1, Matcher{},
+			[]string{"internal/store/cache.go@1", "internal/store/store.go@1"},
+		},
+		{
+			"go, two levels",
+			[]string{"cmd/app/main.go"}, 2, Matcher{},
+			[]string{"internal/store/cache.go@1", "internal/store/store.go@1", "internal/model/generated.go@2", "internal/model/model.go@2"},
+		},
+		{
+			"go, ignored files are not added",
+			[]string{"cmd/app/main.go"}, 2, Matcher{Custom: CompileRules("generated.go")},
+			[]string{"internal/store/cache.go@1", "internal/store/store.go@1", "internal/model/model.go@2"},
+		},
+		{
+			"already selected files are not added",
+			[]string{"cmd/app/main.go", "internal/store/store.go"}, 1, Matcher{},
+			[]string{"internal/store/cache.go@1", "internal/model/generated.go@1", "internal/model/model.go@1"},
+		},
+		{
+			"js, one level",
+			[]string{"web/main.ts"}, 1, Matcher{},
+			[]string{"web/api/index.ts@1", "web/util.ts@1", "web/lazy.tsx@1", "web/legacy.js@1"},
+		},
+		{
+			"js, nothing new at the second level",
+			[]string{"web/main.ts"}, 3, Matcher{},
+			[]string{"web/api/index.ts@1", "web/util.ts@1", "web/lazy.tsx@1", "web/legacy.js@1"},
+		},
+		{
+			"unclean paths and missing files",
+			[]string{"./web//api/index.ts", "web/missing.ts"}, 0, Matcher{},
+			[]string{"web/util.ts@1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := ExpandDependencies(context.Background(), root, tt.selection, tt.depth, tt.ignore)
+			if err != nil {
+				t.Fatalf("ExpandDependencies: %v", err)
+			}
+			var got []string
+			for _, d := range deps {
+				got = append(got, fmt.Sprintf("%s@%d", d.Path, d.Depth))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependencies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandDependenciesReason(t *testing.T) {
+	root := depsProject(t)
+	deps, err := ExpandDependencies(context.Background(), root, []string{"web/main.ts"}, 1, Matcher{})
+	if err != nil || len(deps) == 0 {
+		t.Fatalf("ExpandDependencies = %v, %v", deps, err)
+	}
+	want := Dependency{Path: "web/api/index.ts", From: "web/main.ts", Import: "./api", Depth: 1, Reason: `imported by web/main.ts as "./api"`}
+	if deps[0] != want {
+		t.Errorf("first dependency = %+v, want %+v", deps[0], want)
+	}
+}
+
+func TestExpandDependenciesDepthLimit(t *testing.T) {
+	root := depsProject(t)
+	if _, err := ExpandDependencies(context.Background(), root, []string{"cmd/app/main.go"}, MaxDependencyDepth+1, Matcher{}); err == nil || !strings.Contains(err.Error(), "maximum") {
+		t.Errorf("err = %v, want the depth rejected", err)
+	}
+	deps, err := ExpandDependencies(context.Background(), root, []string{"cmd/app/main.go"}, MaxDependencyDepth, Matcher{})
+	if err != nil || len(deps) != 4 {
+		t.Errorf("at the maximum depth: %v, %v", deps, err)
+	}
+}
+
+func TestExpandDependenciesStaysInProject(t *testing.T) {
+	// The module root lies above the project, which is one of its folders.
+	module := filepath.Join(t.TempDir(), "module")
+	writeFiles(t, module, map[string]string{
+		"go.mod":          "module example.com/mod\n",
+		"lib/lib.go":      "package lib\n",
+		"app/main.go":     "package main\n\nimport (\n\t\"example.com/mod/lib\"\n\t\"example.com/mod/app/sub\"\n)\n",
+		"app/sub/sub.go":  "package sub\n",
+		"app/web/main.ts": "import '../../lib/outside.js';\n",
+		"lib/outside.js":  "",
+	})
+	deps, err := ExpandDependencies(context.Background(), filepath.Join(module, "app"), []string{"main.go", "web/main.ts"}, 1, Matcher{})
+	if err != nil {
+		t.Fatalf("ExpandDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].Path != "sub/sub.go" {
+		t.Errorf("dependencies = %+v, want sub/sub.go only", deps)
+	}
+}
+
+func TestExpandDependenciesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExpandDependencies(ctx, depsProject(t), []string{"cmd/app/main.go"}, 1, Matcher{}); err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestReadModulePath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content, want string
+	}{
+		{"module example.com/a\n", "example.com/a"},
+		{"// comment\nmodule \"example.com/quoted\" // note\n", "example.com/quoted"},
+		{"modulepath example.com/x\n", ""},
+		{"go 1.24\n", ""},
+	}
+	for i, tt := range tests {
+		name := fmt.Sprintf("%d.mod", i)
+		writeFiles(t, dir, map[string]string{name: tt.content})
+		if got := readModulePath(filepath.Join(dir, name)); got != tt.want {
+			t.Errorf("readModulePath(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+	if got := readModulePath(filepath.Join(dir, "missing.mod")); got != "" {
+		t.Errorf("readModulePath of a missing file = %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package shotgun
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// File contents are written raw between <file path="..."> and </file>. A file that itself contains
+// "</file>" or "<file path=" would break those boundaries, so its block gets a fence token instead:
+//
+//	<file path="templates/payload.tmpl" fence="3f9a0c12d4e5b6a7">
+//	...
+//	</file fence="3f9a0c12d4e5b6a7">
+//
+// The token is derived from the content, so payloads stay deterministic, and only such files are
+// fenced, so every other block keeps the classic shape.
+
+// fenceTokenLen is the number of hex characters of a fence token.
+const fenceTokenLen = 16
+
+// needsFence reports whether content could be mistaken for a block boundary.
+func needsFence(content string) bool {
+	return strings.Contains(content, "</file") || strings.Contains(content, "<file path=")
+}
+
+// needsSectionFence is needsFence for <section> blocks, which may also contain file blocks
+// (e.g. a diff of a payload).
+func needsSectionFence(content string) bool {
+	return needsFence(content) || strings.Contains(content, "</section") || strings.Contains(content, "<section name=")
+}
+
+// fenceToken returns a token whose closing tag for element ("file" or "section") does not occur
+// in content.
+func fenceToken(content, element string) string {
+	sum := sha256.Sum256([]byte(content))
+	token := hex.EncodeToString(sum[:])[:fenceTokenLen]
+	for strings.Contains(content, fenceClosingTag(element, token)) {
+		// Only possible if the content quotes its own hash; rehashing moves the token.
+		sum = sha256.Sum256(sum[:])
+		token = hex.EncodeToString(sum[:])[:fenceTokenLen]
+	}
+	return token
+}
+
+func fenceClosingTag(element, token string) string {
+	return "</" + element + " fence=\"" + token + "\">"
+}
+
+var attrEscaper = strings.NewReplacer("&", "&amp;", "\"", "&quot;", "<", "&lt;", ">", "&gt;", "\n", "&#10;")
+var attrUnescaper = strings.NewReplacer("&amp;", "&", "&quot;", "\"", "&lt;", "<", "&gt;", ">", "&#10;", "\n")
+
+// escapeAttr escapes an attribute value of the shotgun format. Ordinary paths are left unchanged.
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
+
+func unescapeAttr(s string) string {
+	return attrUnescaper.Replace(s)
+}
@@ -0,0 +1,86 @@
+package shotgun
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFenceTokenIsStable(t *testing.T) {
+	content := "<file path=\"x\">\n</file>\n"
+	token := fenceToken(content, "file")
+	if len(token) != fenceTokenLen {
+		t.Fatalf("token %q has %d characters, want %d", token, len(token), fenceTokenLen)
+	}
+	if _, err := hex.DecodeString(token); err != nil {
+		t.Errorf("token %q is not hex: %v", token, err)
+	}
+	for i := 0; i < 3; i++ {
+		if again := fenceToken(content, "file"); again != token {
+			t.Fatalf("token changed between calls: %q, then %q", token, again)
+		}
+	}
+	if other := fenceToken(content+" ", "file"); other == token {
+		t.Errorf("different contents share the token %q", token)
+	}
+}
+
+func TestNeedsFence(t *testing.T) {
+	for content, want := range map[string]bool{
+		"plain text":              false,
+		"a <file> tag":            false,
+		"ends with </file>":       true,
+		"</file fence=\"abc\">":   true,
+		"<file path=\"x\">":       true,
+		"</section> on its own":   false,
+		"<section name=\"diff\">": false,
+		"<filepath> is not a tag": false,
+	} {
+		if got := needsFence(content); got != want {
+			t.Errorf("needsFence(%q) = %v, want %v", content, got, want)
+		}
+	}
+	if !needsSectionFence("</section>") || !needsSectionFence("<section name=\"x\">") {
+		t.Error("needsSectionFence misses section boundaries")
+	}
+}
+
+func TestFencedClosingTagRoundTrip(t *testing.T) {
+	// inner.tmpl is fenced; outer.txt quotes the whole fenced block, closing tag included.
+	inner := "<file path=\"{{.Path}}\">\n{{.Content}}\n</file>\n"
+	innerClosing := fenceClosingTag("file", fenceToken(inner, "file"))
+	outer := "<file path=\"inner.tmpl\" fence=\"" + fenceToken(inner, "file") + "\">\n" + inner + "\n" + innerClosing + "\n"
+	files := map[string]string{"inner.tmpl": inner, "outer.txt": outer}
+
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, files)
+	payload := generate(t, Options{RootDir: root})
+	if strings.Count(payload, innerClosing) != 2 {
+		t.Fatalf("want the inner closing tag once as a boundary and once quoted:\n%s", payload)
+	}
+	if !strings.Contains(payload, fenceClosingTag("file", fenceToken(outer, "file"))) {
+		t.Fatalf("outer.txt is not fenced:\n%s", payload)
+	}
+
+	again := generate(t, Options{RootDir: root})
+	if again != payload {
+		t.Error("payload differs between runs")
+	}
+
+	parsed, err := ParsePayload(payload)
+	if err != nil {
+		t.Fatalf("ParsePayload: %v", err)
+	}
+	got := parsed.Contents()
+	for path, want := range files {
+		if got[path] != want {
+			t.Errorf("%s = %q, want %q", path, got[path], want)
+		}
+	}
+	for _, f := range parsed.Files {
+		if _, ok := f.Attrs["fence"]; ok {
+			t.Errorf("%s: fence leaked into the attributes", f.Path)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package shotgun
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Names of the built-in payload formats.
+const (
+	FormatShotgun  = "shotgun"  // ASCII tree followed by <file path="..."> blocks (the default)
+	FormatMarkdown = "markdown" // Headings with fenced code blocks tagged by language
+	FormatJSON     = "json"     // {"tree": ..., "files": [{"path": ..., "content": ...}]}
+	FormatXML      = "xml"      // Well-formed XML document with CDATA sections
+)
+
+// Attr is an extra attribute of a file in the payload, e.g. binary="true" or size="123".
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// FileView is everything a Formatter needs to render one file.
+type FileView struct {
+	// Path is relative to the project root, with forward slashes.
+	Path string
+	// Content is the UTF-8 file content, or a short note when Omitted is set.
+	Content string
+	// Omitted marks placeholders (binary, undecoded or truncated files) whose Content is a note.
+	Omitted bool
+	// Attrs are rendered in order after the path.
+	Attrs []Attr
+}
+
+// Formatter renders the payload. FormatFile is called once per file, possibly concurrently;
+// FormatPayload joins the tree, the rendered files, which are in tree order, and the rendered
+// sections.
+type Formatter interface {
+	Name() string
+	FormatFile(f FileView) string
+	FormatSection(s Section) string
+	FormatPayload(tree string, files, sections []string) string
+}
+
+var formatters = map[string]Formatter{
+	FormatShotgun:  shotgunFormatter{},
+	FormatMarkdown: markdownFormatter{},
+	FormatJSON:     jsonFormatter{},
+	FormatXML:      xmlFormatter{},
+}
+
+// DefaultFormatter returns the formatter of the classic shotgun payload.
+func DefaultFormatter() Formatter {
+	return shotgunFormatter{}
+}
+
+// FormatterByName returns the built-in formatter called name. An empty name selects the default.
+func FormatterByName(name string) (Formatter, error) {
+	if name == "" {
+		return DefaultFormatter(), nil
+	}
+	f, ok := formatters[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (available: %s)", name, strings.Join(FormatNames(), ", "))
+	}
+	return f, nil
+}
+
+// FormatNames lists the names accepted by FormatterByName.
+func FormatNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shotgunFormatter renders the original payload: the tree, a blank line and <file> blocks.
+type shotgunFormatter struct{}
+
+func (shotgunFormatter) Name() string { return FormatShotgun }
+
+func (shotgunFormatter) FormatFile(f FileView) string {
+	attrs := f.Attrs
+	closing := "</file>"
+	if needsFence(f.Content) {
+		token := fenceToken(f.Content, "file")
+		attrs = append(attrs[:len(attrs):len(attrs)], Attr{"fence", token})
+		closing = fenceClosingTag("file", token)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<file path=\"%s\"", escapeAttr(f.Path)))
+	for _, attr := range attrs {
+		sb.WriteString(fmt.Sprintf(" %s=\"%s\"", attr.Name, escapeAttr(attr.Value)))
+	}
+	sb.WriteString(">\n")
+	sb.WriteString(f.Content)
+	sb.WriteString("\n" + closing + "\n") // Each file block ends with a newline
+	return sb.String()
+}
+
+func (shotgunFormatter) FormatSection(s Section) string {
+	attrs := s.Attrs
+	closing := "</section>"
+	if needsSectionFence(s.Content) {
+		token := fenceToken(s.Content, "section")
+		attrs = append(attrs[:len(attrs):len(attrs)], Attr{"fence", token})
+		closing = fenceClosingTag("section", token)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<section name=\"%s\"", escapeAttr(s.Name)))
+	for _, attr := range attrs {
+		sb.WriteString(fmt.Sprintf(" %s=\"%s\"", attr.Name, escapeAttr(attr.Value)))
+	}
+	sb.WriteString(">\n")
+	sb.WriteString(s.Content)
+	sb.WriteString("\n" + closing + "\n")
+	return sb.String()
+}
+
+func (shotgunFormatter) FormatPayload(tree string, files, sections []string) string {
+	// The final output is the tree, a newline, then all concatenated file contents and sections.
+	// If there are no files, we still want the newline after the tree.
+	// Each <file> and <section> block ends with a newline, so only the trailing one is trimmed.
+	return tree + "\n" + strings.TrimRight(strings.Join(files, "")+strings.Join(sections, ""), "\n")
+}
@@ -0,0 +1,77 @@
+package shotgun
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// jsonFile is one element of the "files" array of the JSON format.
+type jsonFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Omitted is set for placeholders whose content is a note instead of the file.
+	Omitted    bool              `json:"omitted,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// jsonSection is one entry of the "sections" array.
+type jsonSection struct {
+	Name       string            `json:"name"`
+	Content    string            `json:"content"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// jsonFormatter renders a single JSON document: {"tree": "...", "files": [{"path", "content"}]}.
+type jsonFormatter struct{}
+
+func (jsonFormatter) Name() string { return FormatJSON }
+
+func (jsonFormatter) FormatFile(f FileView) string {
+	file := jsonFile{Path: f.Path, Content: f.Content, Omitted: f.Omitted}
+	if len(f.Attrs) > 0 {
+		file.Attributes = make(map[string]string, len(f.Attrs))
+		for _, attr := range f.Attrs {
+			file.Attributes[attr.Name] = attr.Value
+		}
+	}
+	return "    " + marshalJSON(file)
+}
+
+func (jsonFormatter) FormatSection(s Section) string {
+	section := jsonSection{Name: s.Name, Content: s.Content}
+	if len(s.Attrs) > 0 {
+		section.Attributes = make(map[string]string, len(s.Attrs))
+		for _, attr := range s.Attrs {
+			section.Attributes[attr.Name] = attr.Value
+		}
+	}
+	return "    " + marshalJSON(section)
+}
+
+func (jsonFormatter) FormatPayload(tree string, files, sections []string) string {
+	var sb strings.Builder
+	sb.WriteString("{\n  \"tree\": " + marshalJSON(tree) + ",\n  \"files\": [")
+	if len(files) > 0 {
+		sb.WriteString("\n" + strings.Join(files, ",\n") + "\n  ")
+	}
+	sb.WriteString("]")
+	if len(sections) > 0 {
+		// Only present when sections were requested, so plain payloads keep their shape.
+		sb.WriteString(",\n  \"sections\": [\n" + strings.Join(sections, ",\n") + "\n  ]")
+	}
+	sb.WriteString("\n}\n")
+	return sb.String()
+}
+
+// marshalJSON encodes v on one line without HTML escaping, so source code stays readable.
+func marshalJSON(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		// Only strings and string maps are encoded here; this cannot fail.
+		panic(err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,144 @@
+package shotgun
+
+import (
+	"path"
+	"strings"
+)
+
+// markdownFormatter renders the tree and every file as fenced code blocks under a heading.
+type markdownFormatter struct{}
+
+func (markdownFormatter) Name() string { return FormatMarkdown }
+
+func (markdownFormatter) FormatFile(f FileView) string {
+	var sb strings.Builder
+	sb.WriteString("## `" + f.Path + "`\n\n")
+	if len(f.Attrs) > 0 {
+		parts := make([]string, len(f.Attrs))
+		for i, attr := range f.Attrs {
+			parts[i] = attr.Name + ": " + attr.Value
+		}
+		sb.WriteString("_" + strings.Join(parts, ", ") + "_\n\n")
+	}
+	if f.Omitted {
+		sb.WriteString(f.Content + "\n\n")
+		return sb.String()
+	}
+	sb.WriteString(fenceBlock(f.Content, languageForPath(f.Path)))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (markdownFormatter) FormatSection(s Section) string {
+	var sb strings.Builder
+	sb.WriteString("# " + s.Name + "\n\n")
+	if len(s.Attrs) > 0 {
+		parts := make([]string, len(s.Attrs))
+		for i, attr := range s.Attrs {
+			parts[i] = attr.Name + ": " + attr.Value
+		}
+		sb.WriteString("_" + strings.Join(parts, ", ") + "_\n\n")
+	}
+	lang := s.Language
+	if lang == "" {
+		lang = "text"
+	}
+	sb.WriteString(fenceBlock(s.Content, lang))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (markdownFormatter) FormatPayload(tree string, files, sections []string) string {
+	var sb strings.Builder
+	sb.WriteString("# Project tree\n\n")
+	sb.WriteString(fenceBlock(strings.TrimRight(tree, "\n"), "text"))
+	if len(files) > 0 {
+		sb.WriteString("\n# Files\n\n")
+		sb.WriteString(strings.TrimRight(strings.Join(files, ""), "\n"))
+		sb.WriteString("\n")
+	}
+	if len(sections) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(strings.TrimRight(strings.Join(sections, ""), "\n"))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// fenceBlock wraps content in a code fence longer than any backtick run inside it.
+func fenceBlock(content, lang string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + content + "\n" + fence + "\n"
+}
+
+// markdownLanguages maps file extensions and well-known file names to fence language tags.
+var markdownLanguages = map[string]string{
+	".go":            "go",
+	".js":            "javascript",
+	".mjs":           "javascript",
+	".cjs":           "javascript",
+	".jsx":           "jsx",
+	".ts":            "typescript",
+	".tsx":           "tsx",
+	".vue":           "vue",
+	".py":            "python",
+	".rb":            "ruby",
+	".rs":            "rust",
+	".java":          "java",
+	".kt":            "kotlin",
+	".swift":         "swift",
+	".c":             "c",
+	".h":             "c",
+	".cc":            "cpp",
+	".cpp":           "cpp",
+	".hpp":           "cpp",
+	".cs":            "csharp",
+	".php":           "php",
+	".sh":            "bash",
+	".bash":          "bash",
+	".zsh":           "bash",
+	".ps1":           "powershell",
+	".sql":           "sql",
+	".html":          "html",
+	".css":           "css",
+	".scss":          "scss",
+	".json":          "json",
+	".yaml":          "yaml",
+	".yml":           "yaml",
+	".toml":          "toml",
+	".xml":           "xml",
+	".md":            "markdown",
+	".proto":         "protobuf",
+	".graphql":       "graphql",
+	".tf":            "hcl",
+	".lua":           "lua",
+	".dart":          "dart",
+	".scala":         "scala",
+	".ex":            "elixir",
+	".exs":           "elixir",
+	"dockerfile":     "dockerfile",
+	"makefile":       "makefile",
+	"go.mod":         "go-mod",
+	"go.sum":         "text",
+	"cmakelists.txt": "cmake",
+	".gitignore":     "gitignore",
+	".dockerfile":    "dockerfile",
+}
+
+// languageForPath guesses the fence language tag of a file. Unknown files get no tag.
+func languageForPath(p string) string {
+	base := strings.ToLower(path.Base(p))
+	if lang, ok := markdownLanguages[base]; ok {
+		return lang
+	}
+	return markdownLanguages[path.Ext(base)]
+}
@@ -0,0 +1,231 @@
+package shotgun
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// awkwardContent holds what the formats have to escape or fence.
+const awkwardContent = "if a < b && c > \"d\" {\n\treturn `x` + '\\\\' // ]]> and ]]]]> ``` </file>\n}\n"
+
+func TestFormatterByName(t *testing.T) {
+	for name, want := range map[string]string{"": FormatShotgun, "shotgun": FormatShotgun, " Markdown ": FormatMarkdown, "JSON": FormatJSON, "xml": FormatXML} {
+		f, err := FormatterByName(name)
+		if err != nil || f.Name() != want {
+			t.Errorf("FormatterByName(%q) = %v, %v; want %s", name, f, err, want)
+		}
+	}
+	_, err := FormatterByName("yaml")
+	if err == nil || !strings.Contains(err.Error(), "json, markdown, shotgun, xml") {
+		t.Errorf("unknown format error = %v, want the available formats listed", err)
+	}
+}
+
+func TestMarkdownFormatter(t *testing.T) {
+	f := markdownFormatter{}
+	tests := []struct {
+		name string
+		file FileView
+		want string
+	}{
+		{
+			"language tag",
+			FileView{Path: "cmd/main.go", Content: "package main"},
+			"## `cmd/main.go`\n\n```go\npackage main\n```\n\n",
+		},
+		{
+			"fence longer than the backtick runs of the content",
+			FileView{Path: "README.md", Content: "```sh\nmake\n```\n````"},
+			"## `README.md`\n\n`````markdown\n```sh\nmake\n```\n````\n`````\n\n",
+		},
+		{
+			"well-known file name, attributes",
+			FileView{Path: "Dockerfile", Content: "FROM scratch", Attrs: []Attr{{"render", "outline"}, {"size", "12"}}},
+			"## `Dockerfile`\n\n_render: outline, size: 12_\n\n```dockerfile\nFROM scratch\n```\n\n",
+		},
+		{
+			"omitted file is a note, not a code block",
+			FileView{Path: "logo.png", Content: "[binary file omitted]", Omitted: true, Attrs: []Attr{{"binary", "true"}}},
+			"## `logo.png`\n\n_binary: true_\n\n[binary file omitted]\n\n",
+		},
+		{
+			"unknown extension",
+			FileView{Path: "data.xyz", Content: "x"},
+			"## `data.xyz`\n\n```\nx\n```\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.FormatFile(tt.file); got != tt.want {
+				t.Errorf("FormatFile =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	section := f.FormatSection(Section{Name: "git-diff", Content: "+x", Language: "diff"})
+	payload := f.FormatPayload("project\n└── main.go\n", []string{f.FormatFile(FileView{Path: "main.go", Content: "package main"})}, []string{section})
+	want := "# Project tree\n\n```text\nproject\n└── main.go\n```\n" +
+		"\n# Files\n\n## `main.go`\n\n```go\npackage main\n```\n" +
+		"\n# git-diff\n\n```diff\n+x\n```\n"
+	if payload != want {
+		t.Errorf("FormatPayload =\n%q\nwant\n%q", payload, want)
+	}
+}
+
+func TestJSONFormatterEscaping(t *testing.T) {
+	f := jsonFormatter{}
+	files := []FileView{
+		{Path: "a \"quoted\" path.go", Content: awkwardContent + "\x00\x1f\u2028"},
+		{Path: "logo.png", Content: "[binary file omitted]", Omitted: true, Attrs: []Attr{{"binary", "true"}}},
+	}
+	var rendered []string
+	for _, file := range files {
+		rendered = append(rendered, f.FormatFile(file))
+	}
+	payload := f.FormatPayload("tree \"with\" \\ quotes\n", rendered, []string{f.FormatSection(Section{Name: "notes", Content: "</section>\n", Attrs: []Attr{{"k", "v"}}})})
+
+	var doc struct {
+		Tree     string        `json:"tree"`
+		Files    []jsonFile    `json:"files"`
+		Sections []jsonSection `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, payload)
+	}
+	want := []jsonFile{
+		{Path: files[0].Path, Content: files[0].Content},
+		{Path: "logo.png", Content: "[binary file omitted]", Omitted: true, Attributes: map[string]string{"binary": "true"}},
+	}
+	if doc.Tree != "tree \"with\" \\ quotes\n" || !reflect.DeepEqual(doc.Files, want) {
+		t.Errorf("decoded = %+v, want %+v", doc, want)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Content != "</section>\n" || doc.Sections[0].Attributes["k"] != "v" {
+		t.Errorf("sections = %+v", doc.Sections)
+	}
+	// Source code stays readable: no HTML escaping of <, > and &.
+	if !strings.Contains(payload, `if a < b && c > \"d\"`) {
+		t.Errorf("the content is HTML-escaped:\n%s", payload)
+	}
+	// Control characters and the JavaScript line separators are escaped.
+	if !strings.Contains(payload, `\u0000\u001f\u2028"}`) {
+		t.Errorf("control characters are not escaped:\n%s", payload)
+	}
+	if strings.Count(payload, "\n") != 10 {
+		t.Errorf("every file is not on a line of its own:\n%s", payload)
+	}
+}
+
+func TestJSONFormatterEmpty(t *testing.T) {
+	f := jsonFormatter{}
+	if got, want := f.FormatPayload("project\n", nil, nil), "{\n  \"tree\": \"project\\n\",\n  \"files\": []\n}\n"; got != want {
+		t.Errorf("FormatPayload =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCDATA(t *testing.T) {
+	tests := []struct {
+		content, want string
+	}{
+		{"plain", "<![CDATA[plain]]>"},
+		{"", "<![CDATA[]]>"},
+		{"a]]>b", "<![CDATA[a]]]]><![CDATA[>b]]>"},
+		{"]]>]]>", "<![CDATA[]]]]><![CDATA[>]]]]><![CDATA[>]]>"},
+		{"ends with ]]", "<![CDATA[ends with ]]]]>"},
+		{"nul\x00 and \x1b escape", "<![CDATA[nul\uFFFD and \uFFFD escape]]>"},
+	}
+	for _, tt := range tests {
+		if got := cdata(tt.content); got != tt.want {
+			t.Errorf("cdata(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestXMLFormatterRoundTrip(t *testing.T) {
+	f := xmlFormatter{}
+	contents := []string{awkwardContent, "]]>", "]]]]>>", "x]]", "<![CDATA[nested]]>", "crlf\r\nline"}
+	var rendered []string
+	for i, content := range contents {
+		rendered = append(rendered, f.FormatFile(FileView{Path: "dir/a&b \"" + strconv.Itoa(i) + "\".go", Content: content}))
+	}
+	rendered = append(rendered, f.FormatFile(FileView{Path: "logo.png", Content: "[binary file omitted]", Omitted: true, Attrs: []Attr{{"size", "<1KB>"}}}))
+	payload := f.FormatPayload("tree ]]> <tag>\n", rendered, []string{f.FormatSection(Section{Name: "git-diff", Content: "-]]>\n+]]>"})})
+
+	type xmlFile struct {
+		Path    string `xml:"path,attr"`
+		Omitted bool   `xml:"omitted,attr"`
+		Size    string `xml:"size,attr"`
+		Content string `xml:",chardata"`
+	}
+	var doc struct {
+		XMLName  xml.Name  `xml:"context"`
+		Tree     string    `xml:"tree"`
+		Files    []xmlFile `xml:"file"`
+		Sections []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"section"`
+	}
+	if err := xml.Unmarshal([]byte(payload), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, payload)
+	}
+	if doc.Tree != "tree ]]> <tag>\n" {
+		t.Errorf("tree = %q", doc.Tree)
+	}
+	if len(doc.Files) != len(contents)+1 {
+		t.Fatalf("files = %+v", doc.Files)
+	}
+	for i, content := range contents {
+		// XML parsers normalise line endings.
+		want := strings.ReplaceAll(content, "\r\n", "\n")
+		if got := doc.Files[i]; got.Content != want || got.Path != "dir/a&b \""+strconv.Itoa(i)+"\".go" {
+			t.Errorf("file %d = %+v, want content %q", i, got, want)
+		}
+	}
+	if got := doc.Files[len(contents)]; !got.Omitted || got.Size != "<1KB>" || got.Content != "[binary file omitted]" {
+		t.Errorf("omitted file = %+v", got)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Name != "git-diff" || doc.Sections[0].Content != "-]]>\n+]]>" {
+		t.Errorf("sections = %+v", doc.Sections)
+	}
+}
+
+func TestGenerateFormats(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"main.go":      "package main\n\n// ]]> \"quoted\" <tag>\n",
+		"sub/notes.md": "```\ncode\n```\n",
+	})
+	for _, name := range FormatNames() {
+		t.Run(name, func(t *testing.T) {
+			formatter, err := FormatterByName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := generate(t, Options{RootDir: root, Formatter: formatter})
+			switch name {
+			case FormatJSON:
+				var doc struct{ Files []jsonFile }
+				if err := json.Unmarshal([]byte(out), &doc); err != nil || len(doc.Files) != 2 || doc.Files[0].Content != "```\ncode\n```\n" || doc.Files[1].Path != "main.go" {
+					t.Errorf("JSON payload = %+v, %v\n%s", doc, err, out)
+				}
+			case FormatXML:
+				if err := xml.Unmarshal([]byte(out), new(struct{})); err != nil {
+					t.Errorf("invalid XML: %v\n%s", err, out)
+				}
+			case FormatMarkdown:
+				if !strings.Contains(out, "## `sub/notes.md`\n\n````markdown\n```\ncode\n```\n\n````\n") {
+					t.Errorf("markdown payload:\n%s", out)
+				}
+			case FormatShotgun:
+				if !strings.Contains(out, "<file path=\"sub/notes.md\">\n") {
+					t.Errorf("shotgun payload:\n%s", out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package shotgun
+
+import (
+	"encoding/xml"
+	"strings"
+	"unicode/utf8"
+)
+
+// xmlFormatter renders a well-formed XML document. Tree and file contents are CDATA sections,
+// attributes are escaped and characters XML 1.0 does not allow are replaced with U+FFFD.
+type xmlFormatter struct{}
+
+func (xmlFormatter) Name() string { return FormatXML }
+
+func (xmlFormatter) FormatFile(f FileView) string {
+	var sb strings.Builder
+	sb.WriteString("<file path=\"" + escapeXMLAttr(f.Path) + "\"")
+	if f.Omitted {
+		sb.WriteString(" omitted=\"true\"")
+	}
+	for _, attr := range f.Attrs {
+		sb.WriteString(" " + attr.Name + "=\"" + escapeXMLAttr(attr.Value) + "\"")
+	}
+	sb.WriteString(">" + cdata(f.Content) + "</file>\n")
+	return sb.String()
+}
+
+func (xmlFormatter) FormatSection(s Section) string {
+	var sb strings.Builder
+	sb.WriteString("<section name=\"" + escapeXMLAttr(s.Name) + "\"")
+	for _, attr := range s.Attrs {
+		sb.WriteString(" " + attr.Name + "=\"" + escapeXMLAttr(attr.Value) + "\"")
+	}
+	sb.WriteString(">" + cdata(s.Content) + "</section>\n")
+	return sb.String()
+}
+
+func (xmlFormatter) FormatPayload(tree string, files, sections []string) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString("<context>\n")
+	sb.WriteString("<tree>" + cdata(tree) + "</tree>\n")
+	for _, f := range files {
+		sb.WriteString(f)
+	}
+	for _, s := range sections {
+		sb.WriteString(s)
+	}
+	sb.WriteString("</context>\n")
+	return sb.String()
+}
+
+// cdata wraps s in a CDATA section, splitting it wherever s itself contains "]]>".
+func cdata(s string) string {
+	return "<![CDATA[" + strings.ReplaceAll(sanitizeXMLChars(s), "]]>", "]]]]><![CDATA[>") + "]]>"
+}
+
+func escapeXMLAttr(s string) string {
+	var sb strings.Builder
+	// EscapeText only fails on write errors, which strings.Builder never returns.
+	_ = xml.EscapeText(&sb, []byte(sanitizeXMLChars(s)))
+	return sb.String()
+}
+
+// sanitizeXMLChars replaces characters that are not allowed anywhere in an XML 1.0 document.
+func sanitizeXMLChars(s string) string {
+	valid := func(r rune) bool {
+		return r == '\t' || r == '\n' || r == '\r' ||
+			(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF)
+	}
+	for _, r := range s {
+		if !valid(r) {
+			return strings.Map(func(r rune) rune {
+				if valid(r) {
+					return r
+				}
+				return utf8.RuneError
+			}, s)
+		}
+	}
+	return s
+}
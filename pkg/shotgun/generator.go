@@ -0,0 +1,610 @@
+// Package shotgun packages a project directory into the "shotgun" context payload:
+// an ASCII tree of the selected files followed by one <file path="..."> block per file.
+//
+// The package has no dependency on the Wails runtime; the desktop app and the headless
+// CLI are both consumers of it.
+package shotgun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxOutputBytes is the payload size limit used when Options.MaxOutputBytes is zero.
+const DefaultMaxOutputBytes = 10_000_000 // 10MB
+
+// DefaultProgressInterval is the minimum time between progress callbacks when Options.ProgressInterval is zero.
+const DefaultProgressInterval = 100 * time.Millisecond
+
+// ErrContextTooLong is returned when the payload grows beyond the configured size limit.
+var ErrContextTooLong = errors.New("context is too long")
+
+// Options configures a Generator.
+type Options struct {
+	// RootDir is the project directory to package.
+	RootDir string
+	// ExcludedPaths are paths relative to RootDir (OS separators) that are left out together with their contents.
+	ExcludedPaths []string
+	// MaxOutputBytes caps the payload size. Zero means DefaultMaxOutputBytes. With a TokenBudget
+	// the cap applies to the payload after truncation; the files may be read up to four times
+	// the cap before that.
+	MaxOutputBytes int
+	// TokenBudget caps the estimated token cost of the payload, typically the context window of
+	// the target model. Zero disables token accounting.
+	TokenBudget int
+	// TokenCounter estimates token costs for TokenBudget. Nil means ApproxTokenCounter.
+	TokenCounter TokenCounter
+	// TruncateToBudget cuts the contents of the heaviest files until the payload fits TokenBudget
+	// instead of failing with a *BudgetError.
+	TruncateToBudget bool
+	// ReencodeText converts UTF-16 (with BOM) and Latin-1 sources to UTF-8 instead of replacing
+	// them with a placeholder. Binary files are always replaced.
+	ReencodeText bool
+	// Workers bounds how many files are read concurrently. Zero means one per CPU, at most 16.
+	Workers int
+	// ProgressInterval throttles progress callbacks. Zero means DefaultProgressInterval.
+	// The first and the final progress updates are always delivered.
+	ProgressInterval time.Duration
+	// Patterns narrows the payload down with doublestar globs, on top of ExcludedPaths.
+	Patterns PathPatterns
+	// Ignore drops the paths matched by the git and custom ignore rules. Leave a side nil to
+	// disable it; the zero Matcher ignores nothing.
+	Ignore Matcher
+	// ForceInclude lists paths relative to RootDir (OS separators) that are kept even when Ignore
+	// matches them or one of their parent folders, e.g. files checked inside node_modules.
+	// A forced folder is kept with all of its contents. ExcludedPaths and Patterns still apply.
+	ForceInclude []string
+	// Symlinks is the symbolic link policy: SymlinkShow (the default), SymlinkSkip or SymlinkFollow.
+	// Files read through a link carry a symlink-target attribute: the link text under
+	// SymlinkShow, the resolved path under SymlinkFollow.
+	Symlinks string
+	// Formatter renders the payload. Nil means DefaultFormatter.
+	Formatter Formatter
+	// Symbols maps paths relative to RootDir (OS separators) of Go files to the declarations to
+	// keep, see ExtractGoSymbols. Such a file is rendered with only those declarations, its
+	// package clause and imports, and tagged with a symbols attribute.
+	Symbols map[string][]string
+	// RenderModes maps paths relative to RootDir (OS separators) to RenderOutline or
+	// RenderOmitted; other files are rendered in full. Symbols take precedence over outlines.
+	// Omitted files are not read.
+	RenderModes map[string]string
+	// Sections are built in order after the files have been read and appended after them.
+	// They count against MaxOutputBytes and TokenBudget but are never truncated.
+	Sections []SectionFunc
+}
+
+// Result is the outcome of a successful generation.
+type Result struct {
+	Output string `json:"output"`
+	// Tokens is the estimated token cost of Output, measured with Options.TokenCounter.
+	Tokens int `json:"tokens"`
+	// Manifest breaks the payload down per file.
+	Manifest Manifest `json:"manifest"`
+	// Truncated lists the files whose contents were cut to fit the token budget, heaviest first.
+	// The entries carry the costs from before truncation.
+	Truncated []ManifestEntry `json:"truncated,omitempty"`
+}
+
+// fileBlock is a rendered <file> block waiting to be appended after the tree.
+type fileBlock struct {
+	relPath  string
+	block    string
+	bytes    int    // Size of the file content
+	lines    int    // Line count of the file content
+	binary   bool   // Content was replaced by a binary placeholder
+	encoding string // Source encoding of non-UTF-8 text, empty for UTF-8
+	target   string // symlink-target of files read through a link
+}
+
+// Generator builds the shotgun payload for a single project directory.
+type Generator struct {
+	opts     Options
+	progress ProgressFunc
+	excluded map[string]bool
+	// forced holds Options.ForceInclude; forcedParents holds the folders above those paths,
+	// which are walked even when ignored.
+	forced        map[string]bool
+	forcedParents map[string]bool
+}
+
+// NewGenerator creates a Generator. progress may be nil.
+func NewGenerator(opts Options, progress ProgressFunc) *Generator {
+	if opts.MaxOutputBytes <= 0 {
+		opts.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	if opts.TokenCounter == nil {
+		opts.TokenCounter = ApproxTokenCounter
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = min(runtime.NumCPU(), 16)
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = DefaultProgressInterval
+	}
+	if opts.Formatter == nil {
+		opts.Formatter = DefaultFormatter()
+	}
+	if progress == nil {
+		progress = func(Progress) {}
+	}
+	excluded := make(map[string]bool, len(opts.ExcludedPaths))
+	for _, p := range opts.ExcludedPaths {
+		excluded[p] = true
+	}
+	forced := make(map[string]bool, len(opts.ForceInclude))
+	forcedParents := make(map[string]bool)
+	for _, p := range opts.ForceInclude {
+		p = filepath.Clean(p)
+		forced[p] = true
+		for dir := filepath.Dir(p); dir != "." && dir != string(os.PathSeparator); dir = filepath.Dir(dir) {
+			forcedParents[dir] = true
+		}
+	}
+	return &Generator{opts: opts, progress: progress, excluded: excluded, forced: forced, forcedParents: forcedParents}
+}
+
+// MaxOutputBytes returns the effective size limit of the generator.
+func (g *Generator) MaxOutputBytes() int {
+	return g.opts.MaxOutputBytes
+}
+
+// budgetReadFactor bounds how far past MaxOutputBytes the files may be read under a token
+// budget, leaving TruncateToBudget room to cut the payload back under MaxOutputBytes.
+const budgetReadFactor = 4
+
+// runningByteCap is the size limit enforced while the payload is being built. With a token
+// budget it is budgetReadFactor times MaxOutputBytes: TruncateToBudget may still bring the
+// payload under MaxOutputBytes, which Generate checks once the budget has been applied.
+func (g *Generator) runningByteCap() int {
+	if g.opts.TokenBudget > 0 && g.opts.MaxOutputBytes <= math.MaxInt/budgetReadFactor {
+		return g.opts.MaxOutputBytes * budgetReadFactor
+	}
+	return g.opts.MaxOutputBytes
+}
+
+// Generate walks RootDir and returns the tree followed by the <file> blocks of every non-excluded file.
+// When a token budget is set and exceeded, it returns a *BudgetError unless Options.TruncateToBudget is set.
+//
+// The directory tree is listed in a single walk; file contents are then read by a bounded pool of
+// Options.Workers goroutines. Blocks keep the tree order regardless of which worker finishes first.
+func (g *Generator) Generate(ctx context.Context) (*Result, error) {
+	if err := ctx.Err(); err != nil { // Check for cancellation at the beginning
+		return nil, err
+	}
+	if err := g.opts.Patterns.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ValidateSymlinkPolicy(g.opts.Symlinks); err != nil {
+		return nil, err
+	}
+	for _, mode := range g.opts.RenderModes {
+		if err := ValidateRenderMode(mode); err != nil {
+			return nil, err
+		}
+	}
+
+	tree, jobs, err := g.walk(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Progress: 1 for the root dir line, 1 for each tree entry, 1 for each file content read.
+	progress := newProgressThrottle(g.progress, g.opts.ProgressInterval)
+	progress.total = tree.entries + 1 + len(jobs)
+	progress.advance(tree.entries + 1)
+
+	files, err := g.readFiles(ctx, jobs, len(tree.text), progress)
+	if err != nil {
+		return nil, err
+	}
+	progress.flush()
+
+	used := len(tree.text)
+	for _, f := range files {
+		used += len(f.block)
+	}
+	sections, err := g.buildSections(ctx, files, used)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil { // Check for cancellation before final string operations
+		return nil, err
+	}
+
+	manifest := g.buildManifest(tree.text, files, sections)
+	result := &Result{}
+	if g.opts.TokenBudget > 0 {
+		if err := g.applyTokenBudget(files, &manifest, result); err != nil {
+			return nil, err
+		}
+	}
+	result.Output = g.assembleOutput(tree.text, files, sections)
+	if maxBytes := g.opts.MaxOutputBytes; len(result.Output) > maxBytes {
+		return nil, fmt.Errorf("%w: content limit of %d bytes exceeded (size: %d bytes)", ErrContextTooLong, maxBytes, len(result.Output))
+	}
+	result.Tokens = manifest.TotalTokens
+	result.Manifest = manifest
+	return result, nil
+}
+
+// walkedTree is the ASCII tree produced by walk.
+type walkedTree struct {
+	text    string
+	entries int // Number of file and folder lines below the root line
+}
+
+// fileJob is a file found by walk whose content still has to be read.
+type fileJob struct {
+	path    string
+	relPath string
+	target  string // Link text or resolved location when read through a link, see listedEntry.target
+}
+
+// walkNode is an entry of RootDir that made it into the payload.
+type walkNode struct {
+	name     string
+	path     string
+	relPath  string
+	isDir    bool
+	link     bool   // Symbolic link listed as "name -> target" without content
+	target   string // Link target, see listedEntry.target
+	children []*walkNode
+}
+
+// walk lists RootDir once, rendering the ASCII tree and collecting the files to read in tree order.
+func (g *Generator) walk(ctx context.Context) (walkedTree, []fileJob, error) {
+	rootDir := g.opts.RootDir
+	maxBytes := g.runningByteCap()
+
+	resolver, root := newLinkResolver(rootDir, g.opts.Symlinks)
+	nodes, err := g.scan(ctx, resolver, root, false, false)
+	if err != nil {
+		return walkedTree{}, nil, err
+	}
+
+	var output strings.Builder
+	var jobs []fileJob
+	entries := 0
+
+	// Root directory line
+	output.WriteString(filepath.Base(rootDir) + string(os.PathSeparator) + "\n")
+	if output.Len() > maxBytes {
+		return walkedTree{}, nil, fmt.Errorf("%w: content limit of %d bytes exceeded after root dir line (size: %d bytes)", ErrContextTooLong, maxBytes, output.Len())
+	}
+
+	var render func(nodes []*walkNode, prefix string) error
+	render = func(nodes []*walkNode, prefix string) error {
+		for i, node := range nodes {
+			branch := "├── "
+			nextPrefix := prefix + "│   "
+			if i == len(nodes)-1 {
+				branch = "└── "
+				nextPrefix = prefix + "    "
+			}
+			name := node.name
+			if node.link {
+				name += " -> " + node.target
+			}
+			output.WriteString(prefix + branch + name + "\n")
+			entries++
+
+			if output.Len() > maxBytes {
+				return fmt.Errorf("%w: content limit of %d bytes exceeded during tree generation (size: %d bytes)", ErrContextTooLong, maxBytes, output.Len())
+			}
+
+			if node.isDir {
+				if err := render(node.children, nextPrefix); err != nil {
+					return err
+				}
+			} else if !node.link {
+				jobs = append(jobs, fileJob{path: node.path, relPath: node.relPath, target: node.target})
+			}
+		}
+		return nil
+	}
+
+	if err := render(nodes, ""); err != nil {
+		return walkedTree{}, nil, fmt.Errorf("failed to build tree for shotgun: %w", err)
+	}
+	return walkedTree{text: output.String(), entries: entries}, jobs, nil
+}
+
+// scan lists dir recursively and keeps the entries that survive ExcludedPaths, Ignore and
+// Patterns, sorted like BuildTree. With include patterns, folders without an included file are dropped.
+// forced is set below a ForceInclude folder, where Ignore no longer applies; ignored is set below an
+// ignored folder that is only walked to reach forced paths.
+func (g *Generator) scan(ctx context.Context, resolver *linkResolver, dir walkDir, forced, ignored bool) ([]*walkNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := resolver.readDir(dir)
+	if err != nil {
+		// Skip unreadable directories but keep the rest of the tree.
+		log.Printf("walk: error reading dir %s: %v", dir.path, err)
+		return nil, nil
+	}
+
+	var nodes []*walkNode
+	for _, entry := range entries {
+		relPath, _ := filepath.Rel(g.opts.RootDir, entry.path)
+		if g.excluded[relPath] || g.opts.Patterns.excludes(relPath) {
+			continue
+		}
+		node := &walkNode{name: entry.name, path: entry.path, relPath: relPath, isDir: entry.isDir, link: entry.link, target: entry.target}
+		entryForced := forced || g.forced[relPath]
+		entryIgnored := !entryForced && (ignored || g.opts.Ignore.Ignored(relPath, node.isDir))
+		if entryIgnored && !(node.isDir && g.forcedParents[relPath]) {
+			continue
+		}
+		if node.isDir {
+			node.children, err = g.scan(ctx, resolver, resolver.enter(dir, entry), entryForced, entryIgnored)
+			if err != nil {
+				return nil, err
+			}
+			if (entryIgnored || g.opts.Patterns.hasIncludes()) && len(node.children) == 0 {
+				continue
+			}
+		} else if !g.opts.Patterns.includes(relPath) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// readFiles reads and renders jobs concurrently. The returned blocks are in job order.
+// treeLen is counted against runningByteCap together with the blocks.
+func (g *Generator) readFiles(ctx context.Context, jobs []fileJob, treeLen int, progress *progressThrottle) ([]fileBlock, error) {
+	maxBytes := g.runningByteCap()
+	files := make([]fileBlock, len(jobs))
+
+	poolCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		size     atomic.Int64
+		firstErr error
+		errOnce  sync.Once
+		wg       sync.WaitGroup
+	)
+	size.Store(int64(treeLen))
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	next := make(chan int)
+	workers := min(g.opts.Workers, len(jobs))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if poolCtx.Err() != nil {
+					continue // Drain remaining indexes after cancellation
+				}
+				job := jobs[i]
+				if g.opts.RenderModes[job.relPath] == RenderOmitted {
+					files[i] = g.renderOmitted(job)
+				} else {
+					content, err := os.ReadFile(job.path)
+					if err != nil {
+						log.Printf("Error reading file %s: %v", job.path, err)
+						content = []byte(fmt.Sprintf("Error reading file: %v", err))
+					}
+					files[i] = g.renderFile(job, content)
+				}
+				progress.advance(1)
+
+				if total := size.Add(int64(len(files[i].block))); total > int64(maxBytes) {
+					fail(fmt.Errorf("%w: content limit of %d bytes exceeded after appending file %s (total size: %d bytes)", ErrContextTooLong, maxBytes, job.relPath, total))
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range jobs {
+		select {
+		case next <- i:
+		case <-poolCtx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("failed to build tree for shotgun: %w", firstErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// applyTokenBudget checks the manifest totals against the budget. If the payload does not fit
+// it either truncates the heaviest blocks in place (updating the manifest) or returns a *BudgetError.
+func (g *Generator) applyTokenBudget(files []fileBlock, manifest *Manifest, result *Result) error {
+	budget := g.opts.TokenBudget
+	counter := g.opts.TokenCounter
+	entries := manifest.Files
+
+	total := manifest.TotalTokens
+	if total <= budget {
+		return nil
+	}
+
+	overflowStart := -1
+	running := manifest.TreeTokens
+	for _, s := range manifest.Sections {
+		running += s.Tokens
+	}
+	for i, e := range entries {
+		running += e.Tokens
+		if running > budget {
+			overflowStart = i
+			break
+		}
+	}
+
+	placeholders := make(map[int]string)
+	placeholderCost := func(i int) int {
+		placeholders[i] = g.opts.Formatter.FormatFile(withLinkTarget(truncatedView(files[i].relPath, entries[i].Tokens), files[i].target))
+		return counter.CountTokens(placeholders[i])
+	}
+	picked, truncatedTotal := planTruncation(entries, total, budget, placeholderCost)
+
+	budgetErr := &BudgetError{Budget: budget, Total: total, TruncationFits: truncatedTotal <= budget}
+	if overflowStart >= 0 {
+		budgetErr.Overflow = append([]ManifestEntry(nil), entries[overflowStart:]...)
+	}
+	for _, idx := range picked {
+		budgetErr.Truncation = append(budgetErr.Truncation, entries[idx])
+	}
+	// Without TruncationFits even cutting every file worth it leaves the tree and the sections over budget.
+	if !g.opts.TruncateToBudget || !budgetErr.TruncationFits {
+		return budgetErr
+	}
+
+	for _, idx := range picked {
+		placeholder := placeholders[idx]
+		placeholderTokens := counter.CountTokens(placeholder)
+		files[idx].block = placeholder
+		entries[idx].Tokens = placeholderTokens
+		entries[idx].Truncated = true
+	}
+	manifest.TotalTokens = truncatedTotal
+	result.Truncated = budgetErr.Truncation
+	return nil
+}
+
+// assembleOutput joins the tree, the file blocks and the sections into the final payload.
+func (g *Generator) assembleOutput(tree string, files []fileBlock, sections []sectionBlock) string {
+	blocks := make([]string, len(files))
+	for i, f := range files {
+		blocks[i] = f.block
+	}
+	var rendered []string
+	for _, s := range sections {
+		rendered = append(rendered, s.block)
+	}
+	return g.opts.Formatter.FormatPayload(tree, blocks, rendered)
+}
+
+// renderFile sniffs content and renders its block: the file itself, its UTF-8 re-encoding,
+// its symbols or outline, or a placeholder for binary and undecoded files.
+func (g *Generator) renderFile(job fileJob, content []byte) fileBlock {
+	relPath := job.relPath
+	file := fileBlock{relPath: relPath, bytes: len(content), target: job.target}
+	binary, encoding := sniffContent(content)
+	file.binary = binary
+	file.encoding = encoding
+	var view FileView
+	mode := g.opts.RenderModes[relPath]
+	switch {
+	case binary:
+		view = binaryView(relPath, len(content))
+	case encoding != "" && !g.opts.ReencodeText:
+		view = undecodedView(relPath, len(content), encoding)
+	default:
+		text := decodeToUTF8(content, encoding)
+		view = FileView{Path: filepath.ToSlash(relPath), Content: string(text)}
+		file.lines = countLines(text)
+		if symbols := g.opts.Symbols[relPath]; len(symbols) > 0 {
+			view = symbolsView(view, symbols)
+		} else if mode == RenderOutline {
+			view = outlineView(view)
+		}
+	}
+	file.block = g.opts.Formatter.FormatFile(withLinkTarget(view, job.target))
+	return file
+}
+
+// renderOmitted renders the placeholder of a file whose render mode is RenderOmitted. The file
+// is not read; its size comes from os.Stat.
+func (g *Generator) renderOmitted(job fileJob) fileBlock {
+	size := 0
+	if info, err := os.Stat(job.path); err != nil {
+		log.Printf("Error reading file info %s: %v", job.path, err)
+	} else {
+		size = int(info.Size())
+	}
+	view := omittedView(job.relPath, size)
+	return fileBlock{relPath: job.relPath, bytes: size, target: job.target, block: g.opts.Formatter.FormatFile(withLinkTarget(view, job.target))}
+}
+
+// symbolsView reduces a Go file to the requested declarations. Files that are not Go, do not
+// parse or declare none of the symbols are kept whole.
+func symbolsView(view FileView, symbols []string) FileView {
+	if !strings.EqualFold(path.Ext(view.Path), ".go") {
+		log.Printf("Symbols requested for %s, which is not a Go file; including it whole", view.Path)
+		return view
+	}
+	extracted, found, err := ExtractGoSymbols([]byte(view.Content), symbols)
+	if err != nil || len(found) == 0 {
+		log.Printf("None of the symbols %v found in %s (%v); including it whole", symbols, view.Path, err)
+		return view
+	}
+	view.Content = extracted
+	view.Attrs = append(view.Attrs, Attr{"symbols", strings.Join(found, ",")})
+	return view
+}
+
+// outlineView elides the function bodies of a file. Languages Outline does not handle are kept
+// whole.
+func outlineView(view FileView) FileView {
+	outline, ok := Outline(view.Path, []byte(view.Content))
+	if !ok {
+		log.Printf("Cannot outline %s; including it whole", view.Path)
+		return view
+	}
+	view.Content = outline
+	view.Attrs = append(view.Attrs, Attr{"render", RenderOutline})
+	return view
+}
+
+// omittedView is the placeholder of a file whose render mode is RenderOmitted.
+func omittedView(relPath string, size int) FileView {
+	return FileView{
+		Path:    filepath.ToSlash(relPath),
+		Content: "[content omitted]",
+		Omitted: true,
+		Attrs:   []Attr{{"render", RenderOmitted}, {"size", strconv.Itoa(size)}},
+	}
+}
+
+// withLinkTarget puts the symlink-target attribute of a file read through a link right after
+// its path.
+func withLinkTarget(view FileView, target string) FileView {
+	if target != "" {
+		view.Attrs = append([]Attr{{"symlink-target", target}}, view.Attrs...)
+	}
+	return view
+}
+
+// truncatedView is the placeholder that replaces a file cut by TruncateToBudget.
+func truncatedView(relPath string, tokens int) FileView {
+	return FileView{
+		Path:    filepath.ToSlash(relPath),
+		Content: fmt.Sprintf("[content omitted to fit the token budget: %d tokens]", tokens),
+		Omitted: true,
+		Attrs:   []Attr{{"truncated", "true"}},
+	}
+}
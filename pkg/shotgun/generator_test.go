@@ -0,0 +1,147 @@
+package shotgun
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files (relative path -> content) below root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// generate runs a Generator over opts.RootDir and returns the payload.
+func generate(t *testing.T, opts Options) string {
+	t.Helper()
+	result, err := NewGenerator(opts, nil).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return result.Output
+}
+
+func TestGenerateTruncatesPayloadOverByteCap(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"big.txt":   strings.Repeat("0123456789\n", 1000), // 11000 bytes, within budgetReadFactor times the cap
+		"small.txt": "small",
+	})
+	const maxBytes = 4000
+
+	_, err := NewGenerator(Options{RootDir: root, MaxOutputBytes: maxBytes}, nil).Generate(context.Background())
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Fatalf("without a budget: got %v, want ErrContextTooLong", err)
+	}
+
+	opts := Options{RootDir: root, MaxOutputBytes: maxBytes, TokenBudget: 500, TruncateToBudget: true}
+	result, err := NewGenerator(opts, nil).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("with a budget: %v", err)
+	}
+	if len(result.Output) > maxBytes {
+		t.Errorf("payload is %d bytes, over the cap of %d", len(result.Output), maxBytes)
+	}
+	if len(result.Truncated) != 1 || result.Truncated[0].Path != "big.txt" {
+		t.Errorf("truncated = %+v, want big.txt only", result.Truncated)
+	}
+	if !strings.Contains(result.Output, "<file path=\"small.txt\">\nsmall\n</file>") {
+		t.Errorf("small.txt is missing:\n%s", result.Output)
+	}
+}
+
+func TestGenerateEnforcesByteCapAfterBudget(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{"big.txt": strings.Repeat("x", 8000)})
+
+	// The payload fits the token budget but not the byte cap.
+	opts := Options{RootDir: root, MaxOutputBytes: 4000, TokenBudget: 1_000_000, TruncateToBudget: true}
+	_, err := NewGenerator(opts, nil).Generate(context.Background())
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Fatalf("got %v, want ErrContextTooLong", err)
+	}
+}
+
+func TestGenerateOmittedFilesAreNotRead(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"big.bin":  strings.Repeat("x", 50000),
+		"main.go":  "package main",
+		"notes.md": "notes",
+	})
+	opts := Options{
+		RootDir:        root,
+		MaxOutputBytes: 4000, // big.bin alone would exceed it
+		RenderModes:    map[string]string{"big.bin": RenderOmitted},
+	}
+	result, err := NewGenerator(opts, nil).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if want := "<file path=\"big.bin\" render=\"omitted\" size=\"50000\">\n[content omitted]\n</file>"; !strings.Contains(result.Output, want) {
+		t.Errorf("payload lacks %q:\n%s", want, result.Output)
+	}
+	for _, entry := range result.Manifest.Files {
+		if entry.Path == "big.bin" && entry.Bytes != 50000 {
+			t.Errorf("manifest bytes of big.bin = %d, want the file size", entry.Bytes)
+		}
+	}
+}
+
+func TestGenerateForceIncludeOverridesIgnoreRules(t *testing.T) {
+	isolateGitConfig(t)
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		".gitignore":                   "*.log\nnode_modules/\n",
+		"main.go":                      "package main",
+		"debug.log":                    "gitignored file",
+		"other.log":                    "gitignored file",
+		"node_modules/pkg/index.js":    "file in a gitignored folder",
+		"node_modules/pkg/README.md":   "sibling of a forced file",
+		"node_modules/other/index.js":  "unrelated package",
+		"build/out.txt":                "file in a custom ignored folder",
+		"build/tmp.txt":                "sibling of a forced file",
+		"dist/a.txt":                   "forced folder",
+		"dist/nested/b.log":            "gitignored inside a forced folder",
+		"dist/nested/excluded.txt":     "excluded inside a forced folder",
+		"dist/nested/deeper/c.txt.bak": "custom ignored inside a forced folder",
+	})
+	gitIgn, err := CompileGitignore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := generate(t, Options{
+		RootDir:       root,
+		Ignore:        Matcher{Gitignore: gitIgn, Custom: CompileRules("build/\ndist/\n*.bak")},
+		ForceInclude:  []string{"debug.log", filepath.FromSlash("node_modules/pkg/index.js"), filepath.FromSlash("build/out.txt"), "dist/"},
+		ExcludedPaths: []string{filepath.FromSlash("dist/nested/excluded.txt")},
+	})
+
+	included := []string{"main.go", "debug.log", "node_modules/pkg/index.js", "build/out.txt", "dist/a.txt", "dist/nested/b.log", "dist/nested/deeper/c.txt.bak"}
+	left := []string{"other.log", "node_modules/pkg/README.md", "node_modules/other/index.js", "build/tmp.txt", "dist/nested/excluded.txt"}
+	for _, p := range included {
+		if !strings.Contains(out, "<file path=\""+p+"\">") {
+			t.Errorf("%s is missing", p)
+		}
+	}
+	for _, p := range left {
+		if strings.Contains(out, "<file path=\""+p+"\">") {
+			t.Errorf("%s is packed", p)
+		}
+	}
+	if strings.Contains(out, "other") || strings.Contains(out, "README.md") || strings.Contains(out, "tmp.txt") {
+		t.Errorf("the tree lists paths that are not forced:\n%s", out)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build unix
+
+package shotgun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// A named pipe blocks whoever reads it until a writer shows up, so generating a payload with an
+// omitted pipe only returns if the pipe is never read.
+func TestGenerateDoesNotOpenOmittedFiles(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{"main.go": "package main"})
+	pipe := filepath.Join(root, "pipe")
+	if err := syscall.Mkfifo(pipe, 0o644); err != nil {
+		t.Skipf("named pipes are not available: %v", err)
+	}
+
+	type outcome struct {
+		result *Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		opts := Options{RootDir: root, RenderModes: map[string]string{"pipe": RenderOmitted}}
+		result, err := NewGenerator(opts, nil).Generate(context.Background())
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case got := <-done:
+		if got.err != nil {
+			t.Fatalf("Generate: %v", got.err)
+		}
+		if !strings.Contains(got.result.Output, "<file path=\"pipe\" render=\"omitted\" size=\"0\">") {
+			t.Errorf("pipe is not omitted:\n%s", got.result.Output)
+		}
+	case <-time.After(5 * time.Second):
+		// Release the blocked reader before failing.
+		if w, err := os.OpenFile(pipe, os.O_WRONLY, 0); err == nil {
+			w.Close()
+		}
+		<-done
+		t.Fatal("Generate read the omitted file")
+	}
+}
@@ -0,0 +1,436 @@
+package shotgun
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+// GitIgnore evaluates ignore rules with git's precedence. From lowest to highest priority:
+//
+//   - the user's core.excludesFile (default $XDG_CONFIG_HOME/git/ignore),
+//   - the repository's .git/info/exclude,
+//   - .gitignore files of the folders above the project root, when the project is a subfolder
+//     of a repository,
+//   - .gitignore files from the project root down to the folder of the path.
+//
+// Within a file the last matching pattern wins, "!" patterns re-include, and the deepest file
+// with a matching pattern decides. As in git, nothing inside an ignored folder can be re-included,
+// and .git folders are always ignored.
+// Nested .gitignore files are loaded lazily the first time a path below them is checked.
+type GitIgnore struct {
+	rootDir string
+	// base holds the layers that apply to every path, lowest priority first.
+	base []baseIgnoreLayer
+
+	mu         sync.Mutex
+	dirLayers  map[string]*ignoreLayer // By folder relative to rootDir; nil when the folder has no .gitignore
+	dirIgnored map[string]bool         // Memoized results for folders
+}
+
+// ignoreRule is one pattern line of an ignore file.
+type ignoreRule struct {
+	pattern *gitignore.GitIgnore // Compiled without the leading "!"
+	negate  bool
+}
+
+// ignoreLayer holds the rules of one ignore file. Its patterns are relative to the file's folder.
+type ignoreLayer struct {
+	source string
+	rules  []ignoreRule
+}
+
+// baseIgnoreLayer is a layer rooted outside the project root. prefix is the project root relative
+// to the layer's folder, with forward slashes ("" when they are the same folder).
+type baseIgnoreLayer struct {
+	*ignoreLayer
+	prefix string
+}
+
+// CompileGitignore builds the layered ignore engine for the project at rootDir. Missing ignore
+// files are not an error; unreadable ones are logged and skipped.
+func CompileGitignore(rootDir string) (*GitIgnore, error) {
+	g := &GitIgnore{
+		rootDir:    rootDir,
+		dirLayers:  make(map[string]*ignoreLayer),
+		dirIgnored: make(map[string]bool),
+	}
+
+	workTree, gitDir := findGitDir(rootDir)
+	if workTree != "" {
+		prefix := func(dir string) string {
+			rel, err := filepath.Rel(dir, rootDir)
+			if err != nil || rel == "." {
+				return ""
+			}
+			return filepath.ToSlash(rel)
+		}
+		// Linked worktrees share info/exclude and the config with the main repository.
+		commonDir := gitCommonDir(gitDir)
+		if excludesFile := coreExcludesFile(commonDir); excludesFile != "" {
+			g.addBaseLayer(excludesFile, prefix(workTree))
+		}
+		g.addBaseLayer(filepath.Join(commonDir, "info", "exclude"), prefix(workTree))
+
+		// .gitignore files between the repository root and the project root.
+		var above []string
+		for dir := filepath.Dir(rootDir); ; dir = filepath.Dir(dir) {
+			if rel, err := filepath.Rel(workTree, dir); err != nil || strings.HasPrefix(rel, "..") {
+				break
+			}
+			above = append(above, dir)
+			if dir == workTree || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+		for i := len(above) - 1; i >= 0; i-- {
+			g.addBaseLayer(filepath.Join(above[i], ".gitignore"), prefix(above[i]))
+		}
+	}
+	return g, nil
+}
+
+// RootDir returns the project root the engine was compiled for.
+func (g *GitIgnore) RootDir() string {
+	return g.rootDir
+}
+
+// Invalidate drops the cached rules of the .gitignore file at relPath (relative to the project
+// root, OS separators), so the next checks read it again, and the memoized folder verdicts,
+// which the file may change. It reports whether relPath names a .gitignore file; other paths
+// leave the cache alone.
+func (g *GitIgnore) Invalidate(relPath string) bool {
+	if g == nil || filepath.Base(relPath) != ".gitignore" {
+		return false
+	}
+	dir := filepath.ToSlash(filepath.Dir(filepath.Clean(relPath)))
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.dirLayers, dir)
+	clear(g.dirIgnored)
+	return true
+}
+
+func (g *GitIgnore) addBaseLayer(file, prefix string) {
+	if layer := loadIgnoreLayer(file); layer != nil {
+		g.base = append(g.base, baseIgnoreLayer{ignoreLayer: layer, prefix: prefix})
+	}
+}
+
+// Ignored reports whether relPath (relative to the project root, OS separators) is ignored,
+// either itself or because one of its parent folders is.
+func (g *GitIgnore) Ignored(relPath string, isDir bool) bool {
+	if g == nil {
+		return false
+	}
+	relPath = filepath.Clean(relPath)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if isDir {
+		return g.dirIgnoredLocked(relPath)
+	}
+	return g.dirIgnoredLocked(filepath.Dir(relPath)) || g.evaluateLocked(relPath, false)
+}
+
+func (g *GitIgnore) dirIgnoredLocked(relDir string) bool {
+	if relDir == "." || relDir == string(os.PathSeparator) {
+		return false
+	}
+	if ignored, ok := g.dirIgnored[relDir]; ok {
+		return ignored
+	}
+	ignored := g.dirIgnoredLocked(filepath.Dir(relDir)) || g.evaluateLocked(relDir, true)
+	g.dirIgnored[relDir] = ignored
+	return ignored
+}
+
+// evaluateLocked applies the layers to relPath alone, ignoring its parents.
+func (g *GitIgnore) evaluateLocked(relPath string, isDir bool) bool {
+	slashPath := filepath.ToSlash(relPath)
+	if isDir && path.Base(slashPath) == ".git" {
+		return true // Git never tracks its own directory
+	}
+	suffix := ""
+	if isDir {
+		suffix = "/" // So "dir/" patterns apply
+	}
+
+	// .gitignore files inside the project, deepest first.
+	for dir := path.Dir(slashPath); ; dir = path.Dir(dir) {
+		if layer := g.dirLayerLocked(dir); layer != nil {
+			p := slashPath
+			if dir != "." {
+				p = strings.TrimPrefix(slashPath, dir+"/")
+			}
+			if ignored, matched := layer.decide(p + suffix); matched {
+				return ignored
+			}
+		}
+		if dir == "." || dir == "/" {
+			break
+		}
+	}
+
+	for i := len(g.base) - 1; i >= 0; i-- {
+		layer := g.base[i]
+		p := slashPath
+		if layer.prefix != "" {
+			p = layer.prefix + "/" + slashPath
+		}
+		if ignored, matched := layer.decide(p + suffix); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// dirLayerLocked returns the .gitignore layer of a folder (slash separated, relative to the
+// root), loading it on first use.
+func (g *GitIgnore) dirLayerLocked(dir string) *ignoreLayer {
+	if layer, ok := g.dirLayers[dir]; ok {
+		return layer
+	}
+	layer := loadIgnoreLayer(filepath.Join(g.rootDir, filepath.FromSlash(dir), ".gitignore"))
+	g.dirLayers[dir] = layer
+	return layer
+}
+
+// decide returns the verdict of the last rule matching p. matched is false when no rule matches.
+func (l *ignoreLayer) decide(p string) (ignored, matched bool) {
+	for i := len(l.rules) - 1; i >= 0; i-- {
+		if l.rules[i].pattern.MatchesPath(p) {
+			return !l.rules[i].negate, true
+		}
+	}
+	return false, false
+}
+
+// loadIgnoreLayer reads an ignore file. It returns nil when the file is missing, unreadable or empty.
+func loadIgnoreLayer(file string) *ignoreLayer {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("gitignore: skipping %s: %v", file, err)
+		}
+		return nil
+	}
+	rules := compileIgnoreRules(strings.Split(string(data), "\n"))
+	if len(rules) == 0 {
+		return nil
+	}
+	return &ignoreLayer{source: file, rules: rules}
+}
+
+// compileIgnoreRules compiles gitignore lines one by one so the verdict of each pattern,
+// including negated ones, can be evaluated separately.
+func compileIgnoreRules(lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(trimmed, "!")
+		if negate {
+			trimmed = trimmed[1:]
+		}
+		// In git a slash at the start or in the middle anchors the pattern to the file's folder;
+		// the matcher only anchors patterns that start with one.
+		if body := strings.TrimSuffix(trimmed, "/"); strings.Contains(body, "/") && !strings.HasPrefix(body, "/") && !strings.HasPrefix(body, "**/") {
+			trimmed = "/" + trimmed
+		}
+		rules = append(rules, ignoreRule{pattern: gitignore.CompileIgnoreLines(trimmed), negate: negate})
+	}
+	return rules
+}
+
+// findGitDir looks for the repository containing dir. It returns the work tree and the git
+// directory, or empty strings when dir is not inside a repository.
+func findGitDir(dir string) (workTree, gitDir string) {
+	for current := dir; ; current = filepath.Dir(current) {
+		dotGit := filepath.Join(current, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return current, dotGit
+			}
+			// Worktrees and submodules have a ".git" file pointing at the real git directory.
+			if data, err := os.ReadFile(dotGit); err == nil {
+				if target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); ok {
+					target = strings.TrimSpace(target)
+					if !filepath.IsAbs(target) {
+						target = filepath.Join(current, target)
+					}
+					return current, target
+				}
+			}
+		}
+		if filepath.Dir(current) == current {
+			return "", ""
+		}
+	}
+}
+
+// gitCommonDir returns the directory holding the files a linked worktree shares with the main
+// repository, as "git rev-parse --git-common-dir" does: the target of the commondir file of a
+// worktree's git directory, or gitDir itself. It reads the file rather than running git, which
+// may not be installed.
+func gitCommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	commonDir := strings.TrimSpace(string(data))
+	if commonDir == "" {
+		return gitDir
+	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir)
+}
+
+// coreExcludesFile resolves core.excludesFile from the user's and the repository's git config,
+// falling back to git's default location.
+func coreExcludesFile(gitDir string) string {
+	home, _ := os.UserHomeDir()
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" && home != "" {
+		xdgConfig = filepath.Join(home, ".config")
+	}
+
+	// Later files override earlier ones, in git's own lookup order.
+	var configs []string
+	if xdgConfig != "" {
+		configs = append(configs, filepath.Join(xdgConfig, "git", "config"))
+	}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	configs = append(configs, filepath.Join(gitDir, "config"))
+
+	value := ""
+	for _, config := range configs {
+		if v, ok := readCoreExcludesFile(config); ok {
+			value = v
+		}
+	}
+	if value == "" {
+		if xdgConfig == "" {
+			return ""
+		}
+		return filepath.Join(xdgConfig, "git", "ignore")
+	}
+	return expandUserPath(value, home)
+}
+
+// expandUserPath expands a leading "~" or "~user" of a git config path like git does.
+func expandUserPath(value, home string) string {
+	rest, ok := strings.CutPrefix(value, "~")
+	if !ok {
+		return value
+	}
+	name, tail, _ := strings.Cut(rest, "/")
+	dir := home
+	if name != "" {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return value
+		}
+		dir = u.HomeDir
+	}
+	if dir == "" {
+		return value
+	}
+	return filepath.Join(dir, filepath.FromSlash(tail))
+}
+
+// readCoreExcludesFile extracts core.excludesFile from a git config file. Values are unquoted
+// and unescaped as git does, and may continue over several lines.
+func readCoreExcludesFile(configPath string) (string, bool) {
+	f, err := os.Open(configPath)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	section := ""
+	value, found := "", false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			// A variable may follow the header on the same line: "[core] excludesFile = x".
+			header, rest, _ := strings.Cut(line[1:], "]")
+			section = strings.ToLower(strings.TrimSpace(header))
+			if line = strings.TrimSpace(rest); line == "" {
+				continue
+			}
+		}
+		if section != "core" {
+			continue
+		}
+		key, v, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "excludesfile") {
+			continue
+		}
+		raw := v
+		for strings.HasSuffix(raw, "\\") && !strings.HasSuffix(raw, "\\\\") && scanner.Scan() {
+			raw = raw[:len(raw)-1] + scanner.Text() // Line continuation
+		}
+		value, found = parseGitConfigValue(raw), true
+	}
+	return value, found
+}
+
+// parseGitConfigValue decodes the raw value of a git config variable: double quotes group text
+// (keeping its whitespace and comment characters), backslash escapes \\ \" \n \t and \b are
+// replaced, a "#" or ";" outside quotes starts a comment, and whitespace around the value is
+// dropped.
+func parseGitConfigValue(raw string) string {
+	var sb strings.Builder
+	quoted := false
+	pendingSpace := "" // Whitespace outside quotes, kept only if more value follows
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '\\' && i+1 < len(raw):
+			i++
+			sb.WriteString(pendingSpace)
+			pendingSpace = ""
+			switch raw[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b':
+				sb.WriteByte('\b')
+			default: // \\, \" and anything git would reject
+				sb.WriteByte(raw[i])
+			}
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == '#' || c == ';'):
+			return sb.String()
+		case !quoted && (c == ' ' || c == '\t'):
+			if sb.Len() > 0 {
+				pendingSpace += string(c)
+			}
+		default:
+			sb.WriteString(pendingSpace)
+			pendingSpace = ""
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,232 @@
+package shotgun
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolateGitConfig points the user's git configuration at an empty folder, so the tests do not
+// see the core.excludesFile of the machine they run on.
+func isolateGitConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	return home
+}
+
+// checkIgnored asserts the verdict for slash-separated paths; a trailing "/" marks a folder.
+func checkIgnored(t *testing.T, g *GitIgnore, want map[string]bool) {
+	t.Helper()
+	for p, ignored := range want {
+		isDir := p[len(p)-1] == '/'
+		rel := filepath.FromSlash(p)
+		if isDir {
+			rel = filepath.FromSlash(p[:len(p)-1])
+		}
+		if got := g.Ignored(rel, isDir); got != ignored {
+			t.Errorf("Ignored(%q) = %v, want %v", p, got, ignored)
+		}
+	}
+}
+
+func TestGitIgnorePatterns(t *testing.T) {
+	isolateGitConfig(t)
+	tests := []struct {
+		name      string
+		gitignore string
+		want      map[string]bool
+	}{
+		{
+			name:      "negation",
+			gitignore: "*.log\n!keep.log\n",
+			want:      map[string]bool{"a.log": true, "sub/b.log": true, "keep.log": false, "sub/keep.log": false, "a.txt": false},
+		},
+		{
+			name:      "last pattern wins",
+			gitignore: "!keep.log\n*.log\n",
+			want:      map[string]bool{"keep.log": true},
+		},
+		{
+			name:      "no re-include inside an ignored folder",
+			gitignore: "build/\n!build/keep.txt\n",
+			want:      map[string]bool{"build/": true, "build/keep.txt": true},
+		},
+		{
+			name:      "anchored",
+			gitignore: "/todo.txt\n/docs/*.pdf\n",
+			want:      map[string]bool{"todo.txt": true, "sub/todo.txt": false, "docs/a.pdf": true, "sub/docs/a.pdf": false},
+		},
+		{
+			name:      "pattern with a slash is anchored",
+			gitignore: "docs/generated\n",
+			want:      map[string]bool{"docs/generated/": true, "docs/generated/x.md": true, "sub/docs/generated/": false},
+		},
+		{
+			name:      "leading double star is not anchored",
+			gitignore: "**/logs/debug\n",
+			want:      map[string]bool{"logs/debug": true, "a/b/logs/debug": true, "logs/info": false},
+		},
+		{
+			name:      "folder only",
+			gitignore: "cache/\n",
+			want:      map[string]bool{"cache/": true, "cache/x": true, "sub/cache/": true, "cache": false},
+		},
+		{
+			name:      "comments and escaped hash",
+			gitignore: "# comment\n\\#notes\n",
+			want:      map[string]bool{"#notes": true, "comment": false, "# comment": false},
+		},
+		{
+			name: "git folder",
+			want: map[string]bool{".git/": true, ".git/config": true, "sub/.git/": true, ".github/": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{".gitignore": tt.gitignore})
+			g, err := CompileGitignore(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkIgnored(t, g, tt.want)
+		})
+	}
+}
+
+func TestGitIgnoreNestedLayers(t *testing.T) {
+	isolateGitConfig(t)
+	repo := t.TempDir()
+	project := filepath.Join(repo, "app")
+	writeFiles(t, repo, map[string]string{
+		".git/info/exclude":   "*.secret\n",
+		".gitignore":          "*.tmp\napp/dist/\n",
+		"app/.gitignore":      "!keep.tmp\n*.out\n",
+		"app/src/.gitignore":  "*.gen.go\n!main.out\n",
+		"app/src/deep/x.go":   "",
+		"app/docs/.gitignore": "/local.md\n",
+	})
+	g, err := CompileGitignore(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIgnored(t, g, map[string]bool{
+		"a.secret":          true,  // info/exclude of the repository
+		"a.tmp":             true,  // .gitignore above the project root
+		"dist/":             true,  // Anchored pattern of the repository root, relative to the project
+		"keep.tmp":          false, // The project's .gitignore overrides the one above it
+		"a.out":             true,
+		"src/main.out":      false, // The deepest file with a matching pattern decides
+		"src/a.gen.go":      true,
+		"src/deep/b.gen.go": true,
+		"b.gen.go":          false,
+		"docs/local.md":     true, // Anchored to its own folder
+		"docs/sub/local.md": false,
+		"local.md":          false,
+	})
+}
+
+func TestGitIgnoreInvalidate(t *testing.T) {
+	isolateGitConfig(t)
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"sub/.gitignore": "*.log\n"})
+	g, err := CompileGitignore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIgnored(t, g, map[string]bool{"sub/a.log": true, "sub/gen/": false})
+
+	writeFiles(t, root, map[string]string{"sub/.gitignore": "gen/\n"})
+	checkIgnored(t, g, map[string]bool{"sub/a.log": true, "sub/gen/": false}) // Still cached
+	if g.Invalidate(filepath.Join("sub", "a.log")) {
+		t.Error("Invalidate accepted a path that is not a .gitignore file")
+	}
+	if !g.Invalidate(filepath.Join("sub", ".gitignore")) {
+		t.Fatal("Invalidate rejected sub/.gitignore")
+	}
+	checkIgnored(t, g, map[string]bool{"sub/a.log": false, "sub/gen/": true, "sub/gen/x.go": true})
+
+	if err := os.Remove(filepath.Join(root, "sub", ".gitignore")); err != nil {
+		t.Fatal(err)
+	}
+	g.Invalidate(filepath.Join("sub", ".gitignore"))
+	checkIgnored(t, g, map[string]bool{"sub/gen/": false})
+}
+
+func TestGitIgnoreWorktreeUsesCommonDir(t *testing.T) {
+	isolateGitConfig(t)
+	main := t.TempDir()
+	worktree := t.TempDir()
+	gitDir := filepath.Join(main, ".git", "worktrees", "feature")
+	writeFiles(t, main, map[string]string{
+		".git/info/exclude":                "*.secret\n",
+		".git/config":                      "[core]\n\texcludesFile = " + filepath.ToSlash(filepath.Join(main, "global-ignore")) + "\n",
+		"global-ignore":                    "*.bak\n",
+		".git/worktrees/feature/HEAD":      "ref: refs/heads/feature\n",
+		".git/worktrees/feature/commondir": "../..\n",
+	})
+	writeFiles(t, worktree, map[string]string{".git": "gitdir: " + gitDir + "\n"})
+
+	if got, want := gitCommonDir(gitDir), filepath.Join(main, ".git"); got != want {
+		t.Errorf("gitCommonDir = %q, want %q", got, want)
+	}
+	g, err := CompileGitignore(worktree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIgnored(t, g, map[string]bool{"a.secret": true, "a.bak": true, "a.txt": false})
+}
+
+func TestCoreExcludesFile(t *testing.T) {
+	home := isolateGitConfig(t)
+	gitDir := filepath.Join(t.TempDir(), ".git")
+
+	if got, want := coreExcludesFile(gitDir), filepath.Join(home, ".config", "git", "ignore"); got != want {
+		t.Errorf("default = %q, want %q", got, want)
+	}
+
+	writeFiles(t, home, map[string]string{".gitconfig": "[user]\n\tname = x\n[core]\n\texcludesFile = ~/global.ignore ; comment\n"})
+	if got, want := coreExcludesFile(gitDir), filepath.Join(home, "global.ignore"); got != want {
+		t.Errorf("home config = %q, want %q", got, want)
+	}
+
+	// The repository's config overrides the user's.
+	writeFiles(t, filepath.Dir(gitDir), map[string]string{".git/config": "[Core] ExcludesFile = \"~/my ignores/#1.txt\"\n"})
+	if got, want := coreExcludesFile(gitDir), filepath.Join(home, "my ignores", "#1.txt"); got != want {
+		t.Errorf("repository config = %q, want %q", got, want)
+	}
+}
+
+func TestParseGitConfigValue(t *testing.T) {
+	for raw, want := range map[string]string{
+		" plain ":                   "plain",
+		" ~/.gitignore_global":      "~/.gitignore_global",
+		` "quoted value" `:          "quoted value",
+		` "C:\\Users\\me\\ignore" `: `C:\Users\me\ignore`,
+		` a b  c `:                  "a b  c",
+		` value # comment`:          "value",
+		` value ; comment`:          "value",
+		` "a # b" ; comment`:        "a # b",
+		` half" quoted "value`:      "half quoted value",
+		` tab\there`:                "tab\there",
+		` escaped \" quote`:         `escaped " quote`,
+		``:                          "",
+	} {
+		if got := parseGitConfigValue(raw); got != want {
+			t.Errorf("parseGitConfigValue(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestReadCoreExcludesFileContinuation(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config")
+	writeFiles(t, dir, map[string]string{"config": "[core]\n\texcludesfile = /very/long/\\\npath/ignore\n[other]\n\texcludesfile = /not/this\n"})
+	got, ok := readCoreExcludesFile(config)
+	if !ok || got != "/very/long/path/ignore" {
+		t.Errorf("readCoreExcludesFile = %q, %v", got, ok)
+	}
+}
@@ -0,0 +1,48 @@
+package shotgun
+
+import (
+	"os"
+	"strings"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+// Matcher combines the project's git ignore rules with the user's custom ignore rules (ignore.glob).
+// Either side may be nil, in which case it never matches.
+type Matcher struct {
+	Gitignore *GitIgnore
+	Custom    *gitignore.GitIgnore
+}
+
+// CompileRules compiles gitignore-syntax rules given as a single string, one pattern per line.
+// It returns nil when rules is blank.
+func CompileRules(rules string) *gitignore.GitIgnore {
+	if strings.TrimSpace(rules) == "" {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(rules, "\r\n", "\n"), "\n")
+	return gitignore.CompileIgnoreLines(lines...)
+}
+
+// Match reports whether relPath (relative to the project root, OS separators) is matched by
+// the .gitignore rules and by the custom rules respectively.
+func (m Matcher) Match(relPath string, isDir bool) (gitignored, customIgnored bool) {
+	gitignored = m.Gitignore.Ignored(relPath, isDir)
+
+	// Custom rules are relative to the project root; directories need a trailing separator
+	// so "dir/" patterns apply.
+	pathToMatch := relPath
+	if isDir && !strings.HasSuffix(pathToMatch, string(os.PathSeparator)) {
+		pathToMatch += string(os.PathSeparator)
+	}
+	if m.Custom != nil {
+		customIgnored = m.Custom.MatchesPath(pathToMatch)
+	}
+	return gitignored, customIgnored
+}
+
+// Ignored reports whether relPath is matched by any of the rules.
+func (m Matcher) Ignored(relPath string, isDir bool) bool {
+	gitignored, customIgnored := m.Match(relPath, isDir)
+	return gitignored || customIgnored
+}
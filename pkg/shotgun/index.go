@@ -0,0 +1,229 @@
+package shotgun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// DefaultIndexChunkEntries is the number of entries IndexTree batches per chunk when chunkEntries is zero.
+const DefaultIndexChunkEntries = 2000
+
+// DirectoryPage is a page of the children of a folder, listed by ListDirectory.
+type DirectoryPage struct {
+	Folder *FileNode `json:"folder"` // The folder with the children of the page
+	Offset int       `json:"offset"` // Index of the first child of the page
+	Total  int       `json:"total"`  // Number of children of the folder
+}
+
+// ListDirectory lists a single folder of the project (relDir is relative to rootDir, "." for the
+// root) and returns its node with the sorted direct children from offset on, at most limit of
+// them (all of them when limit is 0). Sub-folders are not descended into; their ChildCount, the
+// number of their entries that the rules switched on in toggles do not exclude, tells the UI
+// whether there is anything to expand. symlinks is the symbolic link policy, see Options.Symlinks.
+func ListDirectory(rootDir, relDir string, m Matcher, toggles IgnoreToggles, symlinks string, offset, limit int) (*DirectoryPage, error) {
+	relDir = filepath.Clean(filepath.FromSlash(relDir))
+	if !filepath.IsLocal(relDir) && relDir != "." {
+		return nil, fmt.Errorf("folder %q is outside the project", relDir)
+	}
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+	if err := ValidateSymlinkPolicy(symlinks); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(rootDir, relDir)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", relDir)
+	}
+
+	node := &FileNode{Name: filepath.Base(path), Path: path, RelPath: relDir, IsDir: true}
+	if relDir != "." {
+		node.IsGitignored, node.IsCustomIgnored = m.Match(relDir, true)
+	}
+	page := &DirectoryPage{Folder: node, Offset: offset}
+	resolver, dir := newLinkResolver(rootDir, symlinks)
+	if relDir != "." {
+		dir = resolver.descend(dir, relDir)
+	}
+	children, _, err := listFolder(resolver, dir, rootDir, node, m, customIgnoredBelow(relDir, m))
+	if err != nil {
+		return page, err
+	}
+	page.Total = len(children)
+	children = children[min(offset, len(children)):]
+	if limit > 0 && limit < len(children) {
+		children = children[:limit]
+	}
+	for _, child := range children {
+		if child.IsDir {
+			child.ChildCount = countEntries(child.Path, child.RelPath, m, toggles, child.IsCustomIgnored)
+		}
+	}
+	node.Children = children
+	return page, nil
+}
+
+// TreeChunk is a batch of folder listings streamed by IndexTree. Every folder carries its direct
+// children; the contents of sub-folders follow in later chunks.
+type TreeChunk struct {
+	Folders []*FileNode `json:"folders"`
+	Entries int         `json:"entries"` // Entries indexed so far, including this chunk
+}
+
+// IgnoreToggles tells which ignore rules are switched on, like the "use .gitignore" and "use
+// custom rules" switches of the tree. Rules that are switched off still flag entries.
+type IgnoreToggles struct {
+	Gitignore bool
+	Custom    bool
+}
+
+// IndexTree lists rootDir breadth-first and passes the listings to emit in chunks of about
+// chunkEntries entries, so the top levels of the project arrive first. Entries are flagged by
+// all rules of m, but only folders matched by the rules switched on in toggles are not descended
+// into (their ChildCount is set instead); ListDirectory lists them on demand. Symbolic links are
+// handled according to symlinks, see Options.Symlinks. It returns the number of indexed entries.
+func IndexTree(ctx context.Context, rootDir string, m Matcher, toggles IgnoreToggles, symlinks string, chunkEntries int, emit func(TreeChunk)) (int, error) {
+	if chunkEntries <= 0 {
+		chunkEntries = DefaultIndexChunkEntries
+	}
+	if err := ValidateSymlinkPolicy(symlinks); err != nil {
+		return 0, err
+	}
+
+	type pending struct {
+		node          *FileNode
+		dir           walkDir
+		customIgnored bool // An ancestor is matched by the custom rules
+	}
+	resolver, root := newLinkResolver(rootDir, symlinks)
+	queue := []pending{{node: &FileNode{Name: filepath.Base(rootDir), Path: rootDir, RelPath: ".", IsDir: true}, dir: root}}
+
+	var chunk TreeChunk
+	entries, chunkSize := 0, 0
+	flush := func() {
+		if len(chunk.Folders) == 0 {
+			return
+		}
+		chunk.Entries = entries
+		emit(chunk)
+		chunk = TreeChunk{}
+		chunkSize = 0
+	}
+
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
+		current := queue[0]
+		queue = queue[1:]
+
+		children, dirs, err := listFolder(resolver, current.dir, rootDir, current.node, m, current.customIgnored)
+		if err != nil {
+			log.Printf("index: error reading dir %s: %v", current.node.Path, err)
+			continue
+		}
+		folder := *current.node
+		folder.Children = children
+		chunk.Folders = append(chunk.Folders, &folder)
+		entries += len(children)
+		chunkSize += len(children) + 1
+
+		for i, child := range children {
+			if !child.IsDir {
+				continue
+			}
+			if (toggles.Gitignore && child.IsGitignored) || (toggles.Custom && child.IsCustomIgnored) {
+				child.ChildCount = countEntries(child.Path, child.RelPath, m, toggles, child.IsCustomIgnored)
+				continue
+			}
+			queue = append(queue, pending{node: child, dir: dirs[i], customIgnored: current.customIgnored})
+		}
+		if chunkSize >= chunkEntries {
+			flush()
+		}
+	}
+	flush()
+	return entries, nil
+}
+
+// listFolder returns the sorted, flagged children of folder, listed from dir. customIgnored marks
+// every child as matched by the custom rules, for folders inside a custom-ignored one. dirs holds
+// the walkDir of every folder child at the same index (the zero value for files).
+func listFolder(resolver *linkResolver, dir walkDir, rootDir string, folder *FileNode, m Matcher, customIgnored bool) (nodes []*FileNode, dirs []walkDir, err error) {
+	entries, err := resolver.readDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes = make([]*FileNode, 0, len(entries))
+	dirs = make([]walkDir, 0, len(entries))
+	for _, entry := range entries {
+		relPath, _ := filepath.Rel(rootDir, entry.path)
+		isGitignored, isCustomIgnored := m.Match(relPath, entry.isDir)
+		node := &FileNode{
+			Name:            entry.name,
+			Path:            entry.path,
+			RelPath:         relPath,
+			IsDir:           entry.isDir,
+			IsGitignored:    isGitignored,
+			IsCustomIgnored: isCustomIgnored || customIgnored || folder.IsCustomIgnored,
+		}
+		if entry.symlink {
+			node.SymlinkTarget = entry.target
+		}
+		var sub walkDir
+		if node.IsDir {
+			sub = resolver.enter(dir, entry)
+		}
+		nodes = append(nodes, node)
+		dirs = append(dirs, sub)
+	}
+	return nodes, dirs, nil
+}
+
+// customIgnoredBelow reports whether a parent folder of relDir is matched by the custom rules,
+// which, unlike git rules, are not evaluated against parents by Matcher.
+func customIgnoredBelow(relDir string, m Matcher) bool {
+	for dir := filepath.Dir(relDir); dir != "." && dir != string(os.PathSeparator); dir = filepath.Dir(dir) {
+		if _, customIgnored := m.Match(dir, true); customIgnored {
+			return true
+		}
+	}
+	return false
+}
+
+// countEntries returns the number of entries of the folder at path (relPath relative to the
+// project root) that the rules switched on in toggles do not exclude, without sorting or
+// stat-ing them. customIgnored tells that the folder itself is matched by the custom rules,
+// which then cover every entry in it, as in listFolder.
+func countEntries(path, relPath string, m Matcher, toggles IgnoreToggles, customIgnored bool) int {
+	if toggles.Custom && customIgnored {
+		return 0
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	count := 0
+	for {
+		entries, err := f.ReadDir(1024)
+		for _, entry := range entries {
+			isGitignored, isCustomIgnored := m.Match(filepath.Join(relPath, entry.Name()), entry.IsDir())
+			if (toggles.Gitignore && isGitignored) || (toggles.Custom && isCustomIgnored) {
+				continue
+			}
+			count++
+		}
+		if err != nil { // io.EOF once every entry was read
+			return count
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package shotgun
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexTreeIgnoreToggles(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		".gitignore":         "vendor/\n",
+		"main.go":            "package main",
+		"vendor/lib/lib.go":  "package lib",
+		"build/out/app.txt":  "app",
+		"src/build/keep.txt": "nested folders match too",
+	})
+	gitIgn, err := CompileGitignore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Matcher{Gitignore: gitIgn, Custom: CompileRules("build/")}
+
+	tests := []struct {
+		name    string
+		toggles IgnoreToggles
+		listed  map[string]bool // Folders listed by the index
+	}{
+		{"all rules on", IgnoreToggles{Gitignore: true, Custom: true}, map[string]bool{"vendor": false, "build": false, "src/build": false}},
+		{"gitignore off", IgnoreToggles{Custom: true}, map[string]bool{"vendor": true, "vendor/lib": true, "build": false}},
+		{"custom off", IgnoreToggles{Gitignore: true}, map[string]bool{"vendor": false, "build": true, "build/out": true, "src/build": true}},
+		{"all rules off", IgnoreToggles{}, map[string]bool{"vendor": true, "build": true, "src/build": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed := make(map[string]*FileNode)
+			flagged := make(map[string]*FileNode)
+			_, err := IndexTree(context.Background(), root, m, tt.toggles, SymlinkShow, 0, func(chunk TreeChunk) {
+				for _, folder := range chunk.Folders {
+					listed[filepath.ToSlash(folder.RelPath)] = folder
+					for _, child := range folder.Children {
+						flagged[filepath.ToSlash(child.RelPath)] = child
+					}
+				}
+			})
+			if err != nil {
+				t.Fatalf("IndexTree: %v", err)
+			}
+			for folder, want := range tt.listed {
+				if _, got := listed[folder]; got != want {
+					t.Errorf("%s listed = %v, want %v", folder, got, want)
+				}
+			}
+			// Switched-off rules still flag the entries.
+			if vendor := flagged["vendor"]; vendor == nil || !vendor.IsGitignored {
+				t.Errorf("vendor is not flagged as git-ignored: %+v", vendor)
+			}
+			if build := flagged["build"]; build == nil || !build.IsCustomIgnored {
+				t.Errorf("build is not flagged as custom-ignored: %+v", build)
+			}
+		})
+	}
+}
+
+func TestListDirectoryPages(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c", "d.txt": "d", "e.txt": "e"})
+
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 0, []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"}},
+		{0, 2, []string{"a.txt", "b.txt"}},
+		{2, 2, []string{"c.txt", "d.txt"}},
+		{4, 2, []string{"e.txt"}},
+		{9, 2, nil},
+	}
+	for _, tt := range tests {
+		page, err := ListDirectory(root, ".", Matcher{}, IgnoreToggles{}, SymlinkShow, tt.offset, tt.limit)
+		if err != nil {
+			t.Fatalf("ListDirectory(%d, %d): %v", tt.offset, tt.limit, err)
+		}
+		var names []string
+		for _, child := range page.Folder.Children {
+			names = append(names, child.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) || page.Total != 5 || page.Offset != tt.offset {
+			t.Errorf("ListDirectory(%d, %d) = %v, total %d, offset %d; want %v, total 5", tt.offset, tt.limit, names, page.Total, page.Offset, tt.want)
+		}
+	}
+	if _, err := ListDirectory(root, ".", Matcher{}, IgnoreToggles{}, SymlinkShow, -1, 0); err == nil {
+		t.Errorf("a negative offset was accepted")
+	}
+}
+
+func TestListDirectoryCountsWhatTheRulesLeave(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		".gitignore":      "*.log\n",
+		"src/main.go":     "package main",
+		"src/debug.log":   "log",
+		"src/notes.tmp":   "tmp",
+		"tmp/cache/a.txt": "a",
+		"tmp/scratch.txt": "scratch",
+	})
+	gitIgn, err := CompileGitignore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Matcher{Gitignore: gitIgn, Custom: CompileRules("*.tmp\ntmp/")}
+
+	tests := []struct {
+		name    string
+		toggles IgnoreToggles
+		want    map[string]int
+	}{
+		{"all rules on", IgnoreToggles{Gitignore: true, Custom: true}, map[string]int{"src": 1, "tmp": 0}},
+		{"gitignore off", IgnoreToggles{Custom: true}, map[string]int{"src": 2, "tmp": 0}},
+		{"custom off", IgnoreToggles{Gitignore: true}, map[string]int{"src": 2, "tmp": 2}},
+		{"all rules off", IgnoreToggles{}, map[string]int{"src": 3, "tmp": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := ListDirectory(root, ".", m, tt.toggles, SymlinkShow, 0, 0)
+			if err != nil {
+				t.Fatalf("ListDirectory: %v", err)
+			}
+			got := make(map[string]int)
+			for _, child := range page.Folder.Children {
+				if child.IsDir {
+					got[child.Name] = child.ChildCount
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("child counts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package shotgun
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestEntry describes one file included in the payload.
+type ManifestEntry struct {
+	Path      string `json:"path"`      // Relative path with forward slashes, as in the <file> tag
+	Bytes     int    `json:"bytes"`     // Size of the file content
+	Lines     int    `json:"lines"`     // Line count of the file content
+	Tokens    int    `json:"tokens"`    // Estimated tokens of the whole <file> block
+	Truncated bool   `json:"truncated"` // True if the content was cut to fit the token budget
+	Binary    bool   `json:"binary"`    // True if the content was replaced by a binary placeholder
+	// Encoding is the source encoding of non-UTF-8 text ("utf-16le", "utf-16be", "latin-1"), empty for UTF-8.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Manifest is the per-file breakdown of a generated payload, in payload order.
+type Manifest struct {
+	RootDir     string          `json:"rootDir"`
+	Files       []ManifestEntry `json:"files"`
+	TreeTokens  int             `json:"treeTokens"`  // Estimated tokens of the ASCII tree
+	TotalBytes  int             `json:"totalBytes"`  // Sum of Files[].Bytes
+	TotalTokens int             `json:"totalTokens"` // TreeTokens plus the tokens of every file block and section
+	// Sections lists the extra sections appended after the files, see Options.Sections.
+	Sections []SectionEntry `json:"sections,omitempty"`
+}
+
+// Heaviest returns up to n entries ordered by token cost, largest first. n <= 0 returns all.
+func (m Manifest) Heaviest(n int) []ManifestEntry {
+	sorted := append([]ManifestEntry(nil), m.Files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tokens > sorted[j].Tokens
+	})
+	if n > 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// buildManifest measures the tree, every file block and every section with the configured token counter.
+func (g *Generator) buildManifest(tree string, files []fileBlock, sections []sectionBlock) Manifest {
+	counter := g.opts.TokenCounter
+	manifest := Manifest{
+		RootDir:    g.opts.RootDir,
+		Files:      make([]ManifestEntry, len(files)),
+		TreeTokens: counter.CountTokens(tree),
+	}
+	manifest.TotalTokens = manifest.TreeTokens
+	for i, f := range files {
+		entry := ManifestEntry{
+			Path:     filepath.ToSlash(f.relPath),
+			Bytes:    f.bytes,
+			Lines:    f.lines,
+			Tokens:   counter.CountTokens(f.block),
+			Binary:   f.binary,
+			Encoding: f.encoding,
+		}
+		manifest.Files[i] = entry
+		manifest.TotalBytes += entry.Bytes
+		manifest.TotalTokens += entry.Tokens
+	}
+	for _, s := range sections {
+		entry := SectionEntry{Name: s.name, Bytes: s.bytes, Tokens: counter.CountTokens(s.block)}
+		manifest.Sections = append(manifest.Sections, entry)
+		manifest.TotalTokens += entry.Tokens
+	}
+	return manifest
+}
+
+// countLines counts lines the way editors do: a trailing newline does not start a new line.
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+	lines := bytes.Count(content, []byte{'\n'})
+	if content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
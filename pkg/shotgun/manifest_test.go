@@ -0,0 +1,117 @@
+package shotgun
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// byteCounter counts one token per byte, so the manifest's token figures are block sizes.
+var byteCounter = TokenCounterFunc(func(text string) int { return len(text) })
+
+func TestManifest(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"a.txt":     "one\ntwo\n",
+		"b/c.go":    "package c",
+		"empty.txt": "",
+		"logo.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"utf16.txt": "\xff\xfeh\x00i\x00\n\x00",
+	})
+	section := func(ctx context.Context, rootDir string, paths []string) (Section, error) {
+		return Section{Name: "notes", Content: strings.Join(paths, ",")}, nil
+	}
+	opts := Options{RootDir: root, TokenCounter: byteCounter, Sections: []SectionFunc{section}}
+	result, err := NewGenerator(opts, nil).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	m := result.Manifest
+
+	type entry struct {
+		path   string
+		bytes  int
+		lines  int
+		binary bool
+		enc    string
+	}
+	var got []entry
+	for _, e := range m.Files {
+		got = append(got, entry{e.Path, e.Bytes, e.Lines, e.Binary, e.Encoding})
+	}
+	want := []entry{
+		{"b/c.go", 9, 1, false, ""},
+		{"a.txt", 8, 2, false, ""},
+		{"empty.txt", 0, 0, false, ""},
+		{"logo.png", 16, 0, true, ""},
+		{"utf16.txt", 8, 0, false, "utf-16le"}, // Left undecoded, like binary files
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Every file block and section is counted once, on top of the tree.
+	tokens, bytes := m.TreeTokens, 0
+	for _, e := range m.Files {
+		if e.Tokens <= e.Bytes || e.Truncated {
+			t.Errorf("%s costs %d tokens for %d bytes", e.Path, e.Tokens, e.Bytes)
+		}
+		tokens += e.Tokens
+		bytes += e.Bytes
+	}
+	if len(m.Sections) != 1 || m.Sections[0].Name != "notes" || m.Sections[0].Bytes != len("b/c.go,a.txt,empty.txt,logo.png,utf16.txt") {
+		t.Fatalf("sections = %+v", m.Sections)
+	}
+	tokens += m.Sections[0].Tokens
+	if m.RootDir != root || m.TotalBytes != bytes || m.TotalTokens != tokens || result.Tokens != tokens {
+		t.Errorf("totals = %d bytes, %d tokens (result %d); want %d bytes, %d tokens", m.TotalBytes, m.TotalTokens, result.Tokens, bytes, tokens)
+	}
+	// With a byte counter the figures add up to the payload, give or take the separators.
+	if diff := len(result.Output) - m.TotalTokens; diff < 0 || diff > 2 {
+		t.Errorf("payload is %d bytes, manifest counts %d", len(result.Output), m.TotalTokens)
+	}
+
+	// Re-encoded text is measured in bytes of the source file but lines of the decoded text.
+	opts.ReencodeText = true
+	result, err = NewGenerator(opts, nil).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if e := result.Manifest.Files[4]; e.Path != "utf16.txt" || e.Bytes != 8 || e.Lines != 1 || e.Encoding != "utf-16le" {
+		t.Errorf("re-encoded entry = %+v", e)
+	}
+}
+
+func TestManifestHeaviest(t *testing.T) {
+	m := Manifest{Files: []ManifestEntry{
+		{Path: "a", Tokens: 10},
+		{Path: "b", Tokens: 30},
+		{Path: "c", Tokens: 10},
+		{Path: "d", Tokens: 20},
+	}}
+	paths := func(entries []ManifestEntry) string {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Path)
+		}
+		return strings.Join(names, "")
+	}
+	for n, want := range map[int]string{0: "bdac", -1: "bdac", 2: "bd", 4: "bdac", 10: "bdac"} {
+		if got := paths(m.Heaviest(n)); got != want {
+			t.Errorf("Heaviest(%d) = %s, want %s", n, got, want)
+		}
+	}
+	if paths(m.Files) != "abcd" {
+		t.Errorf("Heaviest reordered the manifest: %s", paths(m.Files))
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	for content, want := range map[string]int{"": 0, "x": 1, "x\n": 1, "\n": 1, "x\ny": 2, "x\ny\n": 2, "\n\n": 2, "x\r\ny\r\n": 2} {
+		if got := countLines([]byte(content)); got != want {
+			t.Errorf("countLines(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
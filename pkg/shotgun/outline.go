@@ -0,0 +1,257 @@
+package shotgun
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Render modes of Options.RenderModes.
+const (
+	// RenderFull packs the whole file (the default).
+	RenderFull = "full"
+	// RenderOutline packs declarations and signatures with function bodies elided.
+	RenderOutline = "outline"
+	// RenderOmitted lists the file in the tree and packs a placeholder instead of its content.
+	RenderOmitted = "omitted"
+)
+
+// elidedBody replaces function bodies in outlines.
+const elidedBody = "{ ... }"
+
+// RenderModes returns the accepted render modes.
+func RenderModes() []string {
+	return []string{RenderFull, RenderOutline, RenderOmitted}
+}
+
+// ValidateRenderMode returns an error if mode is not a known render mode. Empty means RenderFull.
+func ValidateRenderMode(mode string) error {
+	if mode == "" || slices.Contains(RenderModes(), mode) {
+		return nil
+	}
+	return fmt.Errorf("unknown render mode %q (expected one of %s)", mode, strings.Join(RenderModes(), ", "))
+}
+
+// braceLanguage describes a C-like language for the heuristic outliner.
+type braceLanguage struct {
+	// charQuotes is false where ' does not always open a literal, e.g. Rust lifetimes; only
+	// complete character literals such as '{' are skipped there.
+	charQuotes bool
+	// templates is true where backticks delimit strings.
+	templates bool
+}
+
+var braceLanguages = map[string]braceLanguage{
+	".js": {true, true}, ".jsx": {true, true}, ".mjs": {true, true}, ".cjs": {true, true},
+	".ts": {true, true}, ".tsx": {true, true}, ".mts": {true, true}, ".cts": {true, true},
+	".java": {true, false}, ".kt": {true, false}, ".kts": {true, false}, ".scala": {true, false},
+	".c": {true, false}, ".h": {true, false}, ".cc": {true, false}, ".cpp": {true, false},
+	".cxx": {true, false}, ".hpp": {true, false}, ".hh": {true, false}, ".m": {true, false},
+	".cs": {true, false}, ".swift": {false, false}, ".dart": {true, false}, ".php": {true, false},
+	".rs": {false, false}, ".groovy": {true, false},
+}
+
+// controlKeyword matches headers of blocks that are not function bodies.
+var controlKeyword = regexp.MustCompile(`^(?:if|else|for|foreach|while|do|switch|case|default|catch|try|finally|with|using|lock|fixed|unsafe|synchronized|return|match|loop|when)\b`)
+
+// Outline returns the outline of a source file: its declarations and signatures with function
+// bodies replaced by "{ ... }". Go files are outlined with go/ast; other C-like languages with a
+// brace matcher that skips strings and comments. ok is false for unsupported languages and
+// sources that do not parse or whose braces do not balance.
+func Outline(relPath string, src []byte) (outline string, ok bool) {
+	ext := strings.ToLower(path.Ext(relPath))
+	if ext == ".go" {
+		return outlineGo(src)
+	}
+	if lang, known := braceLanguages[ext]; known {
+		return outlineBraces(string(src), lang)
+	}
+	return "", false
+}
+
+// outlineGo elides the bodies of functions and methods, keeping everything else verbatim.
+func outlineGo(src []byte) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", false
+	}
+	tf := fset.File(file.Package)
+	var sb strings.Builder
+	last := 0
+	for _, decl := range file.Decls {
+		fn, isFunc := decl.(*ast.FuncDecl)
+		if !isFunc || fn.Body == nil {
+			continue
+		}
+		start, end := tf.Offset(fn.Body.Lbrace), tf.Offset(fn.Body.Rbrace)+1
+		sb.Write(src[last:start])
+		sb.WriteString(elidedBody)
+		last = end
+	}
+	sb.Write(src[last:])
+	return sb.String(), true
+}
+
+// outlineBraces elides every brace block whose header looks like a function signature: it ends
+// in a parameter list (possibly followed by a return type or "=>") and does not start with a
+// control keyword. Other blocks, such as classes and namespaces, are kept and searched further.
+func outlineBraces(src string, lang braceLanguage) (string, bool) {
+	var sb strings.Builder
+	headerStart := 0 // Start of the text since the last ; { or } at the current level
+	parens := 0      // Braces inside parentheses belong to the header, e.g. "f(opts = {}) {"
+	last := 0
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			i = skipComment(src, i)
+		case c == '"' || (c == '\'' && lang.charQuotes) || (c == '`' && lang.templates):
+			i = skipString(src, i)
+		case c == '\'':
+			i = skipCharLiteral(src, i)
+		case c == '(':
+			parens++
+		case c == ')':
+			parens = max(parens-1, 0)
+		case parens > 0:
+		case c == ';' || c == '}':
+			headerStart = i + 1
+		case c == '{':
+			if !looksLikeFunction(src[headerStart:i]) {
+				headerStart = i + 1
+				continue
+			}
+			end := matchBrace(src, i, lang)
+			if end < 0 {
+				return "", false
+			}
+			sb.WriteString(src[last:i])
+			sb.WriteString(elidedBody)
+			last = end + 1
+			i = end
+			headerStart = end + 1
+		}
+	}
+	sb.WriteString(src[last:])
+	return sb.String(), true
+}
+
+// looksLikeFunction reports whether the text before a "{" is a function signature.
+func looksLikeFunction(header string) bool {
+	header = strings.TrimSpace(stripComments(header))
+	closing := strings.LastIndexByte(header, ')')
+	if closing < 0 || strings.IndexByte(header, '(') < 0 {
+		return false
+	}
+	// Only a return type, arrow or clause may follow the parameters, e.g. ": Promise<void>",
+	// "=>", "-> Result<T>", "throws IOException", "const", "where T: Clone".
+	if strings.ContainsAny(header[closing+1:], "(;") {
+		return false
+	}
+	return !controlKeyword.MatchString(lastLine(header))
+}
+
+// lastLine returns the last line of the header, where "} else if (x)" style headers start.
+func lastLine(header string) string {
+	if i := strings.LastIndexByte(header, '\n'); i >= 0 {
+		header = header[i+1:]
+	}
+	return strings.TrimSpace(header)
+}
+
+// stripComments removes comments from a header so that commented-out code does not count.
+func stripComments(header string) string {
+	var sb strings.Builder
+	for i := 0; i < len(header); i++ {
+		if header[i] == '/' && i+1 < len(header) && (header[i+1] == '/' || header[i+1] == '*') {
+			i = skipComment(header, i)
+			sb.WriteByte(' ')
+			continue
+		}
+		sb.WriteByte(header[i])
+	}
+	return sb.String()
+}
+
+// matchBrace returns the index of the brace closing the one at open, or -1.
+func matchBrace(src string, open int, lang braceLanguage) int {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			i = skipComment(src, i)
+		case c == '"' || (c == '\'' && lang.charQuotes) || (c == '`' && lang.templates):
+			i = skipString(src, i)
+		case c == '\'':
+			i = skipCharLiteral(src, i)
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// skipComment returns the index of the last byte of the comment starting at i.
+func skipComment(src string, i int) int {
+	if src[i+1] == '/' {
+		if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+			return i + end - 1 // Leave the newline to the caller
+		}
+		return len(src) - 1
+	}
+	if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+		return i + 2 + end + 1
+	}
+	return len(src) - 1
+}
+
+// skipCharLiteral returns the index of the quote closing a character literal such as '{' or
+// '\n' starting at i, or i itself for a lifetime or label such as 'a.
+func skipCharLiteral(src string, i int) int {
+	j := i + 1
+	if j >= len(src) {
+		return i
+	}
+	if src[j] == '\\' {
+		// Escapes run up to the closing quote: '\'', '\n', '\x7f', '\u{1F600}'.
+		if end := strings.IndexByte(src[min(j+2, len(src)):min(j+12, len(src))], '\''); end >= 0 {
+			return j + 2 + end
+		}
+		return i
+	}
+	_, size := utf8.DecodeRuneInString(src[j:])
+	if j+size < len(src) && src[j+size] == '\'' && src[j] != '\n' {
+		return j + size
+	}
+	return i
+}
+
+// skipString returns the index of the quote closing the literal starting at i. Unterminated
+// character and string literals end at the line break.
+func skipString(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j
+		case '\n':
+			if quote != '`' {
+				return j - 1
+			}
+		}
+	}
+	return len(src) - 1
+}
@@ -0,0 +1,107 @@
+package shotgun
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutlineGo(t *testing.T) {
+	src := "// Package p does things.\npackage p\n\nimport \"fmt\"\n\n" +
+		"// T is a type.\ntype T struct{ n int }\n\n" +
+		"// Print prints t.\nfunc (t T) Print() {\n\tfmt.Println(t.n)\n}\n\n" +
+		"func Map[K comparable, V any](m map[K]V, f func(V) V) map[K]V {\n\tfor k, v := range m {\n\t\tm[k] = f(v)\n\t}\n\treturn m\n}\n\n" +
+		"var handler = func() { println(\"kept\") }\n\n" +
+		"//go:linkname nanotime runtime.nanotime\nfunc nanotime() int64\n"
+	want := "// Package p does things.\npackage p\n\nimport \"fmt\"\n\n" +
+		"// T is a type.\ntype T struct{ n int }\n\n" +
+		"// Print prints t.\nfunc (t T) Print() { ... }\n\n" +
+		"func Map[K comparable, V any](m map[K]V, f func(V) V) map[K]V { ... }\n\n" +
+		"var handler = func() { println(\"kept\") }\n\n" +
+		"//go:linkname nanotime runtime.nanotime\nfunc nanotime() int64\n"
+	got, ok := Outline("p/p.go", []byte(src))
+	if !ok || got != want {
+		t.Errorf("Outline = %v\n%s\nwant\n%s", ok, got, want)
+	}
+	if _, ok := Outline("broken.go", []byte("package p\n\nfunc f( {\n")); ok {
+		t.Errorf("an unparsable Go file was outlined")
+	}
+}
+
+func TestOutlineBraces(t *testing.T) {
+	tests := []struct {
+		name, path, src, want string
+	}{
+		{
+			"typescript class and functions",
+			"web/api.ts",
+			"export class Api {\n  private base = '{';\n  async get(path: string): Promise<Response> {\n    return fetch(`${this.base}${path}`);\n  }\n}\n\nexport function join(a: string, b = {}) {\n  return a + b;\n}\n\nconst add = (a: number, b: number) => {\n  return a + b;\n};\n",
+			"export class Api {\n  private base = '{';\n  async get(path: string): Promise<Response> { ... }\n}\n\nexport function join(a: string, b = {}) { ... }\n\nconst add = (a: number, b: number) => { ... };\n",
+		},
+		{
+			"control blocks are not functions",
+			"Main.java",
+			"class Main {\n  void run() {\n    if (x) { y(); }\n  }\n  static {\n    // init() {\n    init();\n  }\n}\n",
+			"class Main {\n  void run() { ... }\n  static {\n    // init() {\n    init();\n  }\n}\n",
+		},
+		{
+			"strings and comments hide braces",
+			"main.c",
+			"/* } */\nint main(void) {\n  char *s = \"}\";\n  char c = '}';\n  return 0; // }\n}\n",
+			"/* } */\nint main(void) { ... }\n",
+		},
+		{
+			"rust lifetimes are not quotes",
+			"lib.rs",
+			"impl<'a> Parser<'a> {\n    fn next(&mut self) -> Option<&'a str> where Self: Sized {\n        'outer: loop { break 'outer; }\n        self.rest.split('{').find(|s| s.ends_with('\\'') || s.contains('}'))\n    }\n}\n",
+			"impl<'a> Parser<'a> {\n    fn next(&mut self) -> Option<&'a str> where Self: Sized { ... }\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Outline(tt.path, []byte(tt.src))
+			if !ok || got != tt.want {
+				t.Errorf("Outline = %v\n%s\nwant\n%s", ok, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutlineUnsupported(t *testing.T) {
+	for path, src := range map[string]string{
+		"notes.md":   "# Notes\n",
+		"script.py":  "def f():\n    pass\n",
+		"broken.ts":  "function f() {\n  return 1;\n",
+		"Makefile":   "all:\n\tgo build\n",
+		"weird.java": "void f() { \"unterminated }\n",
+	} {
+		if got, ok := Outline(path, []byte(src)); ok {
+			t.Errorf("Outline(%s) = %q, want it unsupported", path, got)
+		}
+	}
+}
+
+func TestGenerateRenderModes(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.go":      "package a\n\nfunc A() {\n\treturn\n}\n",
+		"notes.txt": "cannot be outlined",
+		"big.bin":   "omitted, never read",
+	})
+	out := generate(t, Options{RootDir: root, RenderModes: map[string]string{
+		"a.go":      RenderOutline,
+		"notes.txt": RenderOutline,
+		"big.bin":   RenderOmitted,
+	}})
+	for _, want := range []string{
+		"<file path=\"a.go\" render=\"outline\">\npackage a\n\nfunc A() { ... }\n\n</file>",
+		"<file path=\"notes.txt\">\ncannot be outlined\n</file>",
+		"<file path=\"big.bin\" render=\"omitted\" size=\"19\">\n[content omitted]\n</file>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks\n%s\nin\n%s", want, out)
+		}
+	}
+	if err := ValidateRenderMode("summary"); err == nil {
+		t.Errorf("an unknown render mode was accepted")
+	}
+}
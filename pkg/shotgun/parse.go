@@ -0,0 +1,292 @@
+package shotgun
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrMalformedPayload is returned when a payload does not follow the shotgun format.
+var ErrMalformedPayload = errors.New("malformed shotgun payload")
+
+// ParsedFile is one <file> block read back from a payload.
+type ParsedFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Omitted is set for placeholders (binary, undecoded or truncated files): Content is a note,
+	// not the file.
+	Omitted bool `json:"omitted,omitempty"`
+	// Partial is set for blocks holding only part of the file: symbol extracts and outlines.
+	Partial bool `json:"partial,omitempty"`
+	// Attrs holds every attribute of the opening tag except path and fence.
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// ParsedSection is one <section> block read back from a payload.
+type ParsedSection struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	// Attrs holds every attribute of the opening tag except name and fence.
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// ParsedPayload is a payload split back into its tree, files and sections, in payload order.
+type ParsedPayload struct {
+	Tree     string          `json:"tree"` // ASCII tree as generated, including its trailing newline
+	Files    []ParsedFile    `json:"files"`
+	Sections []ParsedSection `json:"sections,omitempty"`
+}
+
+// placeholderAttrs mark blocks whose content was replaced by the generator.
+var placeholderAttrs = []string{"binary", "encoding", "truncated"}
+
+// partialAttrs mark blocks whose content was reduced by the generator.
+var partialAttrs = []string{"symbols"}
+
+// classify sets Omitted and Partial from the attributes of the block.
+func (f *ParsedFile) classify() {
+	switch f.Attrs["render"] {
+	case RenderOutline:
+		f.Partial = true
+	case RenderOmitted:
+		f.Omitted = true
+	}
+	for _, name := range placeholderAttrs {
+		if _, ok := f.Attrs[name]; ok {
+			f.Omitted = true
+		}
+	}
+	for _, name := range partialAttrs {
+		if _, ok := f.Attrs[name]; ok {
+			f.Partial = true
+		}
+	}
+}
+
+var (
+	openTagPattern = regexp.MustCompile(`^<(file|section)((?: [a-zA-Z][\w-]*="[^"]*")*)>\n`)
+	attrPattern    = regexp.MustCompile(` ([a-zA-Z][\w-]*)="([^"]*)"`)
+)
+
+// parseShotgunPayload reads a payload in the shotgun format back into its tree, files and
+// sections. File contents are returned exactly as they were on disk (after UTF-8 decoding).
+func parseShotgunPayload(payload string) (*ParsedPayload, error) {
+	result := &ParsedPayload{}
+
+	start := strings.Index(payload, "\n<file ")
+	if idx := strings.Index(payload, "\n<section "); idx >= 0 && (start < 0 || idx < start) {
+		start = idx
+	}
+	if start < 0 {
+		result.Tree = strings.TrimSuffix(payload, "\n")
+		return result, nil
+	}
+	result.Tree = payload[:start]
+	pos := start + 1
+
+	for pos < len(payload) {
+		match := openTagPattern.FindStringSubmatchIndex(payload[pos:])
+		if match == nil {
+			return nil, fmt.Errorf("%w: expected <file> or <section> tag at byte %d", ErrMalformedPayload, pos)
+		}
+		element := payload[pos+match[2] : pos+match[3]]
+		key := "path"
+		if element == "section" {
+			key = "name"
+		}
+		var id string
+		var attrs map[string]string
+		closing := "</" + element + ">"
+		for _, attr := range attrPattern.FindAllStringSubmatch(payload[pos+match[4]:pos+match[5]], -1) {
+			name, value := attr[1], unescapeAttr(attr[2])
+			switch name {
+			case key:
+				id = value
+			case "fence":
+				closing = fenceClosingTag(element, value)
+			default:
+				if attrs == nil {
+					attrs = make(map[string]string)
+				}
+				attrs[name] = value
+			}
+		}
+		if id == "" {
+			return nil, fmt.Errorf("%w: <%s> tag without %s at byte %d", ErrMalformedPayload, element, key, pos)
+		}
+
+		contentStart := pos + match[1]
+		// The generator writes a newline between the content and the closing tag.
+		terminator := "\n" + closing
+		end := -1
+		for from := contentStart; ; {
+			idx := strings.Index(payload[from:], terminator)
+			if idx < 0 {
+				break
+			}
+			after := from + idx + len(terminator)
+			if after == len(payload) || payload[after] == '\n' {
+				end = from + idx
+				break
+			}
+			from += idx + 1
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated block for %s", ErrMalformedPayload, id)
+		}
+		content := payload[contentStart:end]
+		if element == "section" {
+			result.Sections = append(result.Sections, ParsedSection{Name: id, Content: content, Attrs: attrs})
+		} else {
+			file := ParsedFile{Path: id, Content: content, Attrs: attrs}
+			file.classify()
+			result.Files = append(result.Files, file)
+		}
+
+		pos = end + len(terminator)
+		if pos < len(payload) {
+			pos++ // Newline after the closing tag
+		}
+	}
+	return result, nil
+}
+
+// ParsePayload reads a payload back into its tree and files. The shotgun, JSON and XML formats
+// are recognized automatically; Markdown payloads are meant for reading and are not supported.
+// For the shotgun and JSON formats file contents round-trip byte for byte, including files fenced
+// because they contain "</file>".
+func ParsePayload(payload string) (*ParsedPayload, error) {
+	trimmed := strings.TrimLeft(payload, " \t\r\n")
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		return parseJSONPayload(trimmed)
+	case strings.HasPrefix(trimmed, "<?xml"), strings.HasPrefix(trimmed, "<context>"):
+		return parseXMLPayload(trimmed)
+	case strings.HasPrefix(trimmed, "# Project tree"):
+		return nil, fmt.Errorf("%w: markdown payloads cannot be parsed, generate the context in the shotgun, json or xml format", ErrMalformedPayload)
+	default:
+		return parseShotgunPayload(payload)
+	}
+}
+
+func parseJSONPayload(payload string) (*ParsedPayload, error) {
+	var doc struct {
+		Tree     string        `json:"tree"`
+		Files    []jsonFile    `json:"files"`
+		Sections []jsonSection `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPayload, err)
+	}
+	result := &ParsedPayload{Tree: doc.Tree}
+	for _, f := range doc.Files {
+		file := ParsedFile{Path: f.Path, Content: f.Content, Omitted: f.Omitted, Attrs: f.Attributes}
+		file.classify()
+		result.Files = append(result.Files, file)
+	}
+	for _, s := range doc.Sections {
+		result.Sections = append(result.Sections, ParsedSection{Name: s.Name, Content: s.Content, Attrs: s.Attributes})
+	}
+	return result, nil
+}
+
+func parseXMLPayload(payload string) (*ParsedPayload, error) {
+	var doc struct {
+		Tree  string `xml:"tree"`
+		Files []struct {
+			Attrs   []xml.Attr `xml:",any,attr"`
+			Content string     `xml:",chardata"`
+		} `xml:"file"`
+		Sections []struct {
+			Attrs   []xml.Attr `xml:",any,attr"`
+			Content string     `xml:",chardata"`
+		} `xml:"section"`
+	}
+	if err := xml.Unmarshal([]byte(payload), &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPayload, err)
+	}
+	result := &ParsedPayload{Tree: doc.Tree}
+	for _, f := range doc.Files {
+		file := ParsedFile{Content: f.Content}
+		for _, attr := range f.Attrs {
+			switch attr.Name.Local {
+			case "path":
+				file.Path = attr.Value
+			case "omitted":
+				file.Omitted = attr.Value == "true"
+			default:
+				if file.Attrs == nil {
+					file.Attrs = make(map[string]string)
+				}
+				file.Attrs[attr.Name.Local] = attr.Value
+			}
+		}
+		if file.Path == "" {
+			return nil, fmt.Errorf("%w: <file> element without path", ErrMalformedPayload)
+		}
+		file.classify()
+		result.Files = append(result.Files, file)
+	}
+	for _, s := range doc.Sections {
+		section := ParsedSection{Content: s.Content}
+		for _, attr := range s.Attrs {
+			if attr.Name.Local == "name" {
+				section.Name = attr.Value
+				continue
+			}
+			if section.Attrs == nil {
+				section.Attrs = make(map[string]string)
+			}
+			section.Attrs[attr.Name.Local] = attr.Value
+		}
+		result.Sections = append(result.Sections, section)
+	}
+	return result, nil
+}
+
+// Contents maps the path of every file whose whole content is present to that content.
+// Placeholders of binary, undecoded and truncated files and partial blocks are left out.
+func (p *ParsedPayload) Contents() map[string]string {
+	contents := make(map[string]string, len(p.Files))
+	for _, f := range p.Files {
+		if !f.Omitted && !f.Partial {
+			contents[f.Path] = f.Content
+		}
+	}
+	return contents
+}
+
+// Materialize writes every file with content below dir, creating folders as needed, and returns
+// the written paths. Placeholders and partial blocks are skipped. Paths that would escape dir are rejected before
+// anything is written.
+func (p *ParsedPayload) Materialize(dir string) ([]string, error) {
+	type target struct{ rel, abs, content string }
+	var targets []target
+	for _, f := range p.Files {
+		if f.Omitted || f.Partial {
+			continue
+		}
+		rel := filepath.FromSlash(f.Path)
+		if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("refusing to write %q outside %s", f.Path, dir)
+		}
+		targets = append(targets, target{rel: f.Path, abs: filepath.Join(dir, rel), content: f.Content})
+	}
+
+	var written []string
+	for _, t := range targets {
+		if err := os.MkdirAll(filepath.Dir(t.abs), 0755); err != nil {
+			return written, fmt.Errorf("failed to create folder for %s: %w", t.rel, err)
+		}
+		if err := os.WriteFile(t.abs, []byte(t.content), 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", t.rel, err)
+		}
+		written = append(written, t.rel)
+	}
+	return written, nil
+}
@@ -0,0 +1,193 @@
+package shotgun
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseShotgunPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []ParsedFile
+	}{
+		{
+			name:    "plain",
+			payload: "p/\n└── a.txt\n\n<file path=\"a.txt\">\nhello\n</file>",
+			want:    []ParsedFile{{Path: "a.txt", Content: "hello"}},
+		},
+		{
+			name:    "trailing newline",
+			payload: "p/\n└── a.txt\n\n<file path=\"a.txt\">\nhello\n\n</file>",
+			want:    []ParsedFile{{Path: "a.txt", Content: "hello\n"}},
+		},
+		{
+			name:    "empty file",
+			payload: "p/\n├── a.txt\n└── b.txt\n\n<file path=\"a.txt\">\n\n</file>\n<file path=\"b.txt\">\nb\n</file>",
+			want:    []ParsedFile{{Path: "a.txt", Content: ""}, {Path: "b.txt", Content: "b"}},
+		},
+		{
+			name:    "CRLF content",
+			payload: "p/\n└── a.txt\n\n<file path=\"a.txt\">\none\r\ntwo\r\n\n</file>",
+			want:    []ParsedFile{{Path: "a.txt", Content: "one\r\ntwo\r\n"}},
+		},
+		{
+			name:    "fenced block containing </file>",
+			payload: "p/\n└── t.tmpl\n\n<file path=\"t.tmpl\" fence=\"0123456789abcdef\">\n<file path=\"x\">\n</file>\n</file fence=\"0123456789abcdef\">",
+			want:    []ParsedFile{{Path: "t.tmpl", Content: "<file path=\"x\">\n</file>"}},
+		},
+		{
+			name:    "closing tag inside a line",
+			payload: "p/\n└── a.md\n\n<file path=\"a.md\">\nuse \n</file> to end a block\n</file>",
+			want:    []ParsedFile{{Path: "a.md", Content: "use \n</file> to end a block"}},
+		},
+		{
+			name:    "attributes",
+			payload: "p/\n└── logo.png\n\n<file path=\"logo.png\" binary=\"true\" size=\"12\">\n[binary file omitted]\n</file>",
+			want:    []ParsedFile{{Path: "logo.png", Content: "[binary file omitted]", Omitted: true, Attrs: map[string]string{"binary": "true", "size": "12"}}},
+		},
+		{
+			name:    "escaped path",
+			payload: "p/\n└── a\"b.txt\n\n<file path=\"a&quot;b.txt\">\nx\n</file>",
+			want:    []ParsedFile{{Path: "a\"b.txt", Content: "x"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParsePayload(tt.payload)
+			if err != nil {
+				t.Fatalf("ParsePayload: %v", err)
+			}
+			if !reflect.DeepEqual(parsed.Files, tt.want) {
+				t.Errorf("files = %#v, want %#v", parsed.Files, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePayloadMalformed(t *testing.T) {
+	for name, payload := range map[string]string{
+		"unterminated": "p/\n\n<file path=\"a.txt\">\nhello",
+		"missing path": "p/\n\n<file size=\"1\">\nx\n</file>",
+		"markdown":     "# Project tree\n\n```\np/\n```\n",
+		"bad json":     "{\"tree\": ",
+	} {
+		if _, err := ParsePayload(payload); !errors.Is(err, ErrMalformedPayload) {
+			t.Errorf("%s: err = %v, want ErrMalformedPayload", name, err)
+		}
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	parsed := &ParsedPayload{Files: []ParsedFile{
+		{Path: "a.txt", Content: "a\n"},
+		{Path: "sub/dir/b.txt", Content: ""},
+		{Path: "crlf.txt", Content: "x\r\ny"},
+		{Path: "logo.png", Content: "[binary file omitted]", Omitted: true},
+		{Path: "outline.go", Content: "func F() { … }", Partial: true},
+	}}
+	dir := t.TempDir()
+	written, err := parsed.Materialize(dir)
+	if err != nil {
+		t.Fatalf("Materialize: %v", err)
+	}
+	if want := []string{"a.txt", "sub/dir/b.txt", "crlf.txt"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+	for rel, want := range map[string]string{"a.txt": "a\n", "sub/dir/b.txt": "", "crlf.txt": "x\r\ny"} {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q (%v), want %q", rel, got, err, want)
+		}
+	}
+	for _, skipped := range []string{"logo.png", "outline.go"} {
+		if _, err := os.Stat(filepath.Join(dir, skipped)); !os.IsNotExist(err) {
+			t.Errorf("%s was written", skipped)
+		}
+	}
+}
+
+func TestMaterializeRejectsEscapingPaths(t *testing.T) {
+	paths := []string{"../evil.txt", "a/../../evil.txt", "..", "/etc/evil.txt", ""}
+	if runtime.GOOS == "windows" {
+		paths = append(paths, `C:\evil.txt`, `\\server\share\evil.txt`)
+	}
+	for _, path := range paths {
+		dir := t.TempDir()
+		parsed := &ParsedPayload{Files: []ParsedFile{
+			{Path: "ok.txt", Content: "ok"},
+			{Path: path, Content: "evil"},
+		}}
+		if _, err := parsed.Materialize(dir); err == nil {
+			t.Errorf("%q: Materialize succeeded, want an error", path)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%q: files were written before the path was rejected", path)
+		}
+	}
+}
+
+// roundTripFiles are contents that must come back unchanged from a payload.
+var roundTripFiles = map[string]string{
+	"plain.txt":           "hello",
+	"newline.txt":         "hello\n",
+	"empty.txt":           "",
+	"blank-lines.txt":     "\n\n",
+	"crlf.txt":            "one\r\ntwo\r\n",
+	"go/main.go":          "package main\n\nfunc main() {}\n",
+	"templates/file.tmpl": "<file path=\"{{.Path}}\">\n{{.Content}}\n</file>\n",
+	"docs/cdata.xml":      "<![CDATA[ nested ]]> and ]]> again",
+	"unicode.txt":         "héllo wörld ✓",
+}
+
+func TestGeneratePayloadRoundTrip(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, roundTripFiles)
+
+	for _, name := range FormatNames() {
+		if name == FormatMarkdown {
+			continue // Not parseable by design
+		}
+		t.Run(name, func(t *testing.T) {
+			formatter, err := FormatterByName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload := generate(t, Options{RootDir: root, Formatter: formatter})
+			parsed, err := ParsePayload(payload)
+			if err != nil {
+				t.Fatalf("ParsePayload: %v", err)
+			}
+			if !strings.HasPrefix(parsed.Tree, "project") {
+				t.Errorf("tree = %q", parsed.Tree)
+			}
+			want := roundTripFiles
+			if name == FormatXML {
+				// XML parsers normalize line endings, see the XML 1.0 spec section 2.11.
+				want = make(map[string]string, len(roundTripFiles))
+				for path, content := range roundTripFiles {
+					want[path] = strings.ReplaceAll(content, "\r\n", "\n")
+				}
+			}
+			if got := parsed.Contents(); !reflect.DeepEqual(got, want) {
+				t.Errorf("contents = %q\nwant %q", got, want)
+			}
+
+			dir := t.TempDir()
+			if _, err := parsed.Materialize(dir); err != nil {
+				t.Fatalf("Materialize: %v", err)
+			}
+			for path, content := range want {
+				got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+				if err != nil || string(got) != content {
+					t.Errorf("materialized %s = %q (%v), want %q", path, got, err, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package shotgun
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// PathPatterns selects files with doublestar globs such as "internal/**/*.go", matched against
+// paths relative to the project root with forward slashes.
+type PathPatterns struct {
+	// Include, when non-empty, keeps only files matching at least one pattern. Folders without
+	// a matching file are left out of the tree.
+	Include []string `json:"include,omitempty"`
+	// Exclude drops files and folders matching any pattern, e.g. "**/*_test.go" or "vendor".
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Validate reports the first malformed pattern.
+func (p PathPatterns) Validate() error {
+	for _, list := range [][]string{p.Include, p.Exclude} {
+		for _, pattern := range list {
+			if !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("invalid glob pattern %q", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func (p PathPatterns) hasIncludes() bool {
+	return len(p.Include) > 0
+}
+
+// includes reports whether the file at relPath is selected. Without include patterns every file is.
+func (p PathPatterns) includes(relPath string) bool {
+	return !p.hasIncludes() || matchAny(p.Include, filepath.ToSlash(relPath))
+}
+
+// excludes reports whether relPath (a file or folder) matches an exclude pattern.
+func (p PathPatterns) excludes(relPath string) bool {
+	return len(p.Exclude) > 0 && matchAny(p.Exclude, filepath.ToSlash(relPath))
+}
+
+func matchAny(patterns []string, slashPath string) bool {
+	for _, pattern := range patterns {
+		// Validate has rejected malformed patterns, so the error can be ignored.
+		if ok, _ := doublestar.Match(pattern, slashPath); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package shotgun
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns PathPatterns
+		included map[string]bool // Slash-separated file paths
+		excluded map[string]bool // Slash-separated file or folder paths
+	}{
+		{
+			"no patterns",
+			PathPatterns{},
+			map[string]bool{"main.go": true, "a/b/c.txt": true},
+			map[string]bool{"main.go": false, "vendor": false},
+		},
+		{
+			"double star spans any number of folders",
+			PathPatterns{Include: []string{"internal/**/*.go"}},
+			map[string]bool{"internal/a.go": true, "internal/x/y/z.go": true, "internal/a.txt": false, "cmd/internal/a.go": false, "internal.go": false},
+			nil,
+		},
+		{
+			"leading double star matches at any depth",
+			PathPatterns{Exclude: []string{"**/*_test.go", "**/testdata"}},
+			nil,
+			map[string]bool{"a_test.go": true, "pkg/x/a_test.go": true, "pkg/x/a.go": false, "testdata": true, "pkg/testdata": true, "pkg/testdata/in.txt": false},
+		},
+		{
+			"excludes subtract from includes",
+			PathPatterns{Include: []string{"internal/**/*.go"}, Exclude: []string{"**/*_test.go"}},
+			map[string]bool{"internal/store/store.go": true, "internal/store/store_test.go": true},
+			map[string]bool{"internal/store/store.go": false, "internal/store/store_test.go": true},
+		},
+		{
+			"negated character classes and alternatives",
+			PathPatterns{Include: []string{"src/[!_]*.{ts,vue}"}, Exclude: []string{"src/*.[!t]*"}},
+			map[string]bool{"src/app.ts": true, "src/App.vue": true, "src/_draft.ts": false, "src/app.js": false},
+			map[string]bool{"src/app.ts": false, "src/App.vue": true},
+		},
+		{
+			"a folder pattern matches the folder, which scan then skips whole",
+			PathPatterns{Exclude: []string{"vendor"}},
+			nil,
+			map[string]bool{"vendor": true, "vendor/lib.go": false, "pkg/vendor": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for p, want := range tt.included {
+				if got := tt.patterns.includes(filepath.FromSlash(p)); got != want {
+					t.Errorf("includes(%q) = %v, want %v", p, got, want)
+				}
+			}
+			for p, want := range tt.excluded {
+				if got := tt.patterns.excludes(filepath.FromSlash(p)); got != want {
+					t.Errorf("excludes(%q) = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPathPatternsValidate(t *testing.T) {
+	if err := (PathPatterns{Include: []string{"**/*.go", "src/{a,b}/[!.]*"}, Exclude: []string{"vendor"}}).Validate(); err != nil {
+		t.Errorf("valid patterns were rejected: %v", err)
+	}
+	for _, p := range []PathPatterns{
+		{Include: []string{"**/*.go", "src/[a-"}},
+		{Exclude: []string{"{a,b"}},
+	} {
+		if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "invalid glob pattern") {
+			t.Errorf("Validate(%+v) = %v, want an invalid pattern error", p, err)
+		}
+	}
+}
+
+func TestGeneratePatterns(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"main.go":                      "package main",
+		"internal/store/store.go":      "package store",
+		"internal/store/store_test.go": "package store",
+		"internal/docs/README.md":      "docs",
+		"internal/vendor/lib/lib.go":   "package lib",
+	})
+	out := generate(t, Options{RootDir: root, Patterns: PathPatterns{
+		Include: []string{"internal/**/*.go"},
+		Exclude: []string{"**/*_test.go", "**/vendor"},
+	}})
+	if !strings.Contains(out, "<file path=\"internal/store/store.go\">") {
+		t.Errorf("store.go is missing:\n%s", out)
+	}
+	// Folders left without an included file are dropped from the tree too.
+	for _, left := range []string{"main.go", "store_test.go", "docs", "README.md", "vendor", "lib.go"} {
+		if strings.Contains(out, left) {
+			t.Errorf("%s is in the payload:\n%s", left, out)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package shotgun
+
+import "testing"
+
+// Relative paths carry backslashes on Windows; the patterns are matched with forward slashes.
+func TestPathPatternsWindowsSeparators(t *testing.T) {
+	p := PathPatterns{Include: []string{"internal/**/*.go"}, Exclude: []string{"**/*_test.go", "internal/gen"}}
+	for path, want := range map[string]bool{
+		`internal\store\store.go`:      true,
+		`internal\store\store_test.go`: true,
+		`cmd\main.go`:                  false,
+	} {
+		if got := p.includes(path); got != want {
+			t.Errorf("includes(%q) = %v, want %v", path, got, want)
+		}
+	}
+	for path, want := range map[string]bool{
+		`internal\store\store_test.go`: true,
+		`internal\gen`:                 true,
+		`internal\store\store.go`:      false,
+	} {
+		if got := p.excludes(path); got != want {
+			t.Errorf("excludes(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package shotgun
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress describes how far a generation job has advanced.
+type Progress struct {
+	Current int `json:"current"`
+	Total   int `json:"total"`
+}
+
+// ProgressFunc receives progress updates while a payload is being built.
+type ProgressFunc func(Progress)
+
+// progressThrottle counts completed items from any goroutine and forwards at most one update per
+// interval to fn. Updates never go backwards, and the first one and the flushed final one are
+// always delivered.
+type progressThrottle struct {
+	fn       ProgressFunc
+	interval time.Duration
+	total    int
+
+	mu       sync.Mutex
+	current  int
+	reported int
+	last     time.Time
+}
+
+func newProgressThrottle(fn ProgressFunc, interval time.Duration) *progressThrottle {
+	return &progressThrottle{fn: fn, interval: interval, reported: -1}
+}
+
+// advance records n more completed items and reports them if the interval has elapsed.
+func (t *progressThrottle) advance(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current += n
+	if t.reported >= 0 && time.Since(t.last) < t.interval {
+		return
+	}
+	t.reportLocked()
+}
+
+// flush reports the current state unless it was already reported.
+func (t *progressThrottle) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != t.reported {
+		t.reportLocked()
+	}
+}
+
+func (t *progressThrottle) reportLocked() {
+	t.reported = t.current
+	t.last = time.Now()
+	t.fn(Progress{Current: t.current, Total: t.total})
+}
@@ -0,0 +1,123 @@
+package shotgun
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressThrottle(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     func(events []Progress) bool
+	}{
+		// Within the interval, only the first update and the flushed final one get through.
+		{"throttled", time.Hour, func(events []Progress) bool { return len(events) == 2 && events[0].Current == 1 }},
+		{"every update", time.Nanosecond, func(events []Progress) bool { return len(events) > 2 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []Progress // fn is called with the throttle's lock held
+			throttle := newProgressThrottle(func(p Progress) { events = append(events, p) }, tt.interval)
+			throttle.total = 400
+
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						throttle.advance(1)
+						time.Sleep(time.Microsecond)
+					}
+				}()
+			}
+			wg.Wait()
+			throttle.flush()
+			throttle.flush() // Nothing new to report
+
+			if !tt.want(events) {
+				t.Errorf("events = %v", events)
+			}
+			for i, p := range events {
+				if p.Total != 400 || (i > 0 && p.Current <= events[i-1].Current) {
+					t.Fatalf("events go backwards or repeat: %v", events)
+				}
+			}
+			if last := events[len(events)-1]; last.Current != 400 {
+				t.Errorf("final event = %+v, want 400 of 400", last)
+			}
+		})
+	}
+}
+
+// workerProject holds files of varied sizes in nested folders, so that workers finish them out
+// of order.
+func workerProject(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "project")
+	files := make(map[string]string)
+	for i := 0; i < 120; i++ {
+		name := fmt.Sprintf("d%d/sub%d/f%03d.txt", i%4, i%3, i)
+		files[name] = strings.Repeat(fmt.Sprintf("line of file %d\n", i), (i*37)%200+1)
+	}
+	writeFiles(t, root, files)
+	return root
+}
+
+func TestGenerateOutputDoesNotDependOnWorkers(t *testing.T) {
+	root := workerProject(t)
+	want := generate(t, Options{RootDir: root, Workers: 1})
+
+	// Files follow the tree order: folders d0 to d3, then sub0 to sub2, then the file names.
+	var paths []string
+	for _, line := range strings.Split(want, "\n") {
+		if strings.HasPrefix(line, "<file path=\"") {
+			paths = append(paths, strings.TrimSuffix(strings.TrimPrefix(line, "<file path=\""), "\">"))
+		}
+	}
+	if len(paths) != 120 || paths[0] != "d0/sub0/f000.txt" || paths[119] != "d3/sub2/f119.txt" {
+		t.Fatalf("%d files, first %q, last %q", len(paths), paths[0], paths[len(paths)-1])
+	}
+	for i := 1; i < len(paths); i++ {
+		if paths[i] < paths[i-1] {
+			t.Fatalf("%s comes after %s", paths[i], paths[i-1])
+		}
+	}
+
+	for _, workers := range []int{2, 7, 16, 500} {
+		for run := 0; run < 3; run++ {
+			if got := generate(t, Options{RootDir: root, Workers: workers}); got != want {
+				t.Fatalf("with %d workers, the payload differs from the sequential one", workers)
+			}
+		}
+	}
+}
+
+func TestGenerateProgressIsThrottled(t *testing.T) {
+	root := workerProject(t)
+	var mu sync.Mutex
+	var events []Progress
+	progress := func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, p)
+	}
+	opts := Options{RootDir: root, Workers: 8, ProgressInterval: time.Hour}
+	if _, err := NewGenerator(opts, progress).Generate(context.Background()); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	// 1 for the root line, 4+12 folders and 120 files in the tree, then 120 files read.
+	const total = 1 + 4 + 12 + 120 + 120
+	want := []Progress{{Current: 1 + 4 + 12 + 120, Total: total}, {Current: total, Total: total}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("progress events = %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,104 @@
+package shotgun
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Project configuration files, relative to the project root. The files inside the .shotgun
+// folder take precedence over the ones at the root.
+var (
+	projectConfigFiles = []string{filepath.Join(".shotgun", "config.json"), ".shotgun.json"}
+	projectIgnoreFiles = []string{filepath.Join(".shotgun", "ignore"), ".shotgunignore"}
+)
+
+// ProjectConfig is the checked-in configuration of a project. It is merged on top of the global
+// settings: ignore and prompt rules are appended to the global ones, the model overrides the
+// global choice and the patterns become the default selection.
+type ProjectConfig struct {
+	// IgnoreRules are gitignore-syntax rules from .shotgunignore (or .shotgun/ignore), after any
+	// "ignoreRules" given in the JSON file.
+	IgnoreRules string `json:"ignoreRules,omitempty"`
+	// Include and Exclude are the default doublestar selection, see PathPatterns.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// PromptRules are added to the global custom prompt rules.
+	PromptRules string `json:"promptRules,omitempty"`
+	// Provider and Model name the preferred LLM, e.g. "openai" and "gpt-5".
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// Sources lists the files the configuration was read from, relative to the project root.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// LoadProjectConfig reads the project configuration of rootDir. A project without configuration
+// files yields an empty config and no error.
+func LoadProjectConfig(rootDir string) (ProjectConfig, error) {
+	var cfg ProjectConfig
+
+	if name, data, err := readFirstFile(rootDir, projectConfigFiles); err != nil {
+		return cfg, err
+	} else if data != nil {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return ProjectConfig{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		cfg.Sources = []string{filepath.ToSlash(name)}
+		if err := cfg.Patterns().Validate(); err != nil {
+			return ProjectConfig{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	if name, data, err := readFirstFile(rootDir, projectIgnoreFiles); err != nil {
+		return cfg, err
+	} else if data != nil {
+		cfg.IgnoreRules = joinRules(cfg.IgnoreRules, string(data))
+		cfg.Sources = append(cfg.Sources, filepath.ToSlash(name))
+	}
+	return cfg, nil
+}
+
+// Patterns returns the default selection of the project.
+func (c ProjectConfig) Patterns() PathPatterns {
+	return PathPatterns{Include: c.Include, Exclude: c.Exclude}
+}
+
+// MergeRules appends project rules to global rules, so project patterns (including negations)
+// are evaluated last and win.
+func MergeRules(global, project string) string {
+	return joinRules(global, project)
+}
+
+func joinRules(first, second string) string {
+	first = strings.TrimRight(first, "\r\n")
+	second = strings.TrimRight(second, "\r\n")
+	switch {
+	case strings.TrimSpace(second) == "":
+		return first
+	case strings.TrimSpace(first) == "":
+		return second
+	default:
+		return first + "\n" + second
+	}
+}
+
+// readFirstFile returns the name and content of the first of names that exists below rootDir.
+// data is nil when none exists.
+func readFirstFile(rootDir string, names []string) (string, []byte, error) {
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(rootDir, name))
+		if err == nil {
+			return name, data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return name, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,122 @@
+package shotgun
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadProjectConfigWithoutFiles(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{"main.go": "package main"})
+
+	cfg, err := LoadProjectConfig(root)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, ProjectConfig{}) {
+		t.Errorf("config = %+v, want it empty", cfg)
+	}
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		".shotgun/config.json": `{"include": ["src/**"], "exclude": ["**/*_test.go"], "ignoreRules": "*.log", "promptRules": "Be brief.", "provider": "openai", "model": "gpt-5"}`,
+		".shotgun/ignore":      "dist/\n",
+		// Shadowed by the files in the .shotgun folder.
+		".shotgun.json":  `{"model": "other"}`,
+		".shotgunignore": "other/\n",
+	})
+
+	cfg, err := LoadProjectConfig(root)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig: %v", err)
+	}
+	want := ProjectConfig{
+		IgnoreRules: "*.log\ndist/",
+		Include:     []string{"src/**"},
+		Exclude:     []string{"**/*_test.go"},
+		PromptRules: "Be brief.",
+		Provider:    "openai",
+		Model:       "gpt-5",
+		Sources:     []string{".shotgun/config.json", ".shotgun/ignore"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+	if got := cfg.Patterns(); !reflect.DeepEqual(got, PathPatterns{Include: want.Include, Exclude: want.Exclude}) {
+		t.Errorf("Patterns() = %+v", got)
+	}
+}
+
+func TestLoadProjectConfigRootFiles(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		".shotgun.json":  `{"model": "gpt-5"}`,
+		".shotgunignore": "dist/\n",
+	})
+
+	cfg, err := LoadProjectConfig(root)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig: %v", err)
+	}
+	if cfg.Model != "gpt-5" || cfg.IgnoreRules != "dist/" || !reflect.DeepEqual(cfg.Sources, []string{".shotgun.json", ".shotgunignore"}) {
+		t.Errorf("config = %+v", cfg)
+	}
+}
+
+func TestLoadProjectConfigMalformed(t *testing.T) {
+	tests := []struct {
+		name, config, want string
+	}{
+		{"bad JSON", `{"include": [`, "invalid .shotgun.json"},
+		{"unknown field", `{"includes": ["src/**"]}`, "unknown field"},
+		{"wrong type", `{"include": "src/**"}`, "invalid .shotgun.json"},
+		{"bad glob", `{"exclude": ["src/[a"]}`, `invalid glob pattern "src/[a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "project")
+			writeFiles(t, root, map[string]string{".shotgun.json": tt.config})
+
+			cfg, err := LoadProjectConfig(root)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.want)
+			}
+			if !reflect.DeepEqual(cfg, ProjectConfig{}) {
+				t.Errorf("config = %+v, want it empty on error", cfg)
+			}
+		})
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	tests := []struct {
+		global, project, want string
+	}{
+		{"", "", ""},
+		{"*.log\n", "", "*.log"},
+		{"", "dist/\r\n", "dist/"},
+		{"*.log\n\n", "!keep.log\n", "*.log\n!keep.log"},
+	}
+	for _, tt := range tests {
+		if got := MergeRules(tt.global, tt.project); got != tt.want {
+			t.Errorf("MergeRules(%q, %q) = %q, want %q", tt.global, tt.project, got, tt.want)
+		}
+	}
+}
+
+func TestMergeRulesProjectWins(t *testing.T) {
+	rules := CompileRules(MergeRules("*.log\nbuild/", "!keep.log"))
+	for path, want := range map[string]bool{"debug.log": true, "keep.log": false, "build/": true} {
+		if got := rules.MatchesPath(path); got != want {
+			t.Errorf("%s ignored = %v, want %v", path, got, want)
+		}
+	}
+	// A project rule also overrides a global negation.
+	if !CompileRules(MergeRules("!keep.log", "*.log")).MatchesPath("keep.log") {
+		t.Errorf("keep.log is kept although the project ignores it")
+	}
+}
@@ -0,0 +1,71 @@
+package shotgun
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// Section is an extra part of the payload that follows the files, such as the git diff of the
+// selection. See Options.Sections.
+type Section struct {
+	// Name identifies the section, e.g. "git-diff".
+	Name string
+	// Content is the raw text of the section. An empty Content leaves the section out.
+	Content string
+	// Language is a syntax hint for formats that highlight code, e.g. "diff".
+	Language string
+	// Attrs are rendered in order after the name.
+	Attrs []Attr
+}
+
+// SectionFunc builds a section once the files of the payload are known. files are the paths of
+// the packed files relative to rootDir, with forward slashes, in payload order.
+type SectionFunc func(ctx context.Context, rootDir string, files []string) (Section, error)
+
+// SectionEntry describes one section of the payload in the manifest.
+type SectionEntry struct {
+	Name   string `json:"name"`
+	Bytes  int    `json:"bytes"`  // Size of the section content
+	Tokens int    `json:"tokens"` // Estimated tokens of the whole rendered section
+}
+
+// sectionBlock is a rendered section waiting to be appended after the files.
+type sectionBlock struct {
+	name  string
+	block string
+	bytes int
+}
+
+// buildSections runs Options.Sections in order. used is the size of the payload so far, counted
+// against runningByteCap together with the sections.
+func (g *Generator) buildSections(ctx context.Context, files []fileBlock, used int) ([]sectionBlock, error) {
+	if len(g.opts.Sections) == 0 {
+		return nil, nil
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = filepath.ToSlash(f.relPath)
+	}
+
+	var sections []sectionBlock
+	for _, build := range g.opts.Sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		section, err := build(ctx, g.opts.RootDir, paths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build payload section: %w", err)
+		}
+		if section.Content == "" {
+			continue
+		}
+		block := g.opts.Formatter.FormatSection(section)
+		used += len(block)
+		if maxBytes := g.runningByteCap(); used > maxBytes {
+			return nil, fmt.Errorf("%w: content limit of %d bytes exceeded after appending section %s (total size: %d bytes)", ErrContextTooLong, maxBytes, section.Name, used)
+		}
+		sections = append(sections, sectionBlock{name: section.Name, block: block, bytes: len(section.Content)})
+	}
+	return sections, nil
+}
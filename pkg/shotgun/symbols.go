@@ -0,0 +1,161 @@
+package shotgun
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// GoSymbols lists the top-level declarations of a Go source that ExtractGoSymbols can pick, in
+// source order: functions, methods as "Type.Method", types, constants and variables.
+func GoSymbols(src []byte) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			symbols = append(symbols, funcSymbol(d))
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				symbols = append(symbols, specNames(spec)...)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// ExtractGoSymbols reduces a Go source to its package clause (with everything above it, such as
+// build constraints and the package doc), its imports and the declarations named by symbols,
+// each with its doc comment, in source order. A symbol declared in a grouped type, const or var
+// block is emitted as a declaration of its own. It returns the symbols that were found.
+func ExtractGoSymbols(src []byte, symbols []string) (string, []string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", nil, err
+	}
+	tf := fset.File(file.Package)
+	text := func(from, to token.Pos) string {
+		return string(src[tf.Offset(from):tf.Offset(to)])
+	}
+
+	wanted := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		wanted[strings.TrimSpace(s)] = true
+	}
+
+	parts := []string{text(tf.Pos(0), file.Name.End())}
+	var found []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if name := funcSymbol(d); wanted[name] {
+				parts = append(parts, text(docStart(d.Doc, d.Pos()), d.End()))
+				found = append(found, name)
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				parts = append(parts, text(d.Pos(), d.End()))
+				continue
+			}
+			if !d.Lparen.IsValid() {
+				names := matchingNames(d.Specs[0], wanted)
+				if len(names) > 0 {
+					parts = append(parts, text(docStart(d.Doc, d.Pos()), d.End()))
+					found = append(found, names...)
+				}
+				continue
+			}
+			for _, spec := range d.Specs {
+				names := matchingNames(spec, wanted)
+				if len(names) == 0 {
+					continue
+				}
+				parts = append(parts, specDoc(spec)+d.Tok.String()+" "+text(spec.Pos(), spec.End()))
+				found = append(found, names...)
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n", found, nil
+}
+
+// funcSymbol names a function, or a method as "Type.Method" regardless of pointer receivers and
+// type parameters.
+func funcSymbol(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name
+	}
+	typ := d.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			typ = t.X
+			continue
+		case *ast.IndexListExpr:
+			typ = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + d.Name.Name
+		}
+		return d.Name.Name
+	}
+}
+
+func specNames(spec ast.Spec) []string {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return []string{s.Name.Name}
+	case *ast.ValueSpec:
+		names := make([]string, 0, len(s.Names))
+		for _, n := range s.Names {
+			if n.Name != "_" {
+				names = append(names, n.Name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func matchingNames(spec ast.Spec, wanted map[string]bool) []string {
+	var names []string
+	for _, name := range specNames(spec) {
+		if wanted[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func docStart(doc *ast.CommentGroup, pos token.Pos) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return pos
+}
+
+// specDoc renders the doc comment of a spec inside a grouped declaration, one comment per line.
+func specDoc(spec ast.Spec) string {
+	var doc *ast.CommentGroup
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		doc = s.Doc
+	case *ast.ValueSpec:
+		doc = s.Doc
+	}
+	if doc == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, c := range doc.List {
+		sb.WriteString(c.Text + "\n")
+	}
+	return sb.String()
+}
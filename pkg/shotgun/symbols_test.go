@@ -0,0 +1,152 @@
+package shotgun
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const symbolsSource = `//go:build linux
+
+// Package store keeps things.
+package store
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Limit bounds the store.
+const Limit = 10
+
+const (
+	// First is first.
+	First = iota
+	Second
+	_
+)
+
+var a, b = 1, 2
+
+// Store keeps values of any type.
+type Store[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]V
+}
+
+type (
+	Key   string
+	Value []byte
+)
+
+// New returns an empty store.
+func New[K comparable, V any]() *Store[K, V] {
+	return &Store[K, V]{m: make(map[K]V)}
+}
+
+// Get returns the value of k.
+func (s *Store[K, V]) Get(k K) V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[k]
+}
+
+func (k Key) String() string { return fmt.Sprint(string(k)) }
+
+func (p Pair[A, B]) Swap() Pair[B, A] { return Pair[B, A]{p.B, p.A} }
+`
+
+func TestGoSymbols(t *testing.T) {
+	symbols, err := GoSymbols([]byte(symbolsSource))
+	if err != nil {
+		t.Fatalf("GoSymbols: %v", err)
+	}
+	want := []string{"Limit", "First", "Second", "a", "b", "Store", "Key", "Value", "New", "Store.Get", "Key.String", "Pair.Swap"}
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("symbols = %v, want %v", symbols, want)
+	}
+}
+
+func TestExtractGoSymbols(t *testing.T) {
+	tests := []struct {
+		name    string
+		symbols []string
+		want    string
+		found   []string
+	}{
+		{
+			"generic method",
+			[]string{"Store.Get"},
+			"//go:build linux\n\n// Package store keeps things.\npackage store\n\n" +
+				"import (\n\t\"fmt\"\n\t\"sync\"\n)\n\n" +
+				"// Get returns the value of k.\nfunc (s *Store[K, V]) Get(k K) V {\n\ts.mu.Lock()\n\tdefer s.mu.Unlock()\n\treturn s.m[k]\n}\n",
+			[]string{"Store.Get"},
+		},
+		{
+			"generic type and function",
+			[]string{"Store", "New"},
+			"//go:build linux\n\n// Package store keeps things.\npackage store\n\n" +
+				"import (\n\t\"fmt\"\n\t\"sync\"\n)\n\n" +
+				"// Store keeps values of any type.\ntype Store[K comparable, V any] struct {\n\tmu sync.Mutex\n\tm  map[K]V\n}\n\n" +
+				"// New returns an empty store.\nfunc New[K comparable, V any]() *Store[K, V] {\n\treturn &Store[K, V]{m: make(map[K]V)}\n}\n",
+			[]string{"Store", "New"},
+		},
+		{
+			"grouped declarations",
+			[]string{" First ", "Value", "b"},
+			"//go:build linux\n\n// Package store keeps things.\npackage store\n\n" +
+				"import (\n\t\"fmt\"\n\t\"sync\"\n)\n\n" +
+				"// First is first.\nconst First = iota\n\n" +
+				"var a, b = 1, 2\n\n" +
+				"type Value []byte\n",
+			[]string{"First", "b", "Value"},
+		},
+		{
+			"unknown symbols",
+			[]string{"Missing", "Get"},
+			"//go:build linux\n\n// Package store keeps things.\npackage store\n\n" +
+				"import (\n\t\"fmt\"\n\t\"sync\"\n)\n",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := ExtractGoSymbols([]byte(symbolsSource), tt.symbols)
+			if err != nil {
+				t.Fatalf("ExtractGoSymbols: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("source =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(found, tt.found) {
+				t.Errorf("found = %v, want %v", found, tt.found)
+			}
+		})
+	}
+}
+
+func TestGoSymbolsParseError(t *testing.T) {
+	for _, src := range []string{"", "package", "package p\n\nfunc broken( {\n", "not go at all"} {
+		if symbols, err := GoSymbols([]byte(src)); err == nil {
+			t.Errorf("GoSymbols(%q) = %v, want a parse error", src, symbols)
+		}
+		if out, _, err := ExtractGoSymbols([]byte(src), []string{"x"}); err == nil {
+			t.Errorf("ExtractGoSymbols(%q) = %q, want a parse error", src, out)
+		}
+	}
+}
+
+func TestGenerateFallsBackOnUnparsableSymbolsFile(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"ok.go":     "package p\n\nfunc Keep() {}\n\nfunc Drop() {}\n",
+		"broken.go": "package p\n\nfunc broken( {\n",
+	})
+	out := generate(t, Options{RootDir: root, Symbols: map[string][]string{"ok.go": {"Keep"}, "broken.go": {"broken"}}})
+	if !strings.Contains(out, "func Keep() {}") || strings.Contains(out, "func Drop") {
+		t.Errorf("ok.go is not reduced to Keep:\n%s", out)
+	}
+	if !strings.Contains(out, "func broken( {") {
+		t.Errorf("broken.go is not packed whole:\n%s", out)
+	}
+}
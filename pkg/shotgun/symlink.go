@@ -0,0 +1,199 @@
+package shotgun
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Symbolic link policies, see Options.Symlinks.
+const (
+	SymlinkShow   = "show"   // List folder links as leaves (name -> target) without following them, read file links (the default)
+	SymlinkSkip   = "skip"   // Leave links out
+	SymlinkFollow = "follow" // Resolve links and walk linked folders, unless that would loop
+)
+
+// SymlinkPolicies lists the accepted symbolic link policies.
+func SymlinkPolicies() []string {
+	return []string{SymlinkShow, SymlinkSkip, SymlinkFollow}
+}
+
+// ValidateSymlinkPolicy checks a policy name. An empty name selects SymlinkShow.
+func ValidateSymlinkPolicy(policy string) error {
+	switch policy {
+	case "", SymlinkShow, SymlinkSkip, SymlinkFollow:
+		return nil
+	}
+	return fmt.Errorf("unknown symlink policy %q (available: %s)", policy, strings.Join(SymlinkPolicies(), ", "))
+}
+
+// walkDir is a folder being walked. real is its resolved location when it was reached through a
+// followed link ("" otherwise); chain identifies the folders from the root down to it.
+type walkDir struct {
+	path  string
+	real  string
+	chain []dirKey
+}
+
+// listedEntry is a folder entry after applying the symlink policy.
+type listedEntry struct {
+	name    string
+	path    string
+	isDir   bool
+	symlink bool // The entry itself is a symbolic link
+	// link marks a link listed as a leaf: a folder or broken link under SymlinkShow, or a folder
+	// link that would loop.
+	link bool
+	// target is where a link points: its text under SymlinkShow, the resolved path (relative to
+	// the root when inside it) for followed links and for entries inside followed folders.
+	target string
+	key    dirKey // Identity of a folder under SymlinkFollow
+	real   string // Resolved path when reached through a followed link
+}
+
+// linkResolver lists folders according to a symbolic link policy.
+type linkResolver struct {
+	policy   string
+	rootReal string
+}
+
+// newLinkResolver returns the resolver for rootDir and the walkDir of the root.
+func newLinkResolver(rootDir, policy string) (*linkResolver, walkDir) {
+	if policy == "" {
+		policy = SymlinkShow
+	}
+	r := &linkResolver{policy: policy, rootReal: rootDir}
+	root := walkDir{path: rootDir}
+	if policy == SymlinkFollow {
+		if real, err := filepath.EvalSymlinks(rootDir); err == nil {
+			r.rootReal = real
+		}
+		if info, err := os.Stat(rootDir); err == nil {
+			root.chain = []dirKey{fileKey(info, r.rootReal)}
+		}
+	}
+	return r, root
+}
+
+// readDir lists dir, sorted like BuildTree (folders first, then by name).
+func (r *linkResolver) readDir(dir walkDir) ([]listedEntry, error) {
+	dirEntries, err := os.ReadDir(dir.path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]listedEntry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		e := listedEntry{name: d.Name(), path: filepath.Join(dir.path, d.Name()), isDir: d.IsDir()}
+		if dir.real != "" {
+			e.real = filepath.Join(dir.real, d.Name())
+			e.target = r.display(e.real)
+		}
+
+		if d.Type()&os.ModeSymlink != 0 {
+			e.symlink = true
+			switch r.policy {
+			case SymlinkSkip:
+				continue
+			case SymlinkShow:
+				text, err := os.Readlink(e.path)
+				if err != nil {
+					log.Printf("symlink: cannot read link %s: %v", e.path, err)
+				}
+				e.isDir, e.target, e.real = false, filepath.ToSlash(text), ""
+				// Links to files are read like the files themselves; only folder and broken
+				// links are listed as leaves.
+				info, err := os.Stat(e.path)
+				e.link = err != nil || info.IsDir()
+			case SymlinkFollow:
+				real, err := filepath.EvalSymlinks(e.path)
+				if err != nil {
+					log.Printf("symlink: skipping broken link %s: %v", e.path, err)
+					continue
+				}
+				info, err := os.Stat(e.path)
+				if err != nil {
+					log.Printf("symlink: skipping link %s: %v", e.path, err)
+					continue
+				}
+				e.isDir, e.real, e.target = info.IsDir(), real, r.display(real)
+				if e.isDir {
+					e.key = fileKey(info, real)
+				}
+			}
+		} else if e.isDir && r.policy == SymlinkFollow {
+			info, err := d.Info()
+			if err != nil {
+				continue
+			}
+			location := e.path
+			if e.real != "" {
+				location = e.real
+			}
+			e.key = fileKey(info, location)
+		}
+
+		if e.isDir && r.policy == SymlinkFollow && containsKey(dir.chain, e.key) {
+			log.Printf("symlink: not following %s, it loops back to %s", e.path, e.target)
+			e.isDir, e.link = false, true
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].isDir != entries[j].isDir {
+			return entries[i].isDir
+		}
+		return strings.ToLower(entries[i].name) < strings.ToLower(entries[j].name)
+	})
+	return entries, nil
+}
+
+// descend returns the walkDir of the folder at relPath below dir, resolving the links on the way
+// like readDir would. It is used when listing starts below the root.
+func (r *linkResolver) descend(dir walkDir, relPath string) walkDir {
+	for _, name := range strings.Split(filepath.Clean(relPath), string(os.PathSeparator)) {
+		e := listedEntry{path: filepath.Join(dir.path, name)}
+		if dir.real != "" {
+			e.real = filepath.Join(dir.real, name)
+		}
+		if real, err := filepath.EvalSymlinks(e.path); err == nil && real != e.path {
+			e.real = real
+		}
+		if info, err := os.Stat(e.path); err == nil {
+			e.key = fileKey(info, e.path)
+		}
+		dir = r.enter(dir, e)
+	}
+	return dir
+}
+
+// enter returns the walkDir of a listed folder.
+func (r *linkResolver) enter(parent walkDir, e listedEntry) walkDir {
+	child := walkDir{path: e.path, real: e.real}
+	if r.policy == SymlinkFollow {
+		child.chain = append(parent.chain[:len(parent.chain):len(parent.chain)], e.key)
+	}
+	return child
+}
+
+// display returns real relative to the project root with forward slashes, or the absolute path
+// when it lies outside the project.
+func (r *linkResolver) display(real string) string {
+	if rel, err := filepath.Rel(r.rootReal, real); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(real)
+}
+
+func containsKey(chain []dirKey, key dirKey) bool {
+	for _, k := range chain {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package shotgun
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dirKey identifies a folder for loop detection: by device and inode where available,
+// otherwise by its resolved path.
+type dirKey struct {
+	dev, ino uint64
+	path     string
+}
+
+func fileKey(info os.FileInfo, realPath string) dirKey {
+	if resolved, err := filepath.EvalSymlinks(realPath); err == nil {
+		realPath = resolved
+	}
+	return dirKey{path: realPath}
+}
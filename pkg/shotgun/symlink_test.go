@@ -0,0 +1,84 @@
+package shotgun
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSymlinkPolicies(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"real.txt":      "hello",
+		"dir/inner.txt": "inner",
+	})
+	if err := os.Symlink("real.txt", filepath.Join(root, "filelink")); err != nil {
+		t.Skipf("symbolic links are not available: %v", err)
+	}
+	if err := os.Symlink("dir", filepath.Join(root, "dirlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy  string
+		want    []string
+		notWant []string
+	}{
+		{
+			policy: SymlinkShow,
+			want: []string{
+				"<file path=\"filelink\" symlink-target=\"real.txt\">\nhello\n</file>",
+				"dirlink -> dir\n",
+			},
+			notWant: []string{"dirlink/inner.txt", "filelink -> "},
+		},
+		{
+			policy: "", // SymlinkShow
+			want: []string{
+				"<file path=\"filelink\" symlink-target=\"real.txt\">\nhello\n</file>",
+				"dirlink -> dir\n",
+			},
+		},
+		{
+			policy:  SymlinkSkip,
+			want:    []string{"<file path=\"real.txt\">\nhello\n</file>"},
+			notWant: []string{"filelink", "dirlink"},
+		},
+		{
+			policy: SymlinkFollow,
+			want: []string{
+				"<file path=\"filelink\" symlink-target=\"real.txt\">\nhello\n</file>",
+				"<file path=\"dirlink/inner.txt\" symlink-target=\"dir/inner.txt\">\ninner\n</file>",
+			},
+			notWant: []string{"dirlink -> "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run("policy="+tt.policy, func(t *testing.T) {
+			out := generate(t, Options{RootDir: root, Symlinks: tt.policy})
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("payload lacks %q:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(out, s) {
+					t.Errorf("payload contains %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateShowsBrokenLinkAsLeaf(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{"a.txt": "a"})
+	if err := os.Symlink("missing.txt", filepath.Join(root, "broken")); err != nil {
+		t.Skipf("symbolic links are not available: %v", err)
+	}
+	out := generate(t, Options{RootDir: root})
+	if !strings.Contains(out, "broken -> missing.txt\n") || strings.Contains(out, "path=\"broken\"") {
+		t.Errorf("broken link not listed as a leaf:\n%s", out)
+	}
+}
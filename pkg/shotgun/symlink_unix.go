@@ -0,0 +1,22 @@
+//go:build unix
+
+package shotgun
+
+import (
+	"os"
+	"syscall"
+)
+
+// dirKey identifies a folder for loop detection: by device and inode where available,
+// otherwise by its resolved path.
+type dirKey struct {
+	dev, ino uint64
+	path     string
+}
+
+func fileKey(info os.FileInfo, realPath string) dirKey {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return dirKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+	}
+	return dirKey{path: realPath}
+}
@@ -0,0 +1,148 @@
+package shotgun
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// TokenCounter estimates how many tokens a piece of text costs for a particular model.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// TokenCounterFunc adapts a plain function to the TokenCounter interface.
+type TokenCounterFunc func(text string) int
+
+// CountTokens implements TokenCounter.
+func (f TokenCounterFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// approxBytesPerToken is the usual rule of thumb for source code and English text.
+const approxBytesPerToken = 4
+
+// ApproxTokenCounter estimates tokens from the byte length alone. It never needs network access.
+var ApproxTokenCounter TokenCounter = TokenCounterFunc(func(text string) int {
+	return (len(text) + approxBytesPerToken - 1) / approxBytesPerToken
+})
+
+// fallbackEncoding is used for models tiktoken does not know (GPT-5, Gemini, Claude, ...).
+// It is not exact for those vendors, but it is much closer than a byte heuristic.
+const fallbackEncoding = tiktoken.MODEL_CL100K_BASE
+
+var (
+	loadMu      sync.Mutex // Serializes LoadTokenEncoding, which may download
+	encodingsMu sync.Mutex // Guards encodings only, so counters never wait for a download
+	encodings   = map[string]*tiktoken.Tiktoken{}
+)
+
+// encodingName returns the tiktoken encoding of model.
+func encodingName(model string) string {
+	if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return name
+	}
+	return fallbackEncoding
+}
+
+// LoadTokenEncoding loads the tiktoken encoding of model so that NewTokenCounter can count its
+// tokens exactly. tiktoken downloads the BPE ranks on first use and caches them (see
+// TIKTOKEN_CACHE_DIR), so call it ahead of generation, e.g. when the model is picked.
+func LoadTokenEncoding(model string) error {
+	name := encodingName(model)
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	encodingsMu.Lock()
+	loaded := encodings[name] != nil
+	encodingsMu.Unlock()
+	if loaded {
+		return nil
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return fmt.Errorf("failed to load token encoding %s: %w", name, err)
+	}
+	encodingsMu.Lock()
+	encodings[name] = enc
+	encodingsMu.Unlock()
+	return nil
+}
+
+// NewTokenCounter returns a tiktoken based counter for model if LoadTokenEncoding has loaded its
+// encoding, and ApproxTokenCounter otherwise. It never touches the network.
+func NewTokenCounter(model string) TokenCounter {
+	encodingsMu.Lock()
+	enc := encodings[encodingName(model)]
+	encodingsMu.Unlock()
+	if enc == nil {
+		return ApproxTokenCounter
+	}
+	return TokenCounterFunc(func(text string) int {
+		return len(enc.Encode(text, nil, nil))
+	})
+}
+
+// ErrTokenBudgetExceeded is returned when the payload needs more tokens than Options.TokenBudget.
+var ErrTokenBudgetExceeded = errors.New("context exceeds the token budget")
+
+// BudgetError reports why a payload did not fit into the token budget and what a ranked
+// truncation would drop to make it fit.
+type BudgetError struct {
+	Budget int `json:"budget"`
+	Total  int `json:"total"`
+	// Overflow lists, in payload order, the file whose block first crossed the budget and every file after it.
+	Overflow []ManifestEntry `json:"overflow"`
+	// Truncation lists, heaviest first, the files Options.TruncateToBudget would cut.
+	Truncation []ManifestEntry `json:"truncation"`
+	// TruncationFits reports whether cutting the Truncation files brings the payload within the
+	// budget. It does not when the tree and the sections alone are too large.
+	TruncationFits bool `json:"truncationFits"`
+}
+
+func (e *BudgetError) Error() string {
+	var hint string
+	switch {
+	case e.TruncationFits:
+		hint = fmt.Sprintf("truncating the %d heaviest files would fit", len(e.Truncation))
+	case len(e.Truncation) > 0:
+		hint = fmt.Sprintf("even truncating the %d heaviest files would not fit", len(e.Truncation))
+	default:
+		hint = "truncating files would not fit"
+	}
+	return fmt.Sprintf("%v: %d tokens needed, budget is %d (%s)", ErrTokenBudgetExceeded, e.Total, e.Budget, hint)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrTokenBudgetExceeded
+}
+
+// planTruncation ranks files by token cost and picks the heaviest ones until replacing their
+// contents with a placeholder (costing placeholderCost(i) tokens) brings total within budget.
+// It returns the indexes of the files to truncate, heaviest first, and the total after
+// truncating them, which is still over budget when no further file is worth cutting.
+func planTruncation(costs []ManifestEntry, total, budget int, placeholderCost func(i int) int) ([]int, int) {
+	ranked := make([]int, len(costs))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return costs[ranked[i]].Tokens > costs[ranked[j]].Tokens
+	})
+
+	var picked []int
+	for _, idx := range ranked {
+		if total <= budget {
+			break
+		}
+		saved := costs[idx].Tokens - placeholderCost(idx)
+		if saved <= 0 {
+			break // Remaining files are no bigger than their placeholders.
+		}
+		picked = append(picked, idx)
+		total -= saved
+	}
+	return picked, total
+}
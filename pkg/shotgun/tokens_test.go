@@ -0,0 +1,174 @@
+package shotgun
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanTruncation(t *testing.T) {
+	costs := []ManifestEntry{
+		{Path: "a", Tokens: 100},
+		{Path: "b", Tokens: 500},
+		{Path: "c", Tokens: 20},
+		{Path: "d", Tokens: 300},
+		{Path: "e", Tokens: 300},
+	}
+	placeholder := func(int) int { return 10 }
+	tests := []struct {
+		name       string
+		total      int
+		budget     int
+		wantPicked []int
+		wantTotal  int
+	}{
+		{"fits", 1220, 1220, nil, 1220},
+		{"heaviest only", 1220, 800, []int{1}, 730},
+		{"ties keep payload order", 1220, 500, []int{1, 3}, 440},
+		{"all worth cutting", 1220, 50, []int{1, 3, 4, 0, 2}, 50},
+		{"does not fit", 1270, 10, []int{1, 3, 4, 0, 2}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, total := planTruncation(costs, tt.total, tt.budget, placeholder)
+			if !reflect.DeepEqual(picked, tt.wantPicked) || total != tt.wantTotal {
+				t.Errorf("planTruncation = %v, %d; want %v, %d", picked, total, tt.wantPicked, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestPlanTruncationSkipsFilesSmallerThanPlaceholders(t *testing.T) {
+	costs := []ManifestEntry{{Path: "big", Tokens: 100}, {Path: "tiny", Tokens: 5}}
+	picked, total := planTruncation(costs, 200, 50, func(int) int { return 8 })
+	if !reflect.DeepEqual(picked, []int{0}) || total != 108 {
+		t.Errorf("planTruncation = %v, %d; want [0], 108", picked, total)
+	}
+}
+
+func TestBudgetErrorMessage(t *testing.T) {
+	files := []ManifestEntry{{Path: "a", Tokens: 900}, {Path: "b", Tokens: 400}}
+	tests := []struct {
+		err  *BudgetError
+		want string
+	}{
+		{&BudgetError{Budget: 1000, Total: 1500, Truncation: files, TruncationFits: true}, "(truncating the 2 heaviest files would fit)"},
+		{&BudgetError{Budget: 10, Total: 1500, Truncation: files}, "(even truncating the 2 heaviest files would not fit)"},
+		{&BudgetError{Budget: 10, Total: 50}, "(truncating files would not fit)"},
+	}
+	for _, tt := range tests {
+		msg := tt.err.Error()
+		if !strings.HasPrefix(msg, "context exceeds the token budget: ") || !strings.HasSuffix(msg, tt.want) {
+			t.Errorf("Error() = %q, want it to end with %q", msg, tt.want)
+		}
+		if !errors.Is(tt.err, ErrTokenBudgetExceeded) {
+			t.Errorf("%q does not wrap ErrTokenBudgetExceeded", msg)
+		}
+	}
+}
+
+// budgetProject holds files whose approximate token costs rank them heavy, medium and light.
+func budgetProject(t *testing.T) string {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{
+		"heavy.txt":  strings.Repeat("h", 4000),
+		"medium.txt": strings.Repeat("m", 2000),
+		"light.txt":  "light",
+	})
+	return root
+}
+
+func TestGenerateReportsBudgetError(t *testing.T) {
+	root := budgetProject(t)
+	_, err := NewGenerator(Options{RootDir: root, TokenBudget: 600}, nil).Generate(context.Background())
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("got %v, want a *BudgetError", err)
+	}
+	if !budgetErr.TruncationFits || budgetErr.Budget != 600 {
+		t.Errorf("budget error = %+v, want a truncation that fits a budget of 600", budgetErr)
+	}
+	if got := manifestPaths(budgetErr.Truncation); !reflect.DeepEqual(got, []string{"heavy.txt"}) {
+		t.Errorf("truncation = %v, want heavy.txt", got)
+	}
+	// Files are packed in tree order: heavy.txt, light.txt, medium.txt.
+	if got := manifestPaths(budgetErr.Overflow); !reflect.DeepEqual(got, []string{"heavy.txt", "light.txt", "medium.txt"}) {
+		t.Errorf("overflow = %v", got)
+	}
+}
+
+func TestGenerateTruncatesHeaviestFirst(t *testing.T) {
+	root := budgetProject(t)
+	result, err := NewGenerator(Options{RootDir: root, TokenBudget: 200, TruncateToBudget: true}, nil).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got := manifestPaths(result.Truncated); !reflect.DeepEqual(got, []string{"heavy.txt", "medium.txt"}) {
+		t.Errorf("truncated = %v, want heavy.txt then medium.txt", got)
+	}
+	if result.Truncated[0].Tokens <= result.Truncated[1].Tokens {
+		t.Errorf("truncated entries lost their costs from before truncation: %+v", result.Truncated)
+	}
+	if result.Tokens > 200 {
+		t.Errorf("payload costs %d tokens, over the budget", result.Tokens)
+	}
+	for _, entry := range result.Manifest.Files {
+		if entry.Truncated != (entry.Path != "light.txt") {
+			t.Errorf("%s: truncated = %v", entry.Path, entry.Truncated)
+		}
+	}
+	if !strings.Contains(result.Output, "<file path=\"light.txt\">\nlight\n</file>") {
+		t.Errorf("light.txt is missing:\n%s", result.Output)
+	}
+	if !strings.Contains(result.Output, "<file path=\"heavy.txt\" truncated=\"true\">\n[content omitted to fit the token budget:") {
+		t.Errorf("heavy.txt placeholder is missing:\n%s", result.Output)
+	}
+}
+
+func TestGenerateDoesNotTruncateWhenItCannotFit(t *testing.T) {
+	root := budgetProject(t)
+	// The tree and the placeholders alone cost more than 10 tokens.
+	_, err := NewGenerator(Options{RootDir: root, TokenBudget: 10, TruncateToBudget: true}, nil).Generate(context.Background())
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("got %v, want a *BudgetError", err)
+	}
+	if budgetErr.TruncationFits || len(budgetErr.Truncation) == 0 {
+		t.Errorf("budget error = %+v, want a truncation that does not fit", budgetErr)
+	}
+}
+
+func TestGenerateCapsReadingUnderBudget(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, root, map[string]string{"big.txt": strings.Repeat("x", 20000)})
+
+	// 20000 bytes are more than budgetReadFactor times the cap: reading stops before truncation.
+	opts := Options{RootDir: root, MaxOutputBytes: 4000, TokenBudget: 100, TruncateToBudget: true}
+	_, err := NewGenerator(opts, nil).Generate(context.Background())
+	if !errors.Is(err, ErrContextTooLong) || !strings.Contains(err.Error(), "16000 bytes") {
+		t.Fatalf("got %v, want ErrContextTooLong at 16000 bytes", err)
+	}
+}
+
+func TestNewTokenCounterDoesNotLoadEncodings(t *testing.T) {
+	counter := NewTokenCounter("some-unloaded-model")
+	if got := counter.CountTokens("0123456789"); got != ApproxTokenCounter.CountTokens("0123456789") {
+		t.Errorf("CountTokens = %d, want the approximate count", got)
+	}
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if len(encodings) != 0 {
+		t.Errorf("NewTokenCounter loaded encodings %v", encodings)
+	}
+}
+
+func manifestPaths(entries []ManifestEntry) []string {
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
@@ -0,0 +1,197 @@
+package shotgun
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileNode represents a file or folder of the project tree shown in the UI.
+type FileNode struct {
+	Name            string      `json:"name"`
+	Path            string      `json:"path"`    // Full path
+	RelPath         string      `json:"relPath"` // Path relative to selected root
+	IsDir           bool        `json:"isDir"`
+	Children        []*FileNode `json:"children,omitempty"`
+	IsGitignored    bool        `json:"isGitignored"`    // True if path matches a .gitignore rule
+	IsCustomIgnored bool        `json:"isCustomIgnored"` // True if path matches a ignore.glob rule
+	// SymlinkTarget is set for symbolic links: the link text when links are shown as leaves,
+	// the resolved location when they are followed.
+	SymlinkTarget string `json:"symlinkTarget,omitempty"`
+	// ChildCount is the number of entries of a folder whose Children were not listed
+	// (see ListDirectory and IndexTree). It is 0 for files and fully listed folders.
+	ChildCount int `json:"childCount,omitempty"`
+}
+
+// BuildTree lists rootDir recursively and returns its root node, flagging ignored entries.
+// Recursion stops only for folders ignored by custom rules. For folders matched by .gitignore,
+// recursion continues so the UI can show their contents and allow selective inclusion.
+// symlinks is the symbolic link policy, see Options.Symlinks.
+func BuildTree(ctx context.Context, rootDir string, m Matcher, symlinks string) (*FileNode, error) {
+	_, rootCustomIgnored := m.Match(".", false)
+	rootNode := &FileNode{
+		Name:            filepath.Base(rootDir),
+		Path:            rootDir,
+		RelPath:         ".",
+		IsDir:           true,
+		IsGitignored:    false, // Root itself is not gitignored by default
+		IsCustomIgnored: rootCustomIgnored,
+	}
+	if err := ValidateSymlinkPolicy(symlinks); err != nil {
+		return rootNode, err
+	}
+
+	resolver, root := newLinkResolver(rootDir, symlinks)
+	children, err := buildTreeRecursive(ctx, resolver, root, rootDir, m)
+	if err != nil {
+		return rootNode, err
+	}
+	rootNode.Children = children
+	return rootNode, nil
+}
+
+func buildTreeRecursive(ctx context.Context, resolver *linkResolver, dir walkDir, rootPath string, m Matcher) ([]*FileNode, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	entries, err := resolver.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries come sorted: directories first, then files, then alphabetically
+	var nodes []*FileNode
+	for _, entry := range entries {
+		relPath, _ := filepath.Rel(rootPath, entry.path)
+		isGitignored, isCustomIgnored := m.Match(relPath, entry.isDir)
+
+		node := &FileNode{
+			Name:            entry.name,
+			Path:            entry.path,
+			RelPath:         relPath,
+			IsDir:           entry.isDir,
+			IsGitignored:    isGitignored,
+			IsCustomIgnored: isCustomIgnored,
+		}
+		if entry.symlink {
+			node.SymlinkTarget = entry.target
+		}
+
+		if entry.isDir && !isCustomIgnored {
+			children, err := buildTreeRecursive(ctx, resolver, resolver.enter(dir, entry), rootPath, m)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return nil, err // Propagate cancellation
+				}
+				// Skip this subtree but keep the rest of the listing.
+				log.Printf("Error building subtree for %s: %v", entry.path, err)
+			} else {
+				node.Children = children
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// sortDirEntries orders entries the same way BuildTree orders nodes: directories first, then by name.
+func sortDirEntries(entries []fs.DirEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		isDirI := entries[i].IsDir()
+		isDirJ := entries[j].IsDir()
+		if isDirI && !isDirJ {
+			return true
+		}
+		if !isDirI && isDirJ {
+			return false
+		}
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+}
+
+// Selection narrows a project down to the paths that should go into the payload.
+type Selection struct {
+	// Include, when non-empty, keeps only these relative paths (and the contents of included folders).
+	// A path listed here is kept even if ignore rules match it.
+	Include []string
+	// Exclude drops these relative paths and their contents.
+	Exclude []string
+}
+
+// ExcludedPaths walks rootDir and returns the relative paths a Generator should skip for the
+// given ignore rules and selection. Ignore rules still apply inside included folders.
+func ExcludedPaths(rootDir string, m Matcher, sel Selection) ([]string, error) {
+	includeSet := make(map[string]bool, len(sel.Include))
+	for _, p := range sel.Include {
+		includeSet[filepath.Clean(filepath.FromSlash(p))] = true
+	}
+	excludeSet := make(map[string]bool, len(sel.Exclude))
+	for _, p := range sel.Exclude {
+		excludeSet[filepath.Clean(filepath.FromSlash(p))] = true
+	}
+
+	// isForced reports whether relPath was named explicitly or is a parent of an explicit include.
+	isForced := func(relPath string) bool {
+		if includeSet[relPath] {
+			return true
+		}
+		prefix := relPath + string(os.PathSeparator)
+		for inc := range includeSet {
+			if strings.HasPrefix(inc, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	isUnderInclude := func(relPath string) bool {
+		for dir := filepath.Dir(relPath); dir != "." && dir != string(os.PathSeparator); dir = filepath.Dir(dir) {
+			if includeSet[dir] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var excluded []string
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if d != nil && d.IsDir() && path != rootDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == rootDir {
+			return nil
+		}
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return nil
+		}
+
+		skip := excludeSet[relPath]
+		if !skip && !isForced(relPath) {
+			if len(includeSet) > 0 && !isUnderInclude(relPath) {
+				skip = true
+			} else {
+				skip = m.Ignored(relPath, d.IsDir())
+			}
+		}
+
+		if skip {
+			excluded = append(excluded, relPath)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	return excluded, err
+}
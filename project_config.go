@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"shotgun_code/pkg/shotgun"
+)
+
+// --- Project configuration (.shotgun/, .shotgunignore, .shotgun.json) ---
+
+// loadProjectConfig reads the checked-in configuration of the project opened by ListFiles and
+// recompiles the custom ignore patterns so the project rules apply. A broken config is logged
+// and ignored rather than blocking the project from opening.
+func (a *App) loadProjectConfig(rootDir string) {
+	cfg, err := shotgun.LoadProjectConfig(rootDir)
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Ignoring project configuration in %s: %v", rootDir, err)
+		cfg = shotgun.ProjectConfig{}
+	} else if len(cfg.Sources) > 0 {
+		runtime.LogInfof(a.ctx, "Loaded project configuration from %s", strings.Join(cfg.Sources, ", "))
+	}
+	changedLLM := !sameProjectLLM(a.projectConfig, cfg)
+	a.projectConfig = cfg
+	a.projectConfigRoot = rootDir
+	if changedLLM {
+		a.warnIgnoredProjectProvider(cfg)
+		a.invalidateProviderCache()
+		a.preloadTokenEncoding()
+	}
+	a.compileCustomIgnorePatterns()
+}
+
+// warnIgnoredProjectProvider logs why effectiveLLMSettings will not use the provider the project
+// prefers: it is unknown, or it has no API key yet.
+func (a *App) warnIgnoredProjectProvider(cfg shotgun.ProjectConfig) {
+	name := strings.TrimSpace(cfg.Provider)
+	if name == "" {
+		return
+	}
+	projectProvider := normalizeProviderName(name)
+	if projectProvider == "" {
+		runtime.LogWarningf(a.ctx, "Ignoring unknown provider %q of the project configuration.", name)
+	} else if !a.settings.LLMSettings.isConfigured(projectProvider) {
+		runtime.LogWarningf(a.ctx, "The project prefers the %s provider, which has no API key; using %q until one is set.", projectProvider, a.settings.LLMSettings.ActiveProvider)
+	}
+}
+
+func sameProjectLLM(x, y shotgun.ProjectConfig) bool {
+	return x.Provider == y.Provider && x.Model == y.Model
+}
+
+// GetProjectConfig returns the configuration of the currently opened project, empty if it has none.
+func (a *App) GetProjectConfig() shotgun.ProjectConfig {
+	return a.projectConfig
+}
+
+// GetEffectivePromptRules returns the global custom prompt rules followed by the project's
+// prompt rules. Use GetCustomPromptRules to edit the global part.
+func (a *App) GetEffectivePromptRules() string {
+	global := a.GetCustomPromptRules()
+	project := strings.TrimSpace(a.projectConfig.PromptRules)
+	if project == "" {
+		return global
+	}
+	return strings.TrimRight(global, "\n") + "\n\n" + project
+}
+
+// effectiveLLMSettings applies the project's preferred provider and model on top of the global
+// LLM settings. An unknown project provider or one without a configured API key is ignored;
+// warnIgnoredProjectProvider logs it when the project is opened.
+func (a *App) effectiveLLMSettings() LLMSettings {
+	settings := a.settings.LLMSettings
+	cfg := a.projectConfig
+	if projectProvider := normalizeProviderName(cfg.Provider); projectProvider != "" && projectProvider != settings.ActiveProvider {
+		if !settings.isConfigured(projectProvider) {
+			return settings
+		}
+		settings.ActiveProvider = projectProvider
+		settings.Model = defaultModelForProvider(projectProvider)
+	}
+	if model := strings.TrimSpace(cfg.Model); model != "" && settings.ActiveProvider != "" {
+		settings.Model = model
+	}
+	return settings
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"shotgun_code/pkg/shotgun"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// TreeIndexer lists the project tree in the background and streams it to the frontend as
+// "fileTreeChunk" events, followed by a single "fileTreeIndexed" event.
+type TreeIndexer struct {
+	app                *App
+	mu                 sync.Mutex
+	currentCancelFunc  context.CancelFunc
+	currentCancelToken interface{} // Token to identify the current cancel func
+}
+
+func NewTreeIndexer(app *App) *TreeIndexer {
+	return &TreeIndexer{app: app}
+}
+
+// treeChunkEvent is the payload of the "fileTreeChunk" event.
+type treeChunkEvent struct {
+	RootDir string `json:"rootDir"`
+	shotgun.TreeChunk
+}
+
+// treeIndexedEvent is the payload of the "fileTreeIndexed" event.
+type treeIndexedEvent struct {
+	RootDir string `json:"rootDir"`
+	Entries int    `json:"entries"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ListDirectory lists one folder of the project (relPath relative to rootDir, "." for the root)
+// with a page of its direct children: from offset on, at most limit of them (all when limit is
+// 0). The counts of the sub-folders leave out the entries excluded by the ignore rules switched
+// on (useGitignore, useCustomIgnore). Listing the root (or another project) opens the project
+// like ListFiles does: git ignore rules and the .shotgun configuration are reloaded.
+func (a *App) ListDirectory(rootDir string, relPath string, useGitignore, useCustomIgnore bool, offset, limit int) (*shotgun.DirectoryPage, error) {
+	relPath = filepath.Clean(filepath.FromSlash(relPath))
+	var matcher shotgun.Matcher
+	if relPath == "." || a.projectGitignore == nil || filepath.Clean(a.projectGitignore.RootDir()) != filepath.Clean(rootDir) {
+		matcher = a.openProject(rootDir)
+	} else {
+		matcher = shotgun.Matcher{Gitignore: a.projectGitignore, Custom: a.currentCustomIgnorePatterns}
+	}
+	toggles := shotgun.IgnoreToggles{Gitignore: useGitignore, Custom: useCustomIgnore}
+	return shotgun.ListDirectory(rootDir, relPath, matcher, toggles, a.settings.SymlinkPolicy, offset, limit)
+}
+
+// StartTreeIndex starts indexing rootDir in the background, cancelling a previous run. Like
+// ListFiles, entries are flagged by every ignore rule and the tree's toggles decide which flags
+// apply: folders matched by the git rules (if useGitignore) or the custom rules (if
+// useCustomIgnore) are not descended into; ListDirectory lists them on demand.
+func (a *App) StartTreeIndex(rootDir string, useGitignore, useCustomIgnore bool) {
+	if a.treeIndexer == nil {
+		runtime.LogError(a.ctx, "TreeIndexer not initialized")
+		return
+	}
+	matcher := shotgun.Matcher{Custom: a.currentCustomIgnorePatterns}
+	if a.projectGitignore != nil && filepath.Clean(a.projectGitignore.RootDir()) == filepath.Clean(rootDir) {
+		matcher.Gitignore = a.projectGitignore
+	} else if gitIgn, err := shotgun.CompileGitignore(rootDir); err == nil {
+		matcher.Gitignore = gitIgn
+	}
+	toggles := shotgun.IgnoreToggles{Gitignore: useGitignore, Custom: useCustomIgnore}
+	a.treeIndexer.start(rootDir, matcher, toggles, a.settings.SymlinkPolicy)
+}
+
+// CancelTreeIndex stops the running background index, if any.
+func (a *App) CancelTreeIndex() {
+	if a.treeIndexer == nil {
+		return
+	}
+	a.treeIndexer.mu.Lock()
+	defer a.treeIndexer.mu.Unlock()
+	if a.treeIndexer.currentCancelFunc != nil {
+		a.treeIndexer.currentCancelFunc()
+	}
+}
+
+func (ti *TreeIndexer) start(rootDir string, matcher shotgun.Matcher, toggles shotgun.IgnoreToggles, symlinks string) {
+	ti.mu.Lock()
+	if ti.currentCancelFunc != nil {
+		runtime.LogDebug(ti.app.ctx, "Cancelling previous tree index.")
+		ti.currentCancelFunc()
+	}
+	indexCtx, cancel := context.WithCancel(ti.app.ctx)
+	myToken := new(struct{})
+	ti.currentCancelFunc = cancel
+	ti.currentCancelToken = myToken
+	ti.mu.Unlock()
+
+	go func(tokenForThisJob interface{}) {
+		defer func() {
+			ti.mu.Lock()
+			if ti.currentCancelToken == tokenForThisJob { // Only clear if it's still this job's token
+				ti.currentCancelFunc = nil
+				ti.currentCancelToken = nil
+			}
+			ti.mu.Unlock()
+			cancel()
+		}()
+
+		entries, err := shotgun.IndexTree(indexCtx, rootDir, matcher, toggles, symlinks, 0, func(chunk shotgun.TreeChunk) {
+			runtime.EventsEmit(ti.app.ctx, "fileTreeChunk", treeChunkEvent{RootDir: rootDir, TreeChunk: chunk})
+		})
+		done := treeIndexedEvent{RootDir: rootDir, Entries: entries}
+		if err != nil {
+			runtime.LogInfof(ti.app.ctx, "Tree index for %s stopped after %d entries: %v", rootDir, entries, err)
+			done.Error = err.Error()
+		} else {
+			runtime.LogInfof(ti.app.ctx, "Tree index for %s finished: %d entries", rootDir, entries)
+		}
+		runtime.EventsEmit(ti.app.ctx, "fileTreeIndexed", done)
+	}(myToken)
+}